@@ -1,52 +1,248 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 const buildUsage = `Usage:
-  singlish build <file>
+  singlish build [flags] [<file>] [-- go build flags]
 
 Description:
-  Transpile and build a binary from a .singlish file.
+  Transpile and build a binary from a .singlish file, or from the entry file
+  set in singlish.toml. The [build] settings of singlish.toml are used as
+  defaults; flags given here override them.
+
+Flags:
+  -o <path>            Write the binary to path (default: the file's base name,
+                       plus .exe when building for Windows, in the output-dir
+                       of singlish.toml if set). If path is an existing
+                       directory the binary is written inside it.
+  -ldflags <flags>     Passed to go build, e.g. -ldflags "-s -w -X main.version=1.2.3"
+  -tags <list>         Comma-separated build tags, passed to go build
+  -race[=bool]         Enable the race detector; -race=false turns off
+                       race in singlish.toml
+  -trimpath[=bool]     Remove file system paths from the binary
+  --goos <os>          Target operating system (same as setting GOOS)
+  --goarch <arch>      Target architecture (same as setting GOARCH)
+  --watch              Rebuild whenever the file or the dictionary changes
+  --                   Pass everything after it to go build unchanged
+
+  GOOS, GOARCH and other Go environment variables are honoured as usual.
+
+Examples:
+  singlish build main.singlish
+  singlish build -o bin/kopi -trimpath -ldflags "-s -w" main.singlish
+  GOOS=windows GOARCH=amd64 singlish build main.singlish
+  singlish build main.singlish -- -v -gcflags=all=-N
 `
 
+// buildOptions holds the parsed command line of singlish build.
+type buildOptions struct {
+	input     string
+	output    string
+	outputDir string // from singlish.toml; used when output is empty
+	ldflags   string
+	tags      string
+	race      bool
+	trimpath  bool
+	goos      string
+	goarch    string
+	extra     []string // passed through after --
+}
+
+// parseBuildArgs parses flags given before or after the input file. Flags
+// may be written with one or two dashes and as "-flag value" or "-flag=value".
+// The project configuration supplies the defaults.
+func parseBuildArgs(args []string) (*buildOptions, error) {
+	opts := &buildOptions{
+		outputDir: project.Build.OutputDir,
+		ldflags:   project.Build.LDFlags,
+		tags:      project.Build.Tags,
+		race:      project.Build.Race,
+		trimpath:  project.Build.Trimpath,
+		extra:     slices.Clone(project.Build.Flags),
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.extra = append(opts.extra, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if opts.input != "" {
+				return nil, fmt.Errorf("unexpected argument %q (only one input file is supported)", arg)
+			}
+			opts.input = arg
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var target *string
+		switch name {
+		case "race", "trimpath":
+			// Boolean flags take a value only after =, as in -race=false.
+			on := true
+			if hasValue {
+				var err error
+				if on, err = strconv.ParseBool(value); err != nil {
+					return nil, fmt.Errorf("invalid value %q for flag -%s: want true or false", value, name)
+				}
+			}
+			if name == "race" {
+				opts.race = on
+			} else {
+				opts.trimpath = on
+			}
+			continue
+		case "o":
+			target = &opts.output
+		case "ldflags":
+			target = &opts.ldflags
+		case "tags":
+			target = &opts.tags
+		case "goos":
+			target = &opts.goos
+		case "goarch":
+			target = &opts.goarch
+		default:
+			return nil, fmt.Errorf("unknown flag %s (use -- to pass flags to go build)", arg)
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag %s requires an argument", arg)
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+	if opts.input == "" {
+		opts.input = project.Entry
+	}
+	if opts.input == "" {
+		return nil, fmt.Errorf("missing input file")
+	}
+	return opts, nil
+}
+
+// targetOS returns the operating system the binary is built for.
+func (o *buildOptions) targetOS() string {
+	if o.goos != "" {
+		return o.goos
+	}
+	if goos := os.Getenv("GOOS"); goos != "" {
+		return goos
+	}
+	return runtime.GOOS
+}
+
+// outputPath decides where the binary goes.
+func (o *buildOptions) outputPath() string {
+	base := filepath.Base(o.input)
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	if name == "" {
+		name = "main"
+	}
+	if o.targetOS() == "windows" {
+		name += ".exe"
+	}
+
+	if o.output == "" {
+		if o.outputDir != "" {
+			return filepath.Join(o.outputDir, name)
+		}
+		return name
+	}
+	if info, err := os.Stat(o.output); err == nil && info.IsDir() {
+		return filepath.Join(o.output, name)
+	}
+	return o.output
+}
+
+// goBuildArgs returns the arguments for go build on the transpiled file.
+func (o *buildOptions) goBuildArgs(goFile string) []string {
+	args := []string{"build", "-o", o.outputPath()}
+	if o.ldflags != "" {
+		args = append(args, "-ldflags", o.ldflags)
+	}
+	if o.tags != "" {
+		args = append(args, "-tags", o.tags)
+	}
+	if o.race {
+		args = append(args, "-race")
+	}
+	if o.trimpath {
+		args = append(args, "-trimpath")
+	}
+	args = append(args, o.extra...)
+	return append(args, goFile)
+}
+
 func runBuild(args []string) int {
-	if len(args) == 0 || isHelpFlag(args[0]) {
+	if len(args) > 0 && isHelpFlag(args[0]) {
 		fmt.Fprint(os.Stdout, buildUsage)
-		if len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "\nError: missing input file")
-			return 1
-		}
 		return 0
 	}
 
-	inputFile := args[0]
-	tempPath, err := transpileToTemp(inputFile)
+	watchMode, args := stripWatchFlag(args)
+	opts, err := parseBuildArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nRun 'singlish build --help' for usage.\n", err)
+		return 1
+	}
+
+	if watchMode {
+		paths := func() []string {
+			return append([]string{opts.input}, dictionaryFiles()...)
+		}
+		return watchLoop(paths, func(ctx context.Context, changed []string) {
+			printWatchChange(changed)
+			if buildOnce(opts) == 0 {
+				fmt.Fprintf(os.Stderr, "[watch] built %s; waiting for changes\n", opts.outputPath())
+			}
+		})
+	}
+	return buildOnce(opts)
+}
+
+// buildOnce transpiles and builds the binary described by opts.
+func buildOnce(opts *buildOptions) int {
+	inputFile := opts.input
+	t, err := transpileFile(inputFile)
 	if err != nil {
 		handleError(err, inputFile)
 		return 1
 	}
-	defer os.Remove(tempPath)
+	defer t.cleanup()
 
-	// Determine output name
-	base := filepath.Base(inputFile)
-	ext := filepath.Ext(base)
-	outputName := strings.TrimSuffix(base, ext)
-	if outputName == "" {
-		outputName = "main"
+	if dir := filepath.Dir(opts.outputPath()); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
 	}
 
 	// Run go build
-	cmd := exec.Command("go", "build", "-o", outputName, tempPath)
+	cmd := exec.Command("go", opts.goBuildArgs(t.goFile)...)
 	cmd.Stdout = os.Stdout
-	// Stderr handled by executeWithInsults
+	cmd.Env = os.Environ()
+	if opts.goos != "" {
+		cmd.Env = append(cmd.Env, "GOOS="+opts.goos)
+	}
+	if opts.goarch != "" {
+		cmd.Env = append(cmd.Env, "GOARCH="+opts.goarch)
+	}
+	// Stderr handled by goBuild
 
-	if err := executeWithInsults(cmd); err != nil {
+	if err := t.goBuild(cmd); err != nil {
 		return 1
 	}
 
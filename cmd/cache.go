@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rickchow/singlish/pkg/cache"
+)
+
+const cacheUsage = `Usage:
+  singlish cache <stats|clean>
+
+Description:
+  Manage the cache of transpiled programs and built binaries that lets
+  run and build skip work for programs that have not changed. Entries are
+  keyed on the source, the dictionary, the singlish version and the Go
+  build settings (GOOS, GOARCH, CGO_ENABLED, GOFLAGS and GOVERSION).
+
+  The cache lives in a singlish directory under the user cache directory.
+  Set SINGLISH_CACHE to use another directory, or to "off" to disable it.
+
+Commands:
+  stats   Show where the cache is and how much space it uses
+  clean   Remove every cached entry
+`
+
+func runCache(args []string) int {
+	if len(args) == 0 || isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, cacheUsage)
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "\nError: missing cache command")
+			return 1
+		}
+		return 0
+	}
+
+	c, err := cache.Open()
+	if errors.Is(err, cache.ErrDisabled) {
+		fmt.Fprintf(os.Stderr, "Error: the cache is disabled (%s=off)\n", cache.EnvVar)
+		return 1
+	}
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+
+	switch args[0] {
+	case "stats":
+		st, err := c.Stats()
+		if err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		fmt.Printf("Location: %s\n", c.Dir)
+		fmt.Printf("Entries:  %d (%d with built binaries)\n", st.Entries, st.Binaries)
+		fmt.Printf("Size:     %s\n", formatBytes(st.Bytes))
+	case "clean":
+		st, _ := c.Stats()
+		if err := c.Clean(); err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		fmt.Printf("Removed %d entries (%s) from %s\n", st.Entries, formatBytes(st.Bytes), c.Dir)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown cache command: %s\n", args[0])
+		return 1
+	}
+	return 0
+}
+
+// formatBytes renders a size for humans, e.g. 1536 -> "1.5 KiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/typecheck"
+)
+
+const checkUsage = `Usage:
+  singlish check [<file | dir>...]
+
+Description:
+  Type-check Singlish programs without building them. The files are
+  transpiled and their Go checked with go/types against the standard library
+  of the local Go installation, which is much quicker than go build. Errors
+  are reported at the Singlish code that caused them, with type names as
+  the dictionary spells them: "cannot use "a" (untyped tar constant) as
+  nombor value". Directories are searched for .singlish files. Without
+  arguments, checks the entry file set in singlish.toml.
+
+  The files in one directory with the same kampung are checked together as
+  a package, so each may use what the others declare. A file with its own
+  boss is a program of its own: it is checked with the files of its package
+  that have no boss, but not with other programs.
+
+  Packages from outside the standard library are not read; code using them
+  is not checked, and a note says so.
+`
+
+func runCheck(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, checkUsage)
+		return 0
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			fmt.Fprintf(os.Stderr, "Error: unknown flag %s\nRun 'singlish check --help' for usage.\n", arg)
+			return 1
+		}
+	}
+
+	inputs := withEntry(args)
+	if len(inputs) == 0 {
+		fmt.Fprint(os.Stdout, checkUsage)
+		fmt.Fprintln(os.Stderr, "\nError: missing input file")
+		return 1
+	}
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+	files, err := singlishFiles(inputs)
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+
+	code := 0
+	var parsed []typecheck.File
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+			continue
+		}
+		program, err := transpiler.ParseFile(string(content), dict)
+		if err != nil {
+			handleError(err, path)
+			code = 1
+			continue
+		}
+		parsed = append(parsed, typecheck.File{Path: path, Source: string(content), Program: program})
+	}
+
+	// Errors in files shared by several programs are found once for each.
+	reported := make(map[string]bool)
+	for _, unit := range checkUnits(parsed, dict) {
+		diags, err := typecheck.CheckPackage(unit, dict)
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+			continue
+		}
+		for _, f := range unit {
+			if reported[f.Path] {
+				continue
+			}
+			reported[f.Path] = true
+			if reportTypeErrors(f, diags[f.Path]) {
+				code = 1
+			}
+		}
+	}
+
+	if code == 0 && !machineDiagnostics() {
+		fmt.Fprintf(os.Stderr, "Checked %d file(s), no type errors\n", len(files))
+	}
+	return code
+}
+
+// reportTypeErrors shows the type errors of f and reports whether there
+// were any.
+func reportTypeErrors(f typecheck.File, diags []lexer.Diagnostic) bool {
+	failed := slices.ContainsFunc(diags, func(d lexer.Diagnostic) bool { return d.Severity == lexer.SeverityError })
+	if machineDiagnostics() {
+		collect(reporting.InFile(f.Path, diags)...)
+	} else if len(diags) > 0 {
+		if failed {
+			printInsult(insults.Major)
+		}
+		printer := &reporting.Printer{Filename: f.Path, Color: reporting.UseColor(os.Stderr), Context: 1}
+		printer.PrintAll(os.Stderr, f.Source, diags)
+	}
+	return failed
+}
+
+// checkUnits groups files into what is type-checked together: the files in
+// one directory with the same kampung make up a package. A file with its
+// own boss is a program of its own, as singlish run treats it, so each such
+// file is checked with only the files of its package that have none.
+func checkUnits(files []typecheck.File, dict *dictionaries.Dictionary) [][]typecheck.File {
+	type pkgKey struct{ dir, name string }
+	var order []pkgKey
+	shared := make(map[pkgKey][]typecheck.File)
+	programs := make(map[pkgKey][]typecheck.File)
+	for _, f := range files {
+		key := pkgKey{filepath.Dir(f.Path), packageName(f.Program)}
+		if _, ok := shared[key]; !ok {
+			order = append(order, key)
+			shared[key] = nil
+		}
+		if hasEntry(f.Program, dict) {
+			programs[key] = append(programs[key], f)
+		} else {
+			shared[key] = append(shared[key], f)
+		}
+	}
+
+	var units [][]typecheck.File
+	for _, key := range order {
+		if len(programs[key]) == 0 {
+			units = append(units, shared[key])
+			continue
+		}
+		for _, p := range programs[key] {
+			units = append(units, append(slices.Clone(shared[key]), p))
+		}
+	}
+	return units
+}
+
+// packageName returns the name in the kampung of program.
+func packageName(program *ast.Program) string {
+	for _, s := range program.Statements {
+		if ps, ok := s.(*ast.PackageStatement); ok && ps.Name != nil {
+			return ps.Name.Value
+		}
+	}
+	return ""
+}
+
+// hasEntry reports whether program declares the main function of a
+// program, usually as boss.
+func hasEntry(program *ast.Program, dict *dictionaries.Dictionary) bool {
+	for _, s := range program.Statements {
+		fs, ok := s.(*ast.FunctionStatement)
+		if !ok || fs.Receiver != nil || fs.Name == nil {
+			continue
+		}
+		if name, ok := dict.Lookup(fs.Name.Value); fs.Name.Value == "main" || ok && name == "main" {
+			return true
+		}
+	}
+	return false
+}
@@ -2,117 +2,228 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 
+	"github.com/rickchow/singlish/pkg/cache"
+	"github.com/rickchow/singlish/pkg/codegen"
 	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/lexer"
 	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/singlish"
+	"github.com/rickchow/singlish/pkg/suggest"
 	"github.com/rickchow/singlish/pkg/transpiler"
 )
 
-var insults = []string{
-	"Eh bodoh",
-	"Go fly kite lah",
-	"Simi sai is this?",
-	"You think this one playground ah?",
-	"Catch no ball sia",
-	"Blur like sotong",
-	"Why you so liddat?",
-	"Aiyo, cannot make it lah",
-	"Wake up your idea",
-}
-
-func getRandomInsult() string {
-	return insults[rand.Intn(len(insults))]
-}
-
-// executeWithInsults runs the command and wraps stderr with an insult if it fails.
-func executeWithInsults(cmd *exec.Cmd) error {
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	if err := cmd.Run(); err != nil {
-		// Command failed, print insult then error
-		fmt.Fprintf(os.Stderr, "%s\n", getRandomInsult())
-		fmt.Fprint(os.Stderr, stderr.String())
-		return err
-	}
-
-	// Command succeeded, just print any stderr output (e.g. warnings)
-	if stderr.Len() > 0 {
-		fmt.Fprint(os.Stderr, stderr.String())
-	}
-	return nil
-}
-
-// printErrorWithInsult prints a random insult followed by the error message.
-func printErrorWithInsult(err error) {
-	fmt.Fprintf(os.Stderr, "%s\n", getRandomInsult())
-	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-}
-
-// handleError prints rich diagnostics if available, or falls back to insults.
+// handleError prints rich diagnostics, preceded by an insult for broken
+// code, if available, or falls back to printErrorWithInsult.
 func handleError(err error, inputPath string) {
+	var diags []lexer.Diagnostic
 	var tErr *transpiler.TranspilationError
-	if errors.As(err, &tErr) {
+	var cErr *singlish.Error
+	switch {
+	case errors.As(err, &tErr):
+		diags = tErr.Diagnostics
+	case errors.As(err, &cErr):
+		for _, d := range cErr.Diagnostics {
+			diags = append(diags, d.Diagnostic)
+		}
+	}
+	if machineDiagnostics() {
+		if len(diags) > 0 {
+			collect(reporting.InFile(inputPath, diags)...)
+		} else {
+			collectError(inputPath, err)
+		}
+		return
+	}
+	if len(diags) > 0 {
 		content, readErr := os.ReadFile(inputPath)
 		if readErr == nil {
-			reporting.PrintDiagnostics(os.Stderr, string(content), tErr.Diagnostics)
+			printInsult(insults.Major)
+			printer := &reporting.Printer{Filename: inputPath, Color: reporting.UseColor(os.Stderr), Context: 1}
+			printer.PrintAll(os.Stderr, string(content), diags)
 			return
 		}
 	}
 	printErrorWithInsult(err)
 }
 
+// loadDictionary loads the dictionary in use: the files given with
+// --dictionary, or else those listed in $SINGLISH_KEYWORDS or singlish.toml,
+// merged in order, or else the built-in dictionary.
 func loadDictionary() (*dictionaries.Dictionary, error) {
-	// 1. Explicit flag
-	if DictionaryPath != "" {
-		return dictionaries.LoadDictionary(DictionaryPath)
+	return dictionaries.Load(dictionaryPaths()...)
+}
+
+// dictionaryPaths returns the dictionary files named on the command line,
+// in $SINGLISH_KEYWORDS, which holds a list like $PATH, or in the project
+// configuration, in that order of preference.
+func dictionaryPaths() []string {
+	if len(DictionaryPaths) > 0 {
+		return DictionaryPaths
 	}
+	if paths := filepath.SplitList(os.Getenv("SINGLISH_KEYWORDS")); len(paths) > 0 {
+		return paths
+	}
+	return project.Dictionaries
+}
+
+// transpiled is the Go translation of a Singlish file on disk.
+type transpiled struct {
+	dir    string // holds the Go file and, once built, the binary
+	goFile string
+	cached bool // dir is a cache entry that outlives this command
 
-	// 2. Try environment variable
-	if path := os.Getenv("SINGLISH_KEYWORDS"); path != "" {
-		return dictionaries.LoadDictionary(path)
+	input  string // the Singlish file as the user named it
+	source string // its absolute path, used in the Go file's line directives
+}
+
+// binary is where the built program for this translation lives.
+func (t *transpiled) binary() string {
+	name := cache.BinaryFile
+	if runtime.GOOS == "windows" {
+		name += ".exe"
 	}
+	return filepath.Join(t.dir, name)
+}
 
-	// 3. Use embedded default
-	return dictionaries.NewDefaultDictionary(), nil
+// cleanup removes the translation unless it belongs to the cache.
+func (t *transpiled) cleanup() {
+	if !t.cached {
+		os.RemoveAll(t.dir)
+	}
 }
 
-// transpileToTemp reads inputPath, transpiles it, and writes the result to a temporary .go file.
-// It returns the path to the temp file and any error.
-func transpileToTemp(inputPath string) (string, error) {
-	// Read input
+// transpileFile transpiles inputPath into its cache entry, skipping the work
+// when the entry already holds the translation of the same source with the
+// same dictionary. Without a usable cache it writes to a temporary directory.
+func transpileFile(inputPath string) (*transpiled, error) {
 	content, err := os.ReadFile(inputPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read input file: %w", err)
+		return nil, fmt.Errorf("failed to read input file: %w", err)
 	}
 
-	// Load dictionary
 	dict, err := loadDictionary()
 	if err != nil {
-		return "", fmt.Errorf("failed to load dictionary: %w", err)
+		return nil, fmt.Errorf("failed to load dictionary: %w", err)
+	}
+
+	t := &transpiled{input: inputPath}
+	if t.source, err = filepath.Abs(inputPath); err != nil {
+		return nil, err
+	}
+	if c, err := cache.Open(); err == nil {
+		if t.dir, err = c.Entry(cache.Key(t.source, content, dict)); err == nil {
+			t.cached = true
+		}
+	}
+	if !t.cached {
+		if t.dir, err = os.MkdirTemp("", "singlish_*"); err != nil {
+			return nil, fmt.Errorf("failed to create temp dir: %w", err)
+		}
 	}
+	t.goFile = filepath.Join(t.dir, cache.GoFile)
 
-	// Transpile
-	goCode, err := transpiler.Transpile(string(content), dict)
+	if _, err := os.Stat(t.goFile); err == nil && t.cached {
+		return t, nil
+	}
+
+	// Line directives make go build errors and panics point at the
+	// Singlish source rather than the generated file.
+	res, err := singlish.Compile(context.Background(), content, singlish.Options{
+		Filename:           t.source,
+		Dictionary:         dict,
+		EmitLineDirectives: true,
+	})
 	if err != nil {
-		return "", fmt.Errorf("transpilation failed: %w", err)
+		t.cleanup()
+		return nil, fmt.Errorf("transpilation failed: %w", err)
+	}
+	if err := cache.WriteFile(t.dir, cache.GoFile, []byte(res.Code), 0644); err != nil {
+		t.cleanup()
+		return nil, fmt.Errorf("failed to write Go file: %w", err)
 	}
+	return t, nil
+}
 
-	// Write to temp file
-	tmpFile, err := os.CreateTemp("", "singlish_*.go")
+// compileProgram transpiles and builds inputFile, reusing the cached binary
+// when neither the source nor the dictionary changed. Errors are reported to
+// the user; ok is false if there is nothing to run. The caller must call
+// cleanup once the binary is no longer needed.
+func compileProgram(ctx context.Context, inputFile string) (binary string, cleanup func(), ok bool) {
+	t, err := transpileFile(inputFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to create temp file: %w", err)
+		handleError(err, inputFile)
+		return "", nil, false
+	}
+
+	binary = t.binary()
+	if _, err := os.Stat(binary); err == nil {
+		return binary, t.cleanup, true
 	}
-	defer tmpFile.Close()
 
-	if _, err := tmpFile.WriteString(goCode); err != nil {
-		return "", fmt.Errorf("failed to write to temp file: %w", err)
+	// Build next to the final name and rename, so that concurrent runs of
+	// the same program never execute a half-written binary.
+	tmp := binary + fmt.Sprintf(".tmp%d", os.Getpid())
+	build := exec.CommandContext(ctx, "go", "build", "-o", tmp, t.goFile)
+	if err := t.goBuild(build); err != nil {
+		os.Remove(tmp)
+		t.cleanup()
+		return "", nil, false
 	}
+	if err := os.Rename(tmp, binary); err != nil {
+		os.Remove(tmp)
+		t.cleanup()
+		printErrorWithInsult(err)
+		return "", nil, false
+	}
+	return binary, t.cleanup, true
+}
 
-	return tmpFile.Name(), nil
+// goBuild runs a go build of the translation. Errors from the Go compiler
+// name the Singlish file, thanks to line directives; they are shown with
+// the path the user gave, or collected as diagnostics.
+func (t *transpiled) goBuild(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	output := strings.ReplaceAll(stderr.String(), t.source, t.input)
+	// The go command shortens paths below the working directory to ./path.
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, t.source); err == nil && filepath.IsLocal(rel) {
+			output = strings.ReplaceAll(output, "."+string(filepath.Separator)+rel+":", t.input+":")
+		}
+	}
+	if dict, err := loadDictionary(); err == nil {
+		output = suggest.AnnotateCompilerOutput(output, dict)
+	}
+	// Names Go reserves were renamed; say what they are called in Singlish.
+	if code, err := os.ReadFile(t.goFile); err == nil {
+		_, sourceMap := codegen.ParseLineDirectives(string(code), false)
+		output = sourceMap.Explain(output)
+	}
+	if err == nil {
+		fmt.Fprint(os.Stderr, output)
+		return nil
+	}
+
+	if machineDiagnostics() {
+		if diags := reporting.ParseCompilerOutput(output); len(diags) > 0 {
+			collect(diags...)
+		} else {
+			collectError(t.input, fmt.Errorf("go build failed: %s", strings.TrimSpace(output)))
+		}
+		return err
+	}
+	printInsult(insults.Major)
+	fmt.Fprint(os.Stderr, output)
+	return err
 }
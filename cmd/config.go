@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/config"
+)
+
+// ConfigPath names the configuration file given with --config. Without it,
+// singlish.toml is looked for next to the source file and above it.
+var ConfigPath string
+
+// project is the configuration in effect; it is empty if there is none.
+// Flags and environment variables override it.
+var project = &config.Config{}
+
+// loadProject reads the configuration for the command line args, which
+// start with the command name.
+func loadProject(args []string) error {
+	var c *config.Config
+	var err error
+	if ConfigPath != "" {
+		c, err = config.Load(ConfigPath)
+	} else {
+		c, err = config.Discover(configStart(args))
+	}
+	if err != nil {
+		return err
+	}
+	project = c
+	return nil
+}
+
+// configStart returns where the search for singlish.toml starts: the first
+// .singlish file or directory the command names, or else the current
+// directory.
+func configStart(args []string) string {
+	if len(args) > 1 {
+		for _, arg := range args[1:] {
+			if arg == "--" {
+				break
+			}
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if strings.HasSuffix(arg, ".singlish") {
+				return arg
+			}
+			if info, err := os.Stat(arg); err == nil && info.IsDir() {
+				return arg
+			}
+		}
+	}
+	return "."
+}
+
+// withEntry returns args, or the project's entry file if args is empty.
+func withEntry(args []string) []string {
+	if len(args) == 0 && project.Entry != "" {
+		return []string{project.Entry}
+	}
+	return args
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/rickchow/singlish/pkg/cache"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/reporting"
+)
+
+// DiagnosticsFormat selects how errors are reported: "text" for people, or
+// "json" or "sarif" for CI. In the machine-readable formats nothing is
+// printed as problems are found; instead every diagnostic of the run is
+// written to standard error as one document when the command finishes.
+var DiagnosticsFormat = "text"
+
+// diagnosticsFormats lists the values accepted by --diagnostics-format.
+var diagnosticsFormats = []string{"text", "json", "sarif"}
+
+// collected holds the diagnostics of this run in the machine-readable formats.
+var collected []reporting.Diagnostic
+
+// machineDiagnostics reports whether diagnostics are being collected for a
+// JSON or SARIF document instead of printed.
+func machineDiagnostics() bool {
+	return DiagnosticsFormat != "text"
+}
+
+// collect records diagnostics for the document written by flushDiagnostics.
+func collect(diags ...reporting.Diagnostic) {
+	collected = append(collected, diags...)
+}
+
+// collectError records an error that is not tied to a source position.
+func collectError(filename string, err error) {
+	collect(reporting.Diagnostic{
+		Filename:   filename,
+		Diagnostic: lexer.Diagnostic{Message: err.Error(), Code: lexer.CodeGeneral},
+	})
+}
+
+// flushDiagnostics writes the collected diagnostics in the machine-readable
+// format, if one was chosen. An empty document is still written so that CI
+// always has something to parse.
+func flushDiagnostics() {
+	var err error
+	switch DiagnosticsFormat {
+	case "json":
+		err = reporting.WriteJSON(os.Stderr, collected)
+	case "sarif":
+		err = reporting.WriteSARIF(os.Stderr, collected, cache.ToolVersion())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to write diagnostics: %v\n", err)
+	}
+	collected = nil
+}
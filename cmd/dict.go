@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/migrate"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/textdiff"
+)
+
+const dictUsage = `Usage:
+  singlish dict <command> [args]
+
+Description:
+  Work with Singlish dictionaries.
+
+Commands:
+  check [file...]   Check dictionaries for mistakes: words defined twice,
+                    words that are not valid identifiers or hide Go keywords
+                    and builtins, Go the parser cannot handle, words that are
+                    also the Go of another word, and @extends or @remove
+                    directives that do not work. Without files, checks the
+                    dictionaries in use. Exits 1 if there are errors;
+                    warnings and notes alone do not fail the check.
+  show              Print the dictionary in use, after merging every layer,
+                    with the file and line each word comes from and the
+                    words that were removed.
+  migrate --to <dict> [--from <dict>] [--dry-run] <file | dir>...
+                    Rewrite the keywords of .singlish files, read with the
+                    --from dictionary (default: the one in use), to the
+                    words the --to dictionary uses for the same Go.
+                    Comments, strings and whitespace are left exactly as
+                    they are. Directories are searched recursively. Both
+                    flags may be repeated to layer dictionaries, and
+                    "default" names the built-in one. --dry-run (-n)
+                    prints a diff instead of changing any file.
+`
+
+func runDict(args []string) int {
+	if len(args) == 0 || isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, dictUsage)
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "\nError: missing dict command")
+			return 1
+		}
+		return 0
+	}
+
+	switch args[0] {
+	case "check":
+		return runDictCheck(args[1:])
+	case "show":
+		return runDictShow(args[1:])
+	case "migrate":
+		return runDictMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown dict command: %s\n", args[0])
+		return 1
+	}
+}
+
+func runDictCheck(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, dictUsage)
+		return 0
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths = dictionaryPaths()
+	}
+	if len(paths) == 0 {
+		return reportDictCheck(dictionaries.BuiltIn+" dictionary", "", dictionaries.Validate(dictionaries.DefaultEntries()))
+	}
+	code := 0
+	for _, path := range paths {
+		source, diags, err := dictionaries.CheckFile(path)
+		if err != nil {
+			printErrorWithInsult(fmt.Errorf("failed to read dictionary: %w", err))
+			code = 1
+			continue
+		}
+		code = max(code, reportDictCheck(path, source, diags))
+	}
+	return code
+}
+
+// reportDictCheck shows the problems found in the dictionary at path and
+// returns the exit code: 1 if any of them is an error.
+func reportDictCheck(path, source string, diags []lexer.Diagnostic) int {
+	errs, warnings := 0, 0
+	for _, d := range diags {
+		switch d.Severity {
+		case lexer.SeverityError:
+			errs++
+		case lexer.SeverityWarning:
+			warnings++
+		}
+	}
+
+	if machineDiagnostics() {
+		collect(reporting.InFile(path, diags)...)
+	} else if len(diags) > 0 {
+		printer := &reporting.Printer{Filename: path, Color: reporting.UseColor(os.Stderr), Context: 0}
+		printer.PrintAll(os.Stderr, source, diags)
+		fmt.Fprintln(os.Stderr)
+	}
+	if errs > 0 {
+		printInsult(insults.Minor)
+	}
+	fmt.Fprintf(os.Stdout, "%s: %d error(s), %d warning(s)\n", path, errs, warnings)
+	if errs > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runDictShow(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, dictUsage)
+		return 0
+	}
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: dict show takes no arguments; use --dictionary to pick dictionaries")
+		return 1
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+
+	entries := dict.Entries()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Word < entries[j].Word })
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORD\tGO\tFROM")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Word, e.Target, origin(e.Origin, e.Line))
+	}
+	w.Flush()
+
+	if removed := dict.Removed(); len(removed) > 0 {
+		fmt.Fprintln(os.Stdout, "\nRemoved:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, r := range removed {
+			fmt.Fprintf(w, "  %s\t%s\n", r.Word, origin(r.Origin, r.Line))
+		}
+		w.Flush()
+	}
+	return 0
+}
+
+// origin formats where a dictionary entry was defined.
+func origin(file string, line int) string {
+	if line == 0 {
+		return file
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+func runDictMigrate(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, dictUsage)
+		return 0
+	}
+
+	var fromPaths, toPaths, inputs []string
+	dryRun := false
+	for i := 0; i < len(args); i++ {
+		if value, ok, err := flagValue(args, &i, "--from"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			fromPaths = append(fromPaths, value)
+		} else if value, ok, err := flagValue(args, &i, "--to"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			toPaths = append(toPaths, value)
+		} else if args[i] == "--dry-run" || args[i] == "-n" {
+			dryRun = true
+		} else if strings.HasPrefix(args[i], "-") {
+			fmt.Fprintf(os.Stderr, "Error: unknown flag %s\nRun 'singlish dict --help' for usage.\n", args[i])
+			return 1
+		} else {
+			inputs = append(inputs, args[i])
+		}
+	}
+	if len(toPaths) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: dict migrate needs --to, the dictionary to migrate to")
+		return 1
+	}
+	if len(inputs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: dict migrate needs the files or directories to migrate")
+		return 1
+	}
+
+	from, err := loadLayers(fromPaths)
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load --from dictionary: %w", err))
+		return 1
+	}
+	to, err := loadLayers(toPaths)
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load --to dictionary: %w", err))
+		return 1
+	}
+	files, err := singlishFiles(inputs)
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+
+	code, rewritten, changed := 0, 0, 0
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+			continue
+		}
+		migrated, changes, diags := migrate.Source(string(content), from, to)
+		if machineDiagnostics() {
+			collect(reporting.InFile(path, diags)...)
+		} else if len(diags) > 0 {
+			printer := &reporting.Printer{Filename: path, Color: reporting.UseColor(os.Stderr), Context: 0}
+			printer.PrintAll(os.Stderr, string(content), diags)
+		}
+		if slices.ContainsFunc(diags, func(d lexer.Diagnostic) bool { return d.Severity == lexer.SeverityError }) {
+			printInsult(insults.Minor)
+			code = 1
+			continue
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		rewritten += len(changes)
+		changed++
+		if dryRun {
+			fmt.Fprint(os.Stdout, textdiff.Unified(path, path+" (migrated)", string(content), migrated))
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil {
+			err = os.WriteFile(path, []byte(migrated), info.Mode().Perm())
+		}
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+		}
+	}
+
+	if dryRun {
+		fmt.Fprintf(os.Stderr, "%d keyword(s) in %d of %d file(s) would change\n", rewritten, changed, len(files))
+	} else {
+		fmt.Fprintf(os.Stderr, "Rewrote %d keyword(s) in %d of %d file(s)\n", rewritten, changed, len(files))
+	}
+	return code
+}
+
+// loadLayers loads the dictionary made of the files at paths, in which
+// "default" names the built-in dictionary. Without paths it loads the
+// dictionary in use.
+func loadLayers(paths []string) (*dictionaries.Dictionary, error) {
+	if len(paths) == 0 {
+		return loadDictionary()
+	}
+	if len(paths) == 1 && paths[0] == dictionaries.DefaultName {
+		return dictionaries.NewDefaultDictionary(), nil
+	}
+	if slices.Contains(paths, dictionaries.DefaultName) {
+		return nil, fmt.Errorf("%q can only be given on its own; use @extends default to layer on it", dictionaries.DefaultName)
+	}
+	return dictionaries.Load(paths...)
+}
+
+// singlishFiles returns the files named by inputs, and the .singlish files
+// in the directories among them, skipping hidden directories.
+func singlishFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+		err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && path != input && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && strings.HasSuffix(path, ".singlish") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
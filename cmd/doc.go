@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/docgen"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+const docUsage = `Usage:
+  singlish doc [--go] [-o file.md] [<dir>...]
+  singlish doc --html -o <site dir> [--go] [<dir>...]
+
+Description:
+  Document Singlish packages, as go doc does for Go. Every directory with
+  .singlish files under the given directories, or the current directory,
+  is a package. The exported actions, patterns, got variables and confirm
+  constants of each package, those whose names start with a capital letter,
+  are listed as they are written, with the comments just above them, less
+  the unexported fields of patterns. The comment above kampung describes
+  the package. Test files are left out.
+
+  By default Markdown is printed to standard output.
+
+Flags:
+  --go          Also show the Go each declaration becomes; side by side in
+                HTML
+  --html        Write a static HTML site, with an index.html and a page per
+                package, into the directory given by -o
+  -o <path>     Write the Markdown to this file, or the HTML site to this
+                directory
+`
+
+func runDoc(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, docUsage)
+		return 0
+	}
+
+	var opts docgen.Options
+	var output string
+	var html bool
+	var inputs []string
+	for i := 0; i < len(args); i++ {
+		if value, ok, err := flagValue(args, &i, "-o"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			output = value
+		} else if args[i] == "--go" {
+			opts.Go = true
+		} else if args[i] == "--html" {
+			html = true
+		} else if strings.HasPrefix(args[i], "-") {
+			fmt.Fprintf(os.Stderr, "Error: unknown flag %s\nRun 'singlish doc --help' for usage.\n", args[i])
+			return 1
+		} else {
+			inputs = append(inputs, args[i])
+		}
+	}
+	if html && output == "" {
+		fmt.Fprintln(os.Stderr, "Error: --html needs -o, the directory to write the site into")
+		return 1
+	}
+	if len(inputs) == 0 {
+		inputs = []string{"."}
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+	dirs, err := packageDirs(inputs)
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+	if len(dirs) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no .singlish files in %s\n", strings.Join(inputs, ", "))
+		return 1
+	}
+
+	var pkgs []*docgen.Package
+	for _, dir := range dirs {
+		var files []docgen.File
+		for _, path := range dir.files {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				printErrorWithInsult(err)
+				return 1
+			}
+			program, err := transpiler.ParseFile(string(content), dict)
+			if err != nil {
+				handleError(err, path)
+				return 1
+			}
+			files = append(files, docgen.File{Path: path, Source: string(content), Program: program})
+		}
+		pkg, err := docgen.Collect(dir.path, files, dict)
+		if err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	if html {
+		err = docgen.HTML(output, pkgs, opts)
+	} else if output != "" {
+		var f *os.File
+		if f, err = os.Create(output); err == nil {
+			err = docgen.Markdown(f, pkgs, opts)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	} else {
+		err = docgen.Markdown(os.Stdout, pkgs, opts)
+	}
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+	if output != "" {
+		fmt.Fprintf(os.Stderr, "Documented %d package(s) in %s\n", len(pkgs), output)
+	}
+	return 0
+}
+
+// packageDir is a directory of Singlish source files.
+type packageDir struct {
+	path  string
+	files []string // sorted; tests are left out
+}
+
+// packageDirs returns the directories under inputs that hold .singlish
+// files, skipping hidden directories and testdata, in order of path.
+func packageDirs(inputs []string) ([]packageDir, error) {
+	byPath := make(map[string]*packageDir)
+	for _, input := range inputs {
+		err := filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != input && (strings.HasPrefix(d.Name(), ".") || d.Name() == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".singlish") || strings.HasSuffix(path, "_test.singlish") {
+				return nil
+			}
+			dir := filepath.Dir(path)
+			if byPath[dir] == nil {
+				byPath[dir] = &packageDir{path: dir}
+			}
+			byPath[dir].files = append(byPath[dir].files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	var dirs []packageDir
+	for _, d := range byPath {
+		sort.Strings(d.files)
+		dirs = append(dirs, *d)
+	}
+	sort.Slice(dirs, func(i, j int) bool { return dirs[i].path < dirs[j].path })
+	return dirs, nil
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/examples"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/workspace"
+)
+
+const examplesUsage = `Usage:
+  singlish examples [flags] [dir | files...]
+
+Description:
+  Build and run every example (default: the examples directory) and check
+  each one against its expectations:
+
+    // expect: <text>         one line of expected stdout, in order
+    // expect-error: <text>   the program must fail with <text> on stderr
+    // skip: <reason>         do not run this example
+    foo.golden                exact expected stdout of foo.singlish
+
+  Examples without expectations pass if they exit successfully.
+
+Flags:
+  -j <n>             Number of examples to run at once (default: number of CPUs)
+  --timeout <dur>    Time limit for running each example (default: 10s)
+  --update           Rewrite the .golden files of passing examples with their
+                     current output; create an empty foo.golden to start one
+`
+
+func runExamples(args []string) int {
+	fs := flag.NewFlagSet("examples", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	parallel := fs.Int("j", runtime.NumCPU(), "")
+	timeout := fs.Duration("timeout", 10*time.Second, "")
+	update := fs.Bool("update", false, "")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(os.Stdout, examplesUsage)
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n\n%s", err, examplesUsage)
+		return 1
+	}
+	if fs.NArg() > 0 && isHelpFlag(fs.Arg(0)) {
+		fmt.Fprint(os.Stdout, examplesUsage)
+		return 0
+	}
+
+	files, err := exampleFiles(fs.Args())
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+	if len(files) == 0 {
+		printErrorWithInsult(errors.New("no .singlish examples found"))
+		return 1
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+
+	var specs []*examples.Spec
+	for _, f := range files {
+		spec, err := examples.Load(f)
+		if err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		if *update && spec.Golden != "" {
+			// Regenerate golden files from scratch rather than checking them.
+			spec.Stdout, spec.HasStdout = "", false
+		}
+		specs = append(specs, spec)
+	}
+
+	results := examples.RunAll(context.Background(), specs, exampleRunner(dict, *timeout), *parallel)
+
+	if *update {
+		for _, r := range results {
+			// Only examples that already have a golden file are rewritten;
+			// the others have no exact output to keep on purpose.
+			if r.Status != examples.Pass || r.Spec.Golden == "" || r.Spec.HasExpectDirectives() || len(r.Spec.Errors) > 0 {
+				continue
+			}
+			if err := os.WriteFile(examples.GoldenPath(r.Spec.Path), []byte(r.Outcome.Stdout), 0644); err != nil {
+				printErrorWithInsult(err)
+				return 1
+			}
+		}
+	}
+
+	examples.Report(os.Stdout, results)
+	if examples.Failed(results) {
+		printInsult(insults.Major)
+		return 1
+	}
+	return 0
+}
+
+// exampleFiles expands the command line into a sorted list of example files.
+func exampleFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		args = []string{"examples"}
+	}
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(arg, "*.singlish"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// exampleRunner transpiles and builds each example in its own workspace, then
+// runs the binary with the given time limit. Build errors are reported
+// against Singlish lines.
+func exampleRunner(dict *dictionaries.Dictionary, timeout time.Duration) examples.RunFunc {
+	return func(ctx context.Context, path string) examples.Outcome {
+		ws, err := workspace.New(dict)
+		if err != nil {
+			return examples.Outcome{Err: err}
+		}
+		defer ws.Close()
+
+		if _, err := ws.Add(path); err != nil {
+			var stderr bytes.Buffer
+			var tErr *transpiler.TranspilationError
+			if content, readErr := os.ReadFile(path); errors.As(err, &tErr) && readErr == nil {
+				reporting.PrintDiagnostics(&stderr, string(content), tErr.Diagnostics)
+			} else {
+				fmt.Fprintf(&stderr, "Error: %v\n", err)
+			}
+			return examples.Outcome{Stderr: stderr.String(), ExitCode: 1}
+		}
+
+		binary := filepath.Join(ws.Dir, "example")
+		build := exec.CommandContext(ctx, "go", "build", "-o", binary, ".")
+		build.Dir = ws.Dir
+		output, err := build.CombinedOutput()
+		if err != nil {
+			return examples.Outcome{Stderr: mapLines(ws, string(output)), ExitCode: 1}
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(runCtx, binary)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		cmd.WaitDelay = time.Second
+		err = cmd.Run()
+
+		out := examples.Outcome{Stdout: stdout.String(), Stderr: mapLines(ws, stderr.String())}
+		var exitErr *exec.ExitError
+		switch {
+		case runCtx.Err() == context.DeadlineExceeded:
+			out.TimedOut = true
+		case errors.As(err, &exitErr):
+			out.ExitCode = exitErr.ExitCode()
+		case err != nil:
+			out.Err = err
+		}
+		return out
+	}
+}
+
+// mapLines points every generated Go position in text back at Singlish.
+func mapLines(ws *workspace.Workspace, text string) string {
+	if text == "" {
+		return ""
+	}
+	var mapped strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		mapped.WriteString(ws.MapLine(line) + "\n")
+	}
+	return mapped.String()
+}
@@ -1,19 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/rickchow/singlish/pkg/formatter"
-	"github.com/rickchow/singlish/pkg/lexer"
-	"github.com/rickchow/singlish/pkg/parser"
+	"github.com/rickchow/singlish/pkg/transpiler"
 )
 
 const fmtUsage = `Usage:
-  singlish fmt <file>
+  singlish fmt [--watch] [--indent <n>] [--imports] <file>
 
 Description:
   Format the Singlish source file using canonical Singlish keywords and standard indentation.
+
+Flags:
+  --watch        Reformat the file every time it is saved
+  --indent <n>   Indent with n spaces instead of tabs; 0 means tabs
+                 (default: indent in the [fmt] table of singlish.toml)
+  --imports      Rewrite the dapao block: add the standard packages the code
+                 uses and remove the ones it does not, as singlish build does
 `
 
 func runFmt(args []string) int {
@@ -26,58 +34,91 @@ func runFmt(args []string) int {
 		return 0
 	}
 
+	watchMode, args := stripWatchFlag(args)
+	opts := formatter.Options{Indent: project.Fmt.Indent}
+	var files []string
+	for i := 0; i < len(args); i++ {
+		if value, ok, err := flagValue(args, &i, "--indent"); ok {
+			if err == nil {
+				opts.Indent, err = strconv.Atoi(value)
+			}
+			if err != nil || opts.Indent < 0 {
+				fmt.Fprintf(os.Stderr, "Error: --indent needs a number of spaces, got %q\n", value)
+				return 1
+			}
+			continue
+		}
+		if args[i] == "--imports" {
+			opts.Imports = true
+			continue
+		}
+		files = append(files, args[i])
+	}
+	args = files
+	if len(args) == 0 {
+		fmt.Fprint(os.Stdout, fmtUsage)
+		fmt.Fprintln(os.Stderr, "\nError: missing input file")
+		return 1
+	}
+
 	inputFile := args[0]
-	if err := formatFile(inputFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if watchMode {
+		paths := func() []string {
+			return append([]string{inputFile}, dictionaryFiles()...)
+		}
+		return watchLoop(paths, func(ctx context.Context, changed []string) {
+			rewritten, err := formatFile(inputFile, opts)
+			if err != nil {
+				handleError(err, inputFile)
+			} else if rewritten {
+				fmt.Fprintf(os.Stderr, "[watch] formatted %s\n", inputFile)
+			}
+		})
+	}
+
+	if _, err := formatFile(inputFile, opts); err != nil {
+		handleError(err, inputFile)
 		return 1
 	}
 
 	return 0
 }
 
-func formatFile(inputPath string) error {
+// formatFile formats inputPath in place and reports whether its contents
+// changed. An already formatted file is left untouched.
+func formatFile(inputPath string, opts formatter.Options) (bool, error) {
 	// Read input
 	content, err := os.ReadFile(inputPath)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
+		return false, fmt.Errorf("failed to read input file: %w", err)
 	}
 
 	// Load dictionary
 	dict, err := loadDictionary()
 	if err != nil {
-		return fmt.Errorf("failed to load dictionary: %w", err)
+		return false, fmt.Errorf("failed to load dictionary: %w", err)
 	}
 
-	// Lex
-	keywords := make(map[string]struct{})
-	for _, k := range dict.Keys() {
-		keywords[k] = struct{}{}
-	}
-	// Ensure 'ki' is treated as a keyword
-	keywords["ki"] = struct{}{}
-
-	tokens, diagnostics := lexer.Lex(string(content), keywords)
-	if len(diagnostics) > 0 {
-		return fmt.Errorf("lexer error: %v", diagnostics[0].Message)
-	}
-
-	// Parse
-	p := parser.New(tokens, dict)
-	program := p.ParseProgram()
-	if len(p.Errors()) > 0 {
-		return fmt.Errorf("parser error: %v", p.Errors()[0])
+	// Lex and parse
+	program, err := transpiler.Parse(string(content), dict)
+	if err != nil {
+		return false, err
 	}
 
 	// Format
-	formatted, err := formatter.Format(program, dict)
+	formatted, err := formatter.FormatWith(program, dict, opts)
 	if err != nil {
-		return fmt.Errorf("formatting failed: %w", err)
+		return false, fmt.Errorf("formatting failed: %w", err)
+	}
+
+	if formatted == string(content) {
+		return false, nil
 	}
 
 	// Write back to file
 	if err := os.WriteFile(inputPath, []byte(formatted), 0644); err != nil {
-		return fmt.Errorf("failed to write formatted file: %w", err)
+		return false, fmt.Errorf("failed to write formatted file: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
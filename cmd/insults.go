@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/rickchow/singlish/pkg/insults"
+)
+
+// InsultLevel caps how harsh the line printed with errors may be: "off",
+// "mild" or "garang". Empty means the project's setting, or else "garang".
+var InsultLevel string
+
+// InsultPackPath names a file of insults to use instead of the built-in
+// ones. Empty means the project's setting, if any.
+var InsultPackPath string
+
+// insulter picks the lines; it is set up by setupInsults.
+var insulter *insults.Engine
+
+// setupInsults builds the insult engine from the global flags, the project
+// configuration and $SINGLISH_SEED.
+func setupInsults() error {
+	level, err := insults.ParseLevel(cmp.Or(InsultLevel, project.InsultLevel, insults.Garang.String()))
+	if err != nil {
+		return err
+	}
+	pack := insults.Default()
+	if path := cmp.Or(InsultPackPath, project.InsultPack); path != "" {
+		if pack, err = insults.LoadPack(path); err != nil {
+			return err
+		}
+	}
+	seed, err := insults.Seed()
+	if err != nil {
+		return err
+	}
+	insulter = insults.New(level, pack, seed)
+	return nil
+}
+
+// insult returns a line for an error of severity sev, or "" if insults are
+// off.
+func insult(sev insults.Severity) string {
+	return insulter.Pick(sev)
+}
+
+// printInsult prints a line for an error of severity sev, except when
+// diagnostics are going to a machine-readable document.
+func printInsult(sev insults.Severity) {
+	if machineDiagnostics() {
+		return
+	}
+	if line := insult(sev); line != "" {
+		fmt.Fprintf(os.Stderr, "%s\n", line)
+	}
+}
+
+// executeWithInsults runs the command and wraps stderr with an insult if it fails.
+func executeWithInsults(cmd *exec.Cmd) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// Command failed, print insult then error
+		printInsult(insults.Major)
+		fmt.Fprint(os.Stderr, stderr.String())
+		// The command may not have started at all, as with a binary built
+		// for another platform; its own stderr is then empty.
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		return err
+	}
+
+	// Command succeeded, just print any stderr output (e.g. warnings)
+	if stderr.Len() > 0 {
+		fmt.Fprint(os.Stderr, stderr.String())
+	}
+	return nil
+}
+
+// printErrorWithInsult prints a mild insult followed by the error message.
+func printErrorWithInsult(err error) {
+	if machineDiagnostics() {
+		collectError("", err)
+		return
+	}
+	printInsult(insults.Minor)
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/repl"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+const replUsage = `Usage:
+  singlish repl
+
+Description:
+  Start an interactive Singlish session. Enter statements, expressions or
+  declarations one at a time; got variables and actions are remembered.
+  Blocks spanning several lines are read until their braces are closed.
+
+  Entries run in the interpreter of singlish run --interp, which keeps
+  variables and their values for the whole session, so each entry runs
+  once. Before that, the session is type-checked as the Go it translates
+  to. Packages the interpreter does not support cannot be used.
+
+Commands:
+  :go      Show the Go program generated for the last input
+  :doc w   Explain the Singlish word w
+  :reset   Forget everything declared so far
+  :help    Show this help
+  :quit    Leave the session (Ctrl-D also works)
+`
+
+const (
+	replPrompt         = "singlish> "
+	replContinuePrompt = "      ... "
+)
+
+func runRepl(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, replUsage)
+		return 0
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+
+	session := repl.NewSession(dict, os.Stdout, os.Stderr)
+	return replLoop(session, dict, os.Stdin, os.Stdout, os.Stderr)
+}
+
+func replLoop(session *repl.Session, dict *dictionaries.Dictionary, in io.Reader, out, errOut io.Writer) int {
+	scanner := bufio.NewScanner(in)
+	var pending strings.Builder
+
+	fmt.Fprint(out, replPrompt)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if word, ok := strings.CutPrefix(strings.TrimSpace(line), ":doc"); ok && pending.Len() == 0 {
+			word = strings.Join(strings.Fields(word), " ") // phrases, such as "see how"
+			if text, found := describeWord(dict, word); found {
+				fmt.Fprint(out, text)
+			} else {
+				fmt.Fprintf(errOut, "%q is not a Singlish word lah.\n", word)
+			}
+			fmt.Fprint(out, replPrompt)
+			continue
+		}
+
+		if pending.Len() == 0 {
+			switch strings.TrimSpace(line) {
+			case "":
+				fmt.Fprint(out, replPrompt)
+				continue
+			case ":quit", ":q", ":exit":
+				return 0
+			case ":help":
+				fmt.Fprint(out, replUsage)
+				fmt.Fprint(out, replPrompt)
+				continue
+			case ":reset":
+				session.Reset()
+				fmt.Fprint(out, replPrompt)
+				continue
+			case ":go":
+				if code := session.LastGo(); code != "" {
+					fmt.Fprint(out, code)
+				} else {
+					fmt.Fprintln(errOut, "Nothing run yet lah.")
+				}
+				fmt.Fprint(out, replPrompt)
+				continue
+			}
+		}
+
+		pending.WriteString(line)
+		pending.WriteString("\n")
+		if repl.Depth(pending.String()) > 0 {
+			fmt.Fprint(out, replContinuePrompt)
+			continue
+		}
+
+		input := pending.String()
+		pending.Reset()
+
+		if err := session.Eval(input); err != nil {
+			var tErr *transpiler.TranspilationError
+			if errors.As(err, &tErr) {
+				reporting.PrintDiagnostics(errOut, input, tErr.Diagnostics)
+			} else {
+				if line := insult(insults.Major); line != "" {
+					fmt.Fprintln(errOut, line)
+				}
+				fmt.Fprintln(errOut, err)
+			}
+		}
+		fmt.Fprint(out, replPrompt)
+	}
+	fmt.Fprintln(out)
+	return 0
+}
+
+// describeWord explains a dictionary word from its metadata:
+//
+//	balek (keyword): return
+//	  Malay for "go back", as in balek kampung.
+//	  e.g. balek a + b
+func describeWord(dict *dictionaries.Dictionary, word string) (string, bool) {
+	e, found := dict.Entry(word)
+	if !found {
+		return "", false
+	}
+	var b strings.Builder
+	b.WriteString(e.Word)
+	if e.Category != "" {
+		fmt.Fprintf(&b, " (%s)", e.Category)
+	}
+	fmt.Fprintf(&b, ": %s\n", e.Target)
+	if e.Description != "" {
+		fmt.Fprintf(&b, "  %s\n", e.Description)
+	}
+	if e.Example != "" {
+		fmt.Fprintf(&b, "  e.g. %s\n", e.Example)
+	}
+	return b.String(), true
+}
@@ -3,10 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 )
 
-var DictionaryPath string
+// DictionaryPaths are the dictionary files given with --dictionary, merged
+// in order.
+var DictionaryPaths []string
 
 const usageBanner = `singlish - Singlish to Go CLI
 
@@ -14,13 +17,36 @@ Usage:
   singlish [global flags] <command> [args]
 
 Global Flags:
-  --dictionary <path>   Path to dictionary file (default: dictionary.txt)
+  --dictionary <path>   Dictionary file to use instead of the built-in one.
+                        Repeat to layer several files, later ones
+                        overriding earlier ones
+  --diagnostics-format <text|json|sarif>
+                        How to report errors (default: text). json and sarif
+                        write one document to stderr when the command ends,
+                        for CI to annotate pull requests with
+  --insult-level <off|mild|garang>
+                        How rude to be about errors (default: garang). mild
+                        keeps it polite for demos; off stops the insults
+  --insult-pack <path>  File of insults to use instead of the built-in ones
+  --config <path>       Configuration file to use instead of the singlish.toml
+                        found next to the source file or above it
+
+Flags override singlish.toml, which sets defaults for a project:
+dictionaries, insults, the entry file, build flags and fmt options.
 
 Commands:
   build       Transpile and build a binary from a .singlish file
+  cache       Show or clean the transpilation cache
+  check       Type-check .singlish files without building them
+  dict        Check dictionaries or show the one in use
+  doc         Generate Markdown or HTML documentation for packages
+  examples    Run examples and check their expected output
   fmt         Format Singlish source code
+  repl        Start an interactive Singlish session
   run         Transpile and run a .singlish file
+  test        Run *_test.singlish tests with go test
   transpile   Emit the generated Go file without building
+  vet         Report suspicious code, such as code after balek
 
 Use "singlish <command> --help" for more information about a command.
 `
@@ -30,42 +56,138 @@ func Execute(args []string) int {
 	var cleanArgs []string
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		if arg == "--dictionary" {
-			if i+1 < len(args) {
-				DictionaryPath = args[i+1]
-				i++ // skip value
-			} else {
-				fmt.Fprintln(os.Stderr, "Error: --dictionary flag requires an argument")
+		if value, ok, err := flagValue(args, &i, "--dictionary"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			DictionaryPaths = append(DictionaryPaths, value)
+		} else if value, ok, err := flagValue(args, &i, "--diagnostics-format"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			if !slices.Contains(diagnosticsFormats, value) {
+				fmt.Fprintf(os.Stderr, "Error: unknown diagnostics format %q (want %s)\n", value, strings.Join(diagnosticsFormats, ", "))
+				return 1
+			}
+			DiagnosticsFormat = value
+		} else if value, ok, err := flagValue(args, &i, "--insult-level"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				return 1
 			}
-		} else if strings.HasPrefix(arg, "--dictionary=") {
-			DictionaryPath = strings.TrimPrefix(arg, "--dictionary=")
+			InsultLevel = value
+		} else if value, ok, err := flagValue(args, &i, "--insult-pack"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			InsultPackPath = value
+		} else if value, ok, err := flagValue(args, &i, "--config"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			ConfigPath = value
 		} else {
 			cleanArgs = append(cleanArgs, arg)
 		}
 	}
 	args = cleanArgs
 
+	if err := loadProject(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := setupInsults(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	if len(args) == 0 || isHelpFlag(args[0]) {
 		printUsage(os.Stdout)
 		return 0
 	}
 
+	code := runCommand(args)
+	flushDiagnostics()
+	return code
+}
+
+// globalFlags returns the global flags Execute was given, to pass on to
+// another run of singlish.
+func globalFlags() []string {
+	var args []string
+	for _, path := range DictionaryPaths {
+		args = append(args, "--dictionary", path)
+	}
+	if DiagnosticsFormat != "text" {
+		args = append(args, "--diagnostics-format", DiagnosticsFormat)
+	}
+	if InsultLevel != "" {
+		args = append(args, "--insult-level", InsultLevel)
+	}
+	if InsultPackPath != "" {
+		args = append(args, "--insult-pack", InsultPackPath)
+	}
+	if ConfigPath != "" {
+		args = append(args, "--config", ConfigPath)
+	}
+	return args
+}
+
+// runCommand runs the command named by args[0].
+func runCommand(args []string) int {
 	switch args[0] {
 	case "build":
 		return runBuild(args[1:])
+	case "cache":
+		return runCache(args[1:])
+	case "check":
+		return runCheck(args[1:])
+	case "dict":
+		return runDict(args[1:])
+	case "doc":
+		return runDoc(args[1:])
+	case "examples":
+		return runExamples(args[1:])
 	case "fmt":
 		return runFmt(args[1:])
+	case "repl":
+		return runRepl(args[1:])
 	case "run":
 		return runRun(args[1:])
+	case "test":
+		return runTest(args[1:])
 	case "transpile":
 		return runTranspile(args[1:])
+	case "vet":
+		return runVet(args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
 		return 1
 	}
 }
 
+// flagValue reports whether args[*i] is the flag name, given as "name value"
+// or "name=value", and returns its value. In the first form *i is advanced
+// past the value.
+func flagValue(args []string, i *int, name string) (value string, ok bool, err error) {
+	arg := args[*i]
+	if value, ok := strings.CutPrefix(arg, name+"="); ok {
+		return value, true, nil
+	}
+	if arg != name {
+		return "", false, nil
+	}
+	if *i+1 >= len(args) {
+		return "", true, fmt.Errorf("%s flag requires an argument", name)
+	}
+	*i++
+	return args[*i], true, nil
+}
+
 func printUsage(out *os.File) {
 	fmt.Fprint(out, usageBanner)
 }
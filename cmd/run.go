@@ -1,48 +1,172 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/interp"
+	"github.com/rickchow/singlish/pkg/transpiler"
 )
 
 const runUsage = `Usage:
-  singlish run <file>
+  singlish run [--interp] [--watch] [<file> [args...]]
 
 Description:
-  Transpile and run a .singlish file.
+  Transpile and run a .singlish file. Without a file, runs the entry file
+  set in singlish.toml.
+
+Flags:
+  --interp   Run the program with the built-in interpreter instead of the Go
+             toolchain. Starts instantly, but only supports the standard
+             library packages it knows about (fmt, strings, strconv, math,
+             time, sort, sync, errors, os, unicode, ...).
+  --watch    Rerun the program whenever the file or the dictionary changes,
+             stopping the previous run first.
 `
 
 func runRun(args []string) int {
-	if len(args) == 0 || isHelpFlag(args[0]) {
+	if len(args) > 0 && isHelpFlag(args[0]) {
 		fmt.Fprint(os.Stdout, runUsage)
-		if len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "\nError: missing input file")
-			return 1
-		}
 		return 0
 	}
 
+	useInterp, watchMode := false, false
+	for len(args) > 0 {
+		if args[0] == "--interp" || args[0] == "-interp" {
+			useInterp = true
+		} else if args[0] == "--watch" || args[0] == "-watch" {
+			watchMode = true
+		} else {
+			break
+		}
+		args = args[1:]
+	}
+	args = withEntry(args)
+	if len(args) == 0 {
+		fmt.Fprint(os.Stdout, runUsage)
+		fmt.Fprintln(os.Stderr, "\nError: missing input file")
+		return 1
+	}
+
 	inputFile := args[0]
-	tempPath, err := transpileToTemp(inputFile)
-	if err != nil {
-		handleError(err, inputFile)
+	if watchMode {
+		return runWatching(inputFile, args[1:], useInterp)
+	}
+	if useInterp {
+		return runInterpreted(inputFile, args[1:])
+	}
+
+	binary, cleanup, ok := compileProgram(context.Background(), inputFile)
+	if !ok {
 		return 1
 	}
-	defer os.Remove(tempPath)
+	defer cleanup()
 
-	// Run go run
-	// Pass remaining args if any (though US-008 doesn't strictly require it, it's good practice)
-	// But go run syntax is `go run [build flags] <files> [arguments...]`
-	goArgs := append([]string{"run", tempPath}, args[1:]...)
-	cmd := exec.Command("go", goArgs...)
+	cmd := exec.Command(binary, args[1:]...)
 	cmd.Stdout = os.Stdout
 	cmd.Stdin = os.Stdin
 	// Stderr handled by executeWithInsults
 
 	if err := executeWithInsults(cmd); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// runInterpreted runs inputFile with the tree-walking interpreter.
+func runInterpreted(inputFile string, programArgs []string) int {
+	content, err := os.ReadFile(inputFile)
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to read input file: %w", err))
+		return 1
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
 		return 1
 	}
 
+	program, err := transpiler.ParseFile(string(content), dict)
+	if err != nil {
+		handleError(fmt.Errorf("transpilation failed: %w", err), inputFile)
+		return 1
+	}
+
+	it := interp.New(dict)
+	it.Args = append([]string{inputFile}, programArgs...)
+	if err := it.Run(program); err != nil {
+		var exitErr *interp.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.Code
+		}
+		var panicErr *interp.PanicError
+		if errors.As(err, &panicErr) {
+			printInsult(insults.Major)
+			fmt.Fprintf(os.Stderr, "%v\n", panicErr)
+			return 2
+		}
+		printErrorWithInsult(err)
+		return 1
+	}
+	return 0
+}
+
+// runWatching reruns inputFile every time it or the dictionary changes.
+func runWatching(inputFile string, programArgs []string, useInterp bool) int {
+	paths := func() []string {
+		return append([]string{inputFile}, dictionaryFiles()...)
+	}
+	return watchLoop(paths, func(ctx context.Context, changed []string) {
+		printWatchChange(changed)
+		code := runProgram(ctx, inputFile, programArgs, useInterp)
+		if ctx.Err() != nil {
+			return // restarted or interrupted
+		}
+		fmt.Fprintf(os.Stderr, "[watch] exited with status %d; waiting for changes\n", code)
+	})
+}
+
+// runProgram runs inputFile as a child process that is killed when ctx is
+// cancelled.
+func runProgram(ctx context.Context, inputFile string, programArgs []string, useInterp bool) int {
+	var cmd *exec.Cmd
+	if useInterp {
+		exe, err := os.Executable()
+		if err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		args := append(append(globalFlags(), "run", "--interp", inputFile), programArgs...)
+		cmd = exec.CommandContext(ctx, exe, args...)
+	} else {
+		binary, cleanup, ok := compileProgram(ctx, inputFile)
+		if !ok {
+			return 1
+		}
+		defer cleanup()
+		cmd = exec.CommandContext(ctx, binary, programArgs...)
+	}
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		printErrorWithInsult(err)
+		return 1
+	}
 	return 0
 }
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/workspace"
+)
+
+const testUsage = `Usage:
+  singlish test [--watch] [dir] [go test flags]
+
+Description:
+  Transpile the .singlish files in dir (default: current directory), including
+  *_test.singlish files, and run go test on them. Tests are written as
+  action TestXxx(t ki testing.T). File names and line numbers in the output
+  point back at the Singlish sources.
+
+Flags:
+  --watch   Rerun the tests whenever a .singlish file in dir or the dictionary
+            changes. Only the files that changed are transpiled again.
+
+Examples:
+  singlish test
+  singlish test ./mathlah -run TestAdd -v
+`
+
+func runTest(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, testUsage)
+		return 0
+	}
+
+	watchMode, args := stripWatchFlag(args)
+	dir := "."
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dir = args[0]
+		args = args[1:]
+	}
+
+	session := &testSession{dir: dir, goTestArgs: args}
+	defer session.close()
+
+	if watchMode {
+		paths := func() []string {
+			files, _ := session.files()
+			return append(files, dictionaryFiles()...)
+		}
+		return watchLoop(paths, func(ctx context.Context, changed []string) {
+			printWatchChange(changed)
+			if session.run(ctx, changed) == 0 && ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, "[watch] waiting for changes")
+			}
+		})
+	}
+	return session.run(context.Background(), nil)
+}
+
+// testSession keeps the transpiled package between runs so that watch mode
+// only has to transpile the files that changed.
+type testSession struct {
+	dir        string
+	goTestArgs []string
+	ws         *workspace.Workspace
+	sums       map[string][sha256.Size]byte // contents of the files in ws
+}
+
+// files lists the Singlish files of the package under test.
+func (s *testSession) files() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.singlish"))
+	sort.Strings(files)
+	return files, err
+}
+
+// run brings the workspace up to date and runs go test in it. changed lists
+// the files that triggered the run; everything is transpiled again when it
+// is nil or includes the dictionary.
+func (s *testSession) run(ctx context.Context, changed []string) int {
+	files, err := s.files()
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+	if !slices.ContainsFunc(files, workspace.IsTestFile) {
+		fmt.Fprintf(os.Stdout, "?   \t%s\t[no test files]\n", s.dir)
+		return 0
+	}
+
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+
+	reloadAll := changed == nil || slices.ContainsFunc(dictionaryFiles(), func(d string) bool {
+		return slices.Contains(changed, d)
+	})
+	if s.ws == nil || reloadAll {
+		s.close()
+		if s.ws, err = workspace.New(dict); err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		s.ws.Label = s.dir
+		s.sums = make(map[string][sha256.Size]byte)
+	}
+
+	failed := false
+	for path := range s.sums {
+		if !slices.Contains(files, path) {
+			s.ws.Remove(path)
+			delete(s.sums, path)
+		}
+	}
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			printErrorWithInsult(err)
+			failed = true
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if prev, ok := s.sums[f]; ok && prev == sum {
+			continue
+		}
+		delete(s.sums, f)
+
+		program, err := s.ws.Add(f)
+		if err != nil {
+			handleError(err, f)
+			s.ws.Remove(f)
+			failed = true
+			continue
+		}
+		if workspace.IsTestFile(f) {
+			if _, diags := workspace.TestFunctions(program, dict); len(diags) > 0 {
+				handleError(&transpiler.TranspilationError{Diagnostics: diags}, f)
+				failed = true
+				continue
+			}
+		}
+		s.sums[f] = sum
+	}
+	if failed {
+		return 1
+	}
+
+	// Keep a copy of the output so that compile errors and test failures
+	// can be reported as diagnostics.
+	var output bytes.Buffer
+	out := s.ws.Writer(io.MultiWriter(os.Stdout, &output))
+	cmd := exec.CommandContext(ctx, "go", append(append([]string{"test"}, s.goTestArgs...), ".")...)
+	cmd.Dir = s.ws.Dir
+	cmd.Stdout = out
+	cmd.Stderr = out
+	err = cmd.Run()
+	out.Close()
+	if err != nil {
+		if ctx.Err() != nil {
+			return 1
+		}
+		if machineDiagnostics() {
+			diags := append(reporting.ParseCompilerOutput(output.String()), reporting.ParseTestOutput(output.String())...)
+			if len(diags) == 0 {
+				collectError("", fmt.Errorf("go test failed: %w", err))
+			}
+			collect(diags...)
+		}
+		printInsult(insults.Major)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// close removes the session's workspace.
+func (s *testSession) close() {
+	if s.ws != nil {
+		s.ws.Close()
+		s.ws = nil
+	}
+}
@@ -1,42 +1,338 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/singlish"
+	"github.com/rickchow/singlish/pkg/textdiff"
+	"github.com/rickchow/singlish/pkg/transpiler"
 )
 
 const transpileUsage = `Usage:
-  singlish transpile <file>
+  singlish transpile [-o file.go] [<file>]
+  singlish transpile --out-dir <dir> <file | dir>...
 
 Description:
-  Emit the generated Go file without building.
+  Emit the generated Go code without building. By default the code is
+  printed to standard output. Without a file, transpiles the entry file set
+  in singlish.toml. Generated files start with
+  "// Code generated by singlish; DO NOT EDIT." and are gofmt-formatted,
+  so they can be committed for review.
+
+Flags:
+  -o <file.go>        Write the Go code for a single file to file.go
+  --out-dir <dir>     Write one .go file per .singlish file into dir. Source
+                      directories are walked recursively and their layout is
+                      mirrored, so src/shop/kopi.singlish becomes
+                      <dir>/shop/kopi.go when transpiling src. The files
+                      of a directory with the same kampung are transpiled
+                      together, so each sees what the others declare.
+  --check             Do not write anything; fail if the Go files given by -o
+                      or --out-dir are missing or out of date
 `
 
+// transpileJob is one Singlish file and where its Go code goes; dst is
+// empty for standard output.
+type transpileJob struct {
+	src string
+	dst string
+}
+
 func runTranspile(args []string) int {
-	if len(args) == 0 || isHelpFlag(args[0]) {
+	if len(args) > 0 && isHelpFlag(args[0]) {
 		fmt.Fprint(os.Stdout, transpileUsage)
-		if len(args) == 0 {
-			// If run without args, usually we show usage and exit non-zero or zero?
-			// US-008 says: "Negative: missing input file exits non-zero and prints a clear error"
-			// But that's if I invoke it expecting a file.
-			// Standard CLI: no args = usage = error?
-			// But `singlish --help` prints usage and returns 0.
-			// `singlish transpile` (no file) should probably error.
-			if len(args) == 0 {
-				fmt.Fprintln(os.Stderr, "\nError: missing input file")
-				return 1
+		return 0
+	}
+
+	var output, outDir string
+	var check bool
+	var inputs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case !strings.HasPrefix(arg, "-") || arg == "-":
+			inputs = append(inputs, arg)
+		case name == "check":
+			check = true
+		case name == "o" || name == "out-dir":
+			if !hasValue {
+				if i+1 >= len(args) {
+					fmt.Fprintf(os.Stderr, "Error: flag %s requires an argument\n", arg)
+					return 1
+				}
+				i++
+				value = args[i]
+			}
+			if name == "o" {
+				output = value
+			} else {
+				outDir = value
 			}
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown flag %s\nRun 'singlish transpile --help' for usage.\n", arg)
+			return 1
 		}
-		return 0
 	}
 
-	inputFile := args[0]
-	tempPath, err := transpileToTemp(inputFile)
+	inputs = withEntry(inputs)
+	var jobs []transpileJob
+	switch {
+	case len(inputs) == 0:
+		fmt.Fprintln(os.Stderr, "Error: missing input file")
+		return 1
+	case output != "" && outDir != "":
+		fmt.Fprintln(os.Stderr, "Error: -o and --out-dir cannot be used together")
+		return 1
+	case outDir != "":
+		var err error
+		if jobs, err = outDirJobs(inputs, outDir); err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+	case len(inputs) > 1:
+		fmt.Fprintln(os.Stderr, "Error: use --out-dir to transpile more than one file")
+		return 1
+	default:
+		if output == "-" {
+			output = ""
+		}
+		jobs = []transpileJob{{src: inputs[0], dst: output}}
+	}
+	if check && output == "" && outDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: --check needs -o or --out-dir to compare against")
+		return 1
+	}
+
+	dict, err := loadDictionary()
 	if err != nil {
-		handleError(err, inputFile)
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
 		return 1
 	}
 
-	fmt.Println(tempPath)
+	codes, failed := generateGoFiles(jobs, dict)
+	stale := 0
+	for i, job := range jobs {
+		code := codes[i]
+		if code == nil {
+			continue
+		}
+
+		if job.dst == "" {
+			os.Stdout.Write(code)
+			continue
+		}
+		existing, readErr := os.ReadFile(job.dst)
+		if readErr == nil && bytes.Equal(existing, code) {
+			continue // up to date; leave the file and its mtime alone
+		}
+		if check {
+			stale++
+			if machineDiagnostics() {
+				collect(reporting.Diagnostic{Filename: job.dst, Diagnostic: lexer.Diagnostic{
+					Message: fmt.Sprintf("out of date with %s; run singlish transpile to regenerate it", job.src),
+					Code:    lexer.CodeStaleOutput,
+				}})
+			}
+			if readErr != nil {
+				fmt.Fprintf(os.Stdout, "%s: missing (generated from %s)\n", job.dst, job.src)
+			} else {
+				fmt.Fprintf(os.Stdout, "%s: out of date with %s\n", job.dst, job.src)
+				fmt.Fprint(os.Stdout, textdiff.Unified(job.dst, job.dst+" (regenerated)", string(existing), string(code)))
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(job.dst), 0755); err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		if err := os.WriteFile(job.dst, code, 0644); err != nil {
+			printErrorWithInsult(fmt.Errorf("failed to write %s: %w", job.dst, err))
+			return 1
+		}
+	}
+
+	if check && outDir != "" {
+		orphans, err := orphanedGoFiles(outDir, jobs)
+		if err != nil {
+			printErrorWithInsult(err)
+			return 1
+		}
+		for _, path := range orphans {
+			stale++
+			if machineDiagnostics() {
+				collect(reporting.Diagnostic{Filename: path, Diagnostic: lexer.Diagnostic{
+					Message: "generated by singlish but its source is gone; delete it",
+					Code:    lexer.CodeStaleOutput,
+				}})
+			}
+			fmt.Fprintf(os.Stdout, "%s: generated by singlish but its source is gone; delete it\n", path)
+		}
+	}
+
+	if failed {
+		return 1
+	}
+	if stale > 0 {
+		printInsult(insults.Minor)
+		fmt.Fprintf(os.Stderr, "%d generated Go file(s) are stale\n", stale)
+		return 1
+	}
 	return 0
 }
+
+// generateGoFiles transpiles the sources of jobs into formatted Go files
+// with the generated-code header, in the order of jobs. The files in one
+// directory with the same kampung are transpiled together as a package, so
+// that each sees what the others declare. The code of a job whose package
+// failed is nil; failed reports whether any did, and the errors have been
+// reported.
+func generateGoFiles(jobs []transpileJob, dict *dictionaries.Dictionary) (codes [][]byte, failed bool) {
+	type pkgKey struct{ dir, name string }
+	var order []pkgKey
+	groups := make(map[pkgKey][]int)
+	files := make([]singlish.File, len(jobs))
+	for i, job := range jobs {
+		content, err := os.ReadFile(job.src)
+		if err != nil {
+			handleError(fmt.Errorf("failed to read input file: %w", err), job.src)
+			failed = true
+			continue
+		}
+		files[i] = singlish.File{Filename: job.src, Source: content}
+		key := pkgKey{dir: filepath.Dir(job.src)}
+		if program, err := transpiler.ParseFile(string(content), dict); err == nil {
+			key.name = packageName(program)
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+
+	codes = make([][]byte, len(jobs))
+	for _, key := range order {
+		var pkg []singlish.File
+		for _, i := range groups[key] {
+			pkg = append(pkg, files[i])
+		}
+		results, err := singlish.CompilePackage(context.Background(), pkg, singlish.Options{
+			Dictionary: dict,
+			Format:     true,
+		})
+		if err != nil {
+			failed = true
+			var cErr *singlish.Error
+			if !errors.As(err, &cErr) {
+				handleError(fmt.Errorf("transpilation failed: %w", err), pkg[0].Filename)
+				continue
+			}
+			for j, res := range results {
+				if len(res.Diagnostics) > 0 {
+					handleError(&singlish.Error{Diagnostics: res.Diagnostics}, pkg[j].Filename)
+				}
+			}
+			continue
+		}
+		for j, i := range groups[key] {
+			codes[i] = []byte(transpiler.GeneratedHeader + "\n" + results[j].Code)
+		}
+	}
+	return codes, failed
+}
+
+// outDirJobs maps every input file, and every .singlish file below every
+// input directory, to its place in outDir.
+func outDirJobs(inputs []string, outDir string) ([]transpileJob, error) {
+	var jobs []transpileJob
+	seen := make(map[string]string)
+	add := func(src, rel string) error {
+		dst := filepath.Join(outDir, strings.TrimSuffix(rel, ".singlish")+".go")
+		if prev, ok := seen[dst]; ok {
+			return fmt.Errorf("%s and %s would both be written to %s", prev, src, dst)
+		}
+		seen[dst] = src
+		jobs = append(jobs, transpileJob{src: src, dst: dst})
+		return nil
+	}
+
+	absOut, _ := filepath.Abs(outDir)
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := add(input, filepath.Base(input)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(input, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				abs, _ := filepath.Abs(path)
+				if path != input && (strings.HasPrefix(d.Name(), ".") || abs == absOut) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".singlish") {
+				return nil
+			}
+			rel, err := filepath.Rel(input, path)
+			if err != nil {
+				return err
+			}
+			return add(path, rel)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// orphanedGoFiles lists generated Go files in outDir that no job produces.
+func orphanedGoFiles(outDir string, jobs []transpileJob) ([]string, error) {
+	expected := make(map[string]bool)
+	for _, job := range jobs {
+		expected[filepath.Clean(job.dst)] = true
+	}
+	var orphans []string
+	err := filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || expected[filepath.Clean(path)] {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.HasPrefix(content, []byte(transpiler.GeneratedHeader)) {
+			orphans = append(orphans, path)
+		}
+		return nil
+	})
+	sort.Strings(orphans)
+	return orphans, err
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/insults"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/typecheck"
+	"github.com/rickchow/singlish/pkg/vet"
+)
+
+const vetUsage = `Usage:
+  singlish vet [--enable <rules>] [--disable <rules>] [<file | dir>...]
+  singlish vet --list
+
+Description:
+  Look for Singlish code that is probably wrong: code after balek that can
+  never run, goroutines in loops using the loop variable, nanti in loops,
+  unused got variables, ignored salah results, names that hide what a
+  keyword stands for, and go (continue) where chiong was meant. Directories
+  are searched for .singlish files. Without arguments, vets the entry file
+  set in singlish.toml. Exits with status 1 if anything is found.
+
+  Files are vetted by package, as singlish check groups them, and
+  type-checked to learn which calls return salah.
+
+  Rules are named by code or short name; see --list. To silence a rule on
+  one line, end the line with a comment, or put the comment on the line
+  before:
+
+    got spare nombor //singlish:ignore unused
+
+  Without rule names, //singlish:ignore silences every rule.
+
+Flags:
+  --enable <rules>    Run only these rules, separated by commas
+                      (default: enable in the [vet] table of singlish.toml)
+  --disable <rules>   Do not run these rules, on top of disable in the
+                      [vet] table of singlish.toml
+  --list              List the rules and exit
+`
+
+func runVet(args []string) int {
+	if len(args) > 0 && isHelpFlag(args[0]) {
+		fmt.Fprint(os.Stdout, vetUsage)
+		return 0
+	}
+
+	disable := slices.Clone(project.Vet.Disable)
+	var enable, inputs []string
+	for i := 0; i < len(args); i++ {
+		if value, ok, err := flagValue(args, &i, "--enable"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			enable = append(enable, ruleList(value)...)
+		} else if value, ok, err := flagValue(args, &i, "--disable"); ok {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			disable = append(disable, ruleList(value)...)
+		} else if args[i] == "--list" {
+			for _, a := range vet.Analyzers {
+				fmt.Fprintf(os.Stdout, "%s  %-12s %s\n", a.Code, a.Name, a.Doc)
+			}
+			return 0
+		} else if strings.HasPrefix(args[i], "-") {
+			fmt.Fprintf(os.Stderr, "Error: unknown flag %s\nRun 'singlish vet --help' for usage.\n", args[i])
+			return 1
+		} else {
+			inputs = append(inputs, args[i])
+		}
+	}
+	if enable == nil {
+		enable = project.Vet.Enable
+	}
+	analyzers, err := vet.Select(enable, disable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\nRun 'singlish vet --list' to see the rules.\n", err)
+		return 1
+	}
+
+	inputs = withEntry(inputs)
+	if len(inputs) == 0 {
+		fmt.Fprint(os.Stdout, vetUsage)
+		fmt.Fprintln(os.Stderr, "\nError: missing input file")
+		return 1
+	}
+	dict, err := loadDictionary()
+	if err != nil {
+		printErrorWithInsult(fmt.Errorf("failed to load dictionary: %w", err))
+		return 1
+	}
+	files, err := singlishFiles(inputs)
+	if err != nil {
+		printErrorWithInsult(err)
+		return 1
+	}
+
+	code := 0
+	var parsed []typecheck.File
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+			continue
+		}
+		program, err := transpiler.ParseFile(string(content), dict)
+		if err != nil {
+			handleError(err, path)
+			code = 1
+			continue
+		}
+		parsed = append(parsed, typecheck.File{Path: path, Source: string(content), Program: program})
+	}
+
+	// Packages are vetted as singlish check groups them; files shared by
+	// several programs are reported once.
+	reported := make(map[string]bool)
+	for _, unit := range checkUnits(parsed, dict) {
+		found, err := vet.RunPackage(unit, dict, analyzers)
+		if err != nil {
+			printErrorWithInsult(err)
+			code = 1
+			continue
+		}
+		for _, f := range unit {
+			diags := found[f.Path]
+			if reported[f.Path] || len(diags) == 0 {
+				continue
+			}
+			reported[f.Path] = true
+			code = 1
+			if machineDiagnostics() {
+				collect(reporting.InFile(f.Path, diags)...)
+				continue
+			}
+			printInsult(insults.Minor)
+			printer := &reporting.Printer{Filename: f.Path, Color: reporting.UseColor(os.Stderr), Context: 1}
+			printer.PrintAll(os.Stderr, f.Source, diags)
+		}
+	}
+	return code
+}
+
+// ruleList splits a comma-separated list of rules.
+func ruleList(value string) []string {
+	var rules []string
+	for _, r := range strings.Split(value, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
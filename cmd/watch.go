@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/watch"
+)
+
+// stripWatchFlag removes --watch (or -watch) from args, looking only at
+// arguments before a "--" separator, and reports whether it was present.
+func stripWatchFlag(args []string) (bool, []string) {
+	found := false
+	var rest []string
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--watch" || arg == "-watch" {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return found, rest
+}
+
+// dictionaryFiles returns the dictionary files in use, including those
+// they extend, so that editing any of them triggers a rebuild too.
+func dictionaryFiles() []string {
+	if dict, err := loadDictionary(); err == nil {
+		return dict.Files()
+	}
+	return dictionaryPaths()
+}
+
+// watchLoop runs action once and then again whenever the files returned by
+// paths change, until interrupted. A run still in progress when files
+// change is cancelled through its context and waited for before the next
+// one starts. changed is nil for the first run.
+func watchLoop(paths func() []string, action func(ctx context.Context, changed []string)) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var cancel context.CancelFunc = func() {}
+	done := make(chan struct{})
+	close(done)
+	start := func(changed []string) {
+		cancel()
+		<-done
+		var runCtx context.Context
+		runCtx, cancel = context.WithCancel(ctx)
+		done = make(chan struct{})
+		go func(finished chan struct{}) {
+			defer close(finished)
+			action(runCtx, changed)
+		}(done)
+	}
+
+	fmt.Fprintf(os.Stderr, "[watch] watching %s (Ctrl+C to stop)\n", strings.Join(paths(), ", "))
+	start(nil)
+	w := &watch.Watcher{Paths: paths}
+	w.Run(ctx, start)
+
+	cancel()
+	<-done
+	return 0
+}
+
+// printWatchChange announces which files triggered a rerun.
+func printWatchChange(changed []string) {
+	if len(changed) > 0 {
+		fmt.Fprintf(os.Stderr, "\n[watch] %s changed\n", strings.Join(changed, ", "))
+	}
+}
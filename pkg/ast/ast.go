@@ -756,3 +756,104 @@ func (se *SliceExpression) String() string {
 	out.WriteString("]")
 	return out.String()
 }
+
+// Position returns the source line and column of the token that introduces n.
+// It returns 0, 0 for nodes that carry no token.
+func Position(n Node) (line, col int) {
+	var tok lexer.Token
+	switch v := n.(type) {
+	case *Identifier:
+		tok = v.Token
+	case *IntegerLiteral:
+		tok = v.Token
+	case *FloatLiteral:
+		tok = v.Token
+	case *StringLiteral:
+		tok = v.Token
+	case *PrefixExpression:
+		tok = v.Token
+	case *InfixExpression:
+		// The operator token sits in the middle; report where the expression starts.
+		if v.Left != nil {
+			return Position(v.Left)
+		}
+		tok = v.Token
+	case *IndexExpression:
+		if v.Left != nil {
+			return Position(v.Left)
+		}
+		tok = v.Token
+	case *CallExpression:
+		if v.Function != nil {
+			return Position(v.Function)
+		}
+		tok = v.Token
+	case *TypeStatement:
+		tok = v.Token
+	case *StructLiteral:
+		tok = v.Token
+	case *InterfaceLiteral:
+		tok = v.Token
+	case *PackageStatement:
+		tok = v.Token
+	case *ImportStatement:
+		tok = v.Token
+	case *LetStatement:
+		tok = v.Token
+	case *ReturnStatement:
+		tok = v.Token
+	case *ExpressionStatement:
+		if v.Expression != nil {
+			if line, col := Position(v.Expression); line > 0 {
+				return line, col
+			}
+		}
+		tok = v.Token
+	case *BlockStatement:
+		tok = v.Token
+	case *FunctionStatement:
+		tok = v.Token
+	case *FunctionLiteral:
+		tok = v.Token
+	case *IfStatement:
+		tok = v.Token
+	case *ForStatement:
+		tok = v.Token
+	case *IncDecStatement:
+		if v.Left != nil {
+			return Position(v.Left)
+		}
+		tok = v.Token
+	case *KeyValueExpression:
+		if v.Key != nil {
+			return Position(v.Key)
+		}
+		tok = v.Token
+	case *CompositeLiteral:
+		if v.Type != nil {
+			return Position(v.Type)
+		}
+		tok = v.Token
+	case *SwitchStatement:
+		tok = v.Token
+	case *GoStatement:
+		tok = v.Token
+	case *DeferStatement:
+		tok = v.Token
+	case *SelectStatement:
+		tok = v.Token
+	case *SelectCase:
+		tok = v.Token
+	case *TypeAssertionExpression:
+		if v.Left != nil {
+			return Position(v.Left)
+		}
+		tok = v.Token
+	case *SliceExpression:
+		if v.Left != nil {
+			return Position(v.Left)
+		}
+		tok = v.Token
+	}
+	return tok.Line, tok.Col
+}
@@ -0,0 +1,217 @@
+// Package cache keeps transpiled Go code and built binaries in a
+// content-addressed directory, by default under the user cache dir.
+//
+// Entries are keyed on the Singlish source, the dictionary contents, the
+// version of the singlish tool and the Go build settings, so the same
+// program always lands in the same directory. That lets singlish skip
+// transpiling and building unchanged programs, and gives go build a stable
+// path so its own build cache hits.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+)
+
+// EnvVar overrides the cache location. Setting it to "off" disables caching.
+const EnvVar = "SINGLISH_CACHE"
+
+// ErrDisabled is returned by Open when caching is turned off.
+var ErrDisabled = errors.New("cache disabled")
+
+// Names of the files inside an entry.
+const (
+	GoFile     = "main.go"
+	BinaryFile = "program"
+)
+
+// Cache is a cache directory.
+type Cache struct {
+	Dir string
+}
+
+// Open returns the cache in $SINGLISH_CACHE or, by default, in a singlish
+// directory under os.UserCacheDir, creating it if needed.
+func Open() (*Cache, error) {
+	dir := os.Getenv(EnvVar)
+	if dir == "off" {
+		return nil, ErrDisabled
+	}
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(base, "singlish")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// objects is where entries live; only this directory is ever removed by
+// Clean, so a cache pointed at the wrong place cannot take other files with it.
+func (c *Cache) objects() string {
+	return filepath.Join(c.Dir, "objects")
+}
+
+// Key returns the cache key for transpiling source, read from filename, with
+// dict. The file name is part of the key because the generated code refers
+// back to it in line directives. So are the target platform, the Go
+// toolchain and its flags, because an entry also holds the built binary.
+func Key(filename string, source []byte, dict *dictionaries.Dictionary) string {
+	h := sha256.New()
+	write := func(s string) {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	write(ToolVersion())
+	write(goEnv())
+	write(filename)
+
+	keys := dict.Keys()
+	sort.Strings(keys)
+	for _, k := range keys {
+		// The target of a qualified alias includes its import path, which
+		// Lookup leaves out.
+		v, _ := dict.Lookup(k)
+		if e, ok := dict.Entry(k); ok {
+			v = e.Target
+		}
+		write(k)
+		write(v)
+	}
+	write(string(source))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// goEnv returns the settings of the go command that change the binary it
+// builds. They come from the environment and the go env file, so the go
+// command is asked; without one, nothing can be built anyway.
+func goEnv() string {
+	out, err := exec.Command("go", "env", "GOOS", "GOARCH", "CGO_ENABLED", "GOFLAGS", "GOVERSION").Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+var toolVersion = sync.OnceValue(func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		revision, modified := "", false
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+		if revision != "" && !modified {
+			return revision
+		}
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			return v
+		}
+	}
+	// Development builds carry no usable version, so fingerprint the binary.
+	if exe, err := os.Executable(); err == nil {
+		if f, err := os.Open(exe); err == nil {
+			defer f.Close()
+			h := sha256.New()
+			if _, err := io.Copy(h, f); err == nil {
+				return hex.EncodeToString(h.Sum(nil))
+			}
+		}
+	}
+	return "unknown"
+})
+
+// ToolVersion identifies the running singlish build. Entries written by a
+// different build are never reused.
+func ToolVersion() string {
+	return toolVersion()
+}
+
+// Entry returns the directory for key, creating it if needed.
+func (c *Cache) Entry(key string) (string, error) {
+	dir := filepath.Join(c.objects(), key[:2], key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	return dir, nil
+}
+
+// WriteFile atomically writes data to name inside an entry directory, so a
+// concurrent reader sees either nothing or the complete file.
+func WriteFile(dir, name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+// Stats describes the contents of the cache.
+type Stats struct {
+	Entries  int   // transpiled programs
+	Binaries int   // entries that also hold a built binary
+	Bytes    int64 // total size on disk
+}
+
+// Stats walks the cache and totals its contents.
+func (c *Cache) Stats() (Stats, error) {
+	var st Stats
+	err := filepath.WalkDir(c.objects(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		switch d.Name() {
+		case GoFile:
+			st.Entries++
+		case BinaryFile, BinaryFile + ".exe":
+			st.Binaries++
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st.Bytes += info.Size()
+		return nil
+	})
+	return st, err
+}
+
+// Clean removes every entry.
+func (c *Cache) Clean() error {
+	return os.RemoveAll(c.objects())
+}
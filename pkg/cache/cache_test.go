@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+)
+
+func TestKey(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := []byte("kampung main\n")
+
+	if Key("kopi.singlish", src, dict) != Key("kopi.singlish", src, dict) {
+		t.Errorf("Key is not deterministic")
+	}
+	if Key("kopi.singlish", src, dict) == Key("kopi.singlish", []byte("kampung utils\n"), dict) {
+		t.Errorf("Key ignores the source")
+	}
+	if Key("kopi.singlish", src, dict) == Key("teh.singlish", src, dict) {
+		t.Errorf("Key ignores the file name")
+	}
+
+	t.Setenv("GOARCH", "arm64")
+	arm := Key("kopi.singlish", src, dict)
+	t.Setenv("GOARCH", "amd64")
+	if arm == Key("kopi.singlish", src, dict) {
+		t.Errorf("Key ignores GOARCH")
+	}
+
+	path := filepath.Join(t.TempDir(), "dict.txt")
+	if err := os.WriteFile(path, []byte("kampung: package\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other, err := dictionaries.LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary failed: %v", err)
+	}
+	if Key("kopi.singlish", src, dict) == Key("kopi.singlish", src, other) {
+		t.Errorf("Key ignores the dictionary")
+	}
+
+	// Aliases for the same Go expression from different packages.
+	var aliases [2]*dictionaries.Dictionary
+	for i, target := range []string{"math/rand.Intn", "example.com/rand.Intn"} {
+		path := filepath.Join(t.TempDir(), "dict.txt")
+		if err := os.WriteFile(path, []byte("agak: "+target+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if aliases[i], err = dictionaries.LoadDictionary(path); err != nil {
+			t.Fatalf("LoadDictionary failed: %v", err)
+		}
+	}
+	if Key("kopi.singlish", src, aliases[0]) == Key("kopi.singlish", src, aliases[1]) {
+		t.Errorf("Key ignores the import path of qualified aliases")
+	}
+}
+
+func TestEntryStatsClean(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvVar, dir)
+	c, err := Open()
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if c.Dir != dir {
+		t.Errorf("Dir = %q, want %q", c.Dir, dir)
+	}
+
+	key := Key("kopi.singlish", []byte("kampung main\n"), dictionaries.NewDefaultDictionary())
+	entry, err := c.Entry(key)
+	if err != nil {
+		t.Fatalf("Entry failed: %v", err)
+	}
+	if err := WriteFile(entry, GoFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := WriteFile(entry, BinaryFile, []byte("binary"), 0755); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if again, _ := c.Entry(key); again != entry {
+		t.Errorf("same key gave entries %q and %q", entry, again)
+	}
+
+	st, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if st.Entries != 1 || st.Binaries != 1 || st.Bytes != int64(len("package main\n")+len("binary")) {
+		t.Errorf("Stats = %+v", st)
+	}
+
+	keep := filepath.Join(dir, "not-ours.txt")
+	if err := os.WriteFile(keep, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Clean(); err != nil {
+		t.Fatalf("Clean failed: %v", err)
+	}
+	if st, _ := c.Stats(); st.Entries != 0 || st.Bytes != 0 {
+		t.Errorf("Stats after Clean = %+v", st)
+	}
+	if _, err := os.Stat(keep); err != nil {
+		t.Errorf("Clean removed a file it does not own: %v", err)
+	}
+}
+
+func TestOpenDisabled(t *testing.T) {
+	t.Setenv(EnvVar, "off")
+	if _, err := Open(); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Open with %s=off returned %v, want ErrDisabled", EnvVar, err)
+	}
+}
@@ -3,39 +3,249 @@ package codegen
 import (
 	"bytes"
 	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
 	"strings"
 
 	"github.com/rickchow/singlish/pkg/ast"
 	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/imports"
+	"github.com/rickchow/singlish/pkg/lexer"
 )
 
 // Generate converts AST to Go source code.
 func Generate(program *ast.Program, dict *dictionaries.Dictionary) (string, error) {
+	code, _, err := GenerateWithSourceMap(program, dict)
+	return code, err
+}
+
+// GenerateWithSourceMap converts AST to Go source code and also returns a
+// SourceMap from generated Go lines back to Singlish lines.
+//
+// Standard packages the code uses without importing are imported, and
+// standard packages it imports without using are dropped, as goimports
+// would; see CheckImports.
+func GenerateWithSourceMap(program *ast.Program, dict *dictionaries.Dictionary) (string, *SourceMap, error) {
+	return GenerateInPackage(program, []*ast.Program{program}, dict)
+}
+
+// GenerateInPackage is GenerateWithSourceMap for one of pkg, the files of a
+// package. A builtin that any of them declares at package level is renamed
+// in each, so that every file refers to it by the same name.
+func GenerateInPackage(program *ast.Program, pkg []*ast.Program, dict *dictionaries.Dictionary) (string, *SourceMap, error) {
+	hidden := hiddenBuiltins(pkg, dict)
+	g := newGenerator(program, hidden, dict)
+	g.generate(program)
+	code := g.out.String()
+
+	fix, err := checkImports(code)
+	if err != nil || fix.Empty() {
+		return code, g.sourceMap, nil
+	}
+	// Generate again with the imports fixed, so the source map matches.
+	g = newGenerator(program, hidden, dict)
+	for _, path := range fix.Unused {
+		delete(g.userImports, path)
+		delete(g.imports, path)
+	}
+	for _, path := range fix.Missing {
+		g.imports[path] = struct{}{}
+	}
+	g.generate(program)
+	return g.out.String(), g.sourceMap, nil
+}
+
+// CheckImports reports the standard packages program uses without a
+// dapao and the ones it imports with dapao but never uses. Packages that
+// qualified aliases such as gong bring are counted as imported.
+func CheckImports(program *ast.Program, dict *dictionaries.Dictionary) (imports.Fix, error) {
+	g := newGenerator(program, hiddenBuiltins([]*ast.Program{program}, dict), dict)
+	g.generate(program)
+	return checkImports(g.out.String())
+}
+
+// checkImports checks the imports of generated code against the standard
+// library of the local Go installation.
+func checkImports(code string) (imports.Fix, error) {
+	std, err := imports.Std()
+	if err != nil {
+		return imports.Fix{}, err
+	}
+	return imports.Check(code, std)
+}
+
+// newGenerator returns a generator for program that has collected its
+// explicit imports and the packages its aliases need, and renames the
+// builtins in hidden.
+func newGenerator(program *ast.Program, hidden map[string]bool, dict *dictionaries.Dictionary) *generator {
 	g := &generator{
 		dict:        dict,
 		imports:     make(map[string]struct{}),
 		userImports: make(map[string]struct{}),
+		sourceMap:   &SourceMap{},
+		goLine:      1,
+		hidden:      hidden,
+		renamed:     make(map[string]bool),
 	}
-
-	// First pass: collect explicit imports and detect implicit usage
 	g.inspect(program)
+	return g
+}
 
-	// Second pass: generate code
-	g.generate(program)
+// hiddenBuiltins returns the Go builtins that pkg, the files of a package,
+// declare at package level although the dictionary has a word of its own
+// for them. Declaring len would otherwise hide the builtin that the
+// dictionary's word for len means throughout the package.
+func hiddenBuiltins(pkg []*ast.Program, dict *dictionaries.Dictionary) map[string]bool {
+	hidden := make(map[string]bool)
+	check := func(id *ast.Identifier) {
+		if hides(id, dict) {
+			hidden[id.Value] = true
+		}
+	}
+	for _, program := range pkg {
+		for _, s := range program.Statements {
+			switch s := s.(type) {
+			case *ast.FunctionStatement:
+				if s.Receiver == nil && s.Name != nil {
+					check(s.Name)
+				}
+			case *ast.LetStatement:
+				for _, name := range s.Names {
+					check(name)
+				}
+			case *ast.TypeStatement:
+				if s.Name != nil {
+					check(s.Name)
+				}
+			}
+		}
+	}
+	return hidden
+}
+
+// hides reports whether declaring id hides a Go builtin the dictionary has
+// a word of its own for.
+func hides(id *ast.Identifier, dict *dictionaries.Dictionary) bool {
+	if !written(id) || types.Universe.Lookup(id.Value) == nil {
+		return false
+	}
+	word, ok := dict.ReverseLookup(id.Value)
+	return ok && word != id.Value
+}
+
+// written reports whether id is a name as the author wrote it, rather than
+// a keyword the dictionary translated.
+func written(id *ast.Identifier) bool {
+	return id != nil && id.Token.Type == lexer.TokenIdentifier && id.Token.Value == id.Value
+}
+
+// name returns the Go spelling of id. A name Go reserves gets an underscore
+// after it, as in type_: keywords always, and builtins the program hides at
+// package level or in a scope around the code being generated. Every rename
+// is recorded in the source map.
+func (g *generator) name(id *ast.Identifier) string {
+	if !written(id) || !(token.IsKeyword(id.Value) || g.hidden[id.Value] || g.scoped(id.Value)) {
+		return id.Value
+	}
+	return g.rename(id)
+}
+
+// member returns the Go spelling of id as a field or method name, which
+// the builtins that local declarations hide leave alone.
+func (g *generator) member(id *ast.Identifier) string {
+	if !written(id) || !(token.IsKeyword(id.Value) || g.hidden[id.Value]) {
+		return id.Value
+	}
+	return g.rename(id)
+}
+
+func (g *generator) rename(id *ast.Identifier) string {
+	goName := id.Value + "_"
+	if !g.renamed[id.Value] {
+		g.renamed[id.Value] = true
+		g.sourceMap.Renames = append(g.sourceMap.Renames, Rename{Singlish: id.Value, Go: goName})
+	}
+	return goName
+}
+
+// scoped reports whether a declaration in one of the scopes around the code
+// being generated hides the builtin called name.
+func (g *generator) scoped(name string) bool {
+	for _, scope := range g.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) openScope() {
+	g.scopes = append(g.scopes, make(map[string]bool))
+}
+
+func (g *generator) closeScope() {
+	g.scopes = g.scopes[:len(g.scopes)-1]
+}
+
+// declare adds the builtins that ids hide to the innermost scope and
+// returns the ones it was not hiding yet. Declarations at package level are
+// left to hiddenBuiltins.
+func (g *generator) declare(ids ...*ast.Identifier) []string {
+	if len(g.scopes) == 0 {
+		return nil
+	}
+	scope := g.scopes[len(g.scopes)-1]
+	var added []string
+	for _, id := range ids {
+		if hides(id, g.dict) && !scope[id.Value] {
+			scope[id.Value] = true
+			added = append(added, id.Value)
+		}
+	}
+	return added
+}
+
+// undeclare removes names from the innermost scope again, so that the
+// values a declaration gives its names still see the builtins, as in Go.
+func (g *generator) undeclare(names []string) {
+	for _, name := range names {
+		delete(g.scopes[len(g.scopes)-1], name)
+	}
+}
 
-	return g.out.String(), nil
+// assigned returns the names on the left of a short variable declaration.
+func assigned(left ast.Expression) []*ast.Identifier {
+	switch e := left.(type) {
+	case *ast.Identifier:
+		return []*ast.Identifier{e}
+	case *ast.InfixExpression:
+		if e.Operator == "," {
+			return append(assigned(e.Left), assigned(e.Right)...)
+		}
+	}
+	return nil
 }
 
+// statementKeywords are the Go keywords the parser leaves as bare words.
+var statementKeywords = map[string]bool{"break": true, "continue": true, "fallthrough": true, "goto": true}
+
 type generator struct {
 	dict        *dictionaries.Dictionary
+	hidden      map[string]bool   // builtins the program hides, see hiddenBuiltins
+	renamed     map[string]bool   // names already recorded as renamed
+	scopes      []map[string]bool // builtins hidden by local declarations, innermost last
 	out         bytes.Buffer
 	indentLevel int
-	imports     map[string]struct{} // Implicitly required imports (e.g. "fmt")
+	imports     map[string]struct{} // Implicitly required imports, such as "fmt" for gong
 	userImports map[string]struct{} // Explicit imports from source
+	sourceMap   *SourceMap
+	goLine      int // 1-based Go line currently being written
+	srcLine     int // Singlish line of the statement being generated
 }
 
 func (g *generator) inspect(node ast.Node) {
-	// Simple inspection to find usages of "fmt" and explicit imports
+	// Simple inspection to find the packages used and explicit imports
 	switch n := node.(type) {
 	case *ast.Program:
 		if n == nil {
@@ -55,7 +265,8 @@ func (g *generator) inspect(node ast.Node) {
 		if n == nil {
 			return
 		}
-		// Check for fmt.Method
+		// Check for fmt.Method; other packages are found once the code is
+		// generated, but fmt is needed even when it cannot be parsed.
 		if n.Operator == "." {
 			if left, ok := n.Left.(*ast.Identifier); ok && left.Value == "fmt" {
 				g.imports["fmt"] = struct{}{}
@@ -113,8 +324,9 @@ func (g *generator) inspect(node ast.Node) {
 		if translated, found := g.dict.Lookup(val); found {
 			val = translated
 		}
-		if strings.HasPrefix(val, "fmt.") {
-			g.imports["fmt"] = struct{}{}
+		// Qualified aliases such as gong (fmt.Println) bring their package.
+		if path, found := g.dict.Import(val); found {
+			g.imports[path] = struct{}{}
 		}
 	case *ast.TypeStatement:
 		if n == nil {
@@ -270,6 +482,10 @@ func (g *generator) generate(program *ast.Program) {
 		g.visit(s)
 		g.write("\n")
 	}
+
+	if len(g.sourceMap.Renames) > 0 {
+		g.write("\n" + writeRenames(g.sourceMap.Renames))
+	}
 }
 
 func (g *generator) generateImports() {
@@ -285,9 +501,15 @@ func (g *generator) generateImports() {
 		return
 	}
 
+	paths := make([]string, 0, len(allImports))
+	for imp := range allImports {
+		paths = append(paths, imp)
+	}
+	sort.Strings(paths)
+
 	g.write("import (\n")
 	g.indent()
-	for imp := range allImports {
+	for _, imp := range paths {
 		g.writeIndent()
 		g.write(fmt.Sprintf("%q\n", imp))
 	}
@@ -299,6 +521,11 @@ func (g *generator) visit(node ast.Node) {
 	if node == nil {
 		return
 	}
+	if line, _ := ast.Position(node); line > 0 {
+		prev := g.srcLine
+		g.srcLine = line
+		defer func() { g.srcLine = prev }()
+	}
 	switch n := node.(type) {
 	case *ast.LetStatement:
 		g.visitLetStatement(n)
@@ -314,6 +541,10 @@ func (g *generator) visit(node ast.Node) {
 
 		g.visitBlockStatement(n)
 	case *ast.ExpressionStatement:
+		if id, ok := n.Expression.(*ast.Identifier); ok && id != nil && statementKeywords[id.Value] {
+			g.write(id.Value)
+			return
+		}
 		g.visitExpression(n.Expression)
 		// Expression statements usually end with newline, added by loop
 	case *ast.TypeStatement:
@@ -333,10 +564,7 @@ func (g *generator) visit(node ast.Node) {
 	case *ast.SliceExpression:
 		g.visitSliceExpression(n)
 	case *ast.TypeAssertionExpression:
-		g.visitExpression(n.Left)
-		g.write(".(")
-		g.visitExpression(n.Type)
-		g.write(")")
+		g.visitTypeAssertion(n)
 	default:
 		// Fallback for expressions
 		if expr, ok := node.(ast.Expression); ok {
@@ -360,11 +588,13 @@ func (g *generator) visitLetStatement(stmt *ast.LetStatement) {
 	g.write(kw)
 	g.write(" ")
 
+	added := g.declare(stmt.Names...)
 	names := []string{}
 	for _, name := range stmt.Names {
-		names = append(names, name.Value)
+		names = append(names, g.name(name))
 	}
 	g.write(strings.Join(names, ", "))
+	g.undeclare(added)
 
 	if stmt.Type != nil {
 		g.write(" ")
@@ -390,6 +620,7 @@ func (g *generator) visitLetStatement(stmt *ast.LetStatement) {
 		g.write(" = ")
 		g.visitExpression(stmt.Value)
 	}
+	g.declare(stmt.Names...)
 }
 
 func (g *generator) visitReturnStatement(stmt *ast.ReturnStatement) {
@@ -413,24 +644,28 @@ func (g *generator) visitReturnStatement(stmt *ast.ReturnStatement) {
 
 func (g *generator) visitFunctionStatement(stmt *ast.FunctionStatement) {
 	g.write("func ")
+	funcName := g.name(stmt.Name)
+	if translated, found := g.dict.Lookup(funcName); found {
+		funcName = translated
+	}
+	g.openScope()
+	defer g.closeScope()
 	if stmt.Receiver != nil {
+		g.declare(stmt.Receiver.Name)
 		g.write("(")
-		g.write(stmt.Receiver.Name.Value)
+		g.write(g.name(stmt.Receiver.Name))
 		g.write(" ")
 		g.visitExpression(stmt.Receiver.Type)
 		g.write(") ")
 	}
-	funcName := stmt.Name.Value
-	if translated, found := g.dict.Lookup(funcName); found {
-		funcName = translated
-	}
 	g.write(funcName)
 	g.write("(")
 	for i, param := range stmt.Parameters {
 		if i > 0 {
 			g.write(", ")
 		}
-		g.write(param.Name.Value)
+		g.declare(param.Name)
+		g.write(g.name(param.Name))
 		g.write(" ")
 		g.visitExpression(param.Type)
 
@@ -447,6 +682,8 @@ func (g *generator) visitFunctionStatement(stmt *ast.FunctionStatement) {
 }
 
 func (g *generator) visitBlockStatement(stmt *ast.BlockStatement) {
+	g.openScope()
+	defer g.closeScope()
 	g.write("{\n")
 	g.indent()
 	for _, s := range stmt.Statements {
@@ -477,6 +714,8 @@ func (g *generator) visitExpression(expr ast.Expression) {
 
 		if isPtr {
 			g.write("*" + baseVal)
+		} else if baseVal == val {
+			g.write(g.name(e))
 		} else {
 			g.write(baseVal)
 		}
@@ -511,18 +750,29 @@ func (g *generator) visitExpression(expr ast.Expression) {
 			}
 			g.visitExpression(e.Left)
 			g.write(".")
-			g.visitExpression(e.Right)
+			if id, ok := e.Right.(*ast.Identifier); ok && id != nil {
+				g.write(g.member(id))
+			} else {
+				g.visitExpression(e.Right)
+			}
 
 		} else if e.Operator == ":=" || e.Operator == "=" ||
 			e.Operator == "+=" || e.Operator == "-=" ||
 			e.Operator == "*=" || e.Operator == "/=" ||
 			e.Operator == "%=" || e.Operator == "&=" ||
 			e.Operator == "|=" || e.Operator == "^=" {
+			var declared []*ast.Identifier
+			if e.Operator == ":=" {
+				declared = assigned(e.Left)
+			}
+			added := g.declare(declared...)
 			g.visitExpression(e.Left)
+			g.undeclare(added)
 			g.write(" ")
 			g.write(e.Operator)
 			g.write(" ")
 			g.visitExpression(e.Right)
+			g.declare(declared...)
 		} else if e.Operator == "<-" {
 			// Send statement c <- v
 			g.visitExpression(e.Left)
@@ -578,21 +828,34 @@ func (g *generator) visitExpression(expr ast.Expression) {
 	case *ast.SliceExpression:
 		g.visitSliceExpression(e)
 	case *ast.TypeAssertionExpression:
-		g.visitExpression(e.Left)
-		g.write(".(")
+		g.visitTypeAssertion(e)
+	}
+}
+
+// visitTypeAssertion writes x.(T), leaving the type of a type switch,
+// x.(type), as it is.
+func (g *generator) visitTypeAssertion(e *ast.TypeAssertionExpression) {
+	g.visitExpression(e.Left)
+	g.write(".(")
+	if id, ok := e.Type.(*ast.Identifier); ok && id != nil && id.Value == "type" {
+		g.write("type")
+	} else {
 		g.visitExpression(e.Type)
-		g.write(")")
 	}
+	g.write(")")
 }
 
 func (g *generator) visitFunctionLiteral(lit *ast.FunctionLiteral) {
+	g.openScope()
+	defer g.closeScope()
 	g.write("func")
 	g.write("(")
 	for i, param := range lit.Parameters {
 		if i > 0 {
 			g.write(", ")
 		}
-		g.write(param.Name.Value)
+		g.declare(param.Name)
+		g.write(g.name(param.Name))
 		g.write(" ")
 		g.visitExpression(param.Type)
 	}
@@ -607,6 +870,13 @@ func (g *generator) visitFunctionLiteral(lit *ast.FunctionLiteral) {
 }
 
 func (g *generator) write(s string) {
+	for _, r := range s {
+		if r == '\n' {
+			g.goLine++
+		} else {
+			g.sourceMap.mark(g.goLine, g.srcLine)
+		}
+	}
 	g.out.WriteString(s)
 }
 
@@ -628,7 +898,7 @@ func (g *generator) dedent() {
 
 func (g *generator) visitTypeStatement(stmt *ast.TypeStatement) {
 	g.write("type ")
-	g.write(stmt.Name.Value)
+	g.write(g.name(stmt.Name))
 	if stmt.IsAlias {
 		g.write(" = ")
 	} else {
@@ -642,7 +912,7 @@ func (g *generator) visitStructLiteral(expr *ast.StructLiteral) {
 	g.indent()
 	for _, field := range expr.Fields {
 		g.writeIndent()
-		g.write(field.Name.Value)
+		g.write(g.member(field.Name))
 		g.write(" ")
 		g.visitExpression(field.Type)
 		if field.Tag != nil {
@@ -661,13 +931,13 @@ func (g *generator) visitInterfaceLiteral(expr *ast.InterfaceLiteral) {
 	g.indent()
 	for _, method := range expr.Methods {
 		g.writeIndent()
-		g.write(method.Name.Value)
+		g.write(g.member(method.Name))
 		g.write("(")
 		for i, param := range method.Parameters {
 			if i > 0 {
 				g.write(", ")
 			}
-			g.write(param.Name.Value)
+			g.write(g.name(param.Name))
 			g.write(" ")
 			g.visitExpression(param.Type)
 		}
@@ -696,33 +966,41 @@ func (g *generator) visitIfStatement(stmt *ast.IfStatement) {
 }
 
 func (g *generator) visitForStatement(stmt *ast.ForStatement) {
+	g.openScope()
+	defer g.closeScope()
 	g.write("for ")
 
 	if stmt.IsRange {
 		if stmt.Key != nil {
-			g.write(stmt.Key.Value)
+			added := g.declare(stmt.Key, stmt.Value)
+			g.write(g.name(stmt.Key))
 			if stmt.Value != nil {
-				g.write(", " + stmt.Value.Value)
+				g.write(", " + g.name(stmt.Value))
 			}
+			g.undeclare(added)
 			g.write(" := range ")
 		} else {
 			// for range iterable
 			g.write("range ")
 		}
 		g.visitExpression(stmt.Iterable)
+		g.declare(stmt.Key, stmt.Value)
 	} else {
 		// Handle Init
 		if stmt.Init != nil {
 			// Go requires ShortVarDecl (:=) or simple stmt in init clause.
 			// "var i = 0" is not allowed.
 			if let, ok := stmt.Init.(*ast.LetStatement); ok {
+				added := g.declare(let.Names...)
 				names := []string{}
 				for _, name := range let.Names {
-					names = append(names, name.Value)
+					names = append(names, g.name(name))
 				}
 				g.write(strings.Join(names, ", "))
+				g.undeclare(added)
 				g.write(" := ")
 				g.visitExpression(let.Value)
+				g.declare(let.Names...)
 			} else {
 				g.visit(stmt.Init)
 			}
@@ -805,11 +1083,13 @@ func (g *generator) visitSwitchStatement(stmt *ast.SwitchStatement) {
 			g.write(":\n")
 		}
 		g.indent()
+		g.openScope()
 		for _, s := range c.Body.Statements {
 			g.writeIndent()
 			g.visit(s)
 			g.write("\n")
 		}
+		g.closeScope()
 		g.dedent()
 	}
 	g.dedent()
@@ -821,6 +1101,7 @@ func (g *generator) visitSelectStatement(stmt *ast.SelectStatement) {
 	g.write("select {\n")
 	g.indent()
 	for _, c := range stmt.Cases {
+		g.openScope()
 		g.writeIndent()
 		if c.Default {
 			g.write("default:\n")
@@ -840,6 +1121,7 @@ func (g *generator) visitSelectStatement(stmt *ast.SelectStatement) {
 			}
 		}
 		g.dedent()
+		g.closeScope()
 	}
 	g.dedent()
 	g.writeIndent()
@@ -3,8 +3,11 @@ package codegen
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/rickchow/singlish/pkg/ast"
 	"github.com/rickchow/singlish/pkg/dictionaries"
 	"github.com/rickchow/singlish/pkg/lexer"
 	"github.com/rickchow/singlish/pkg/parser"
@@ -135,3 +138,350 @@ return 5
 		})
 	}
 }
+
+func TestGenerateWithSourceMap(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := "kampung main\n\naction boss() {\n\tgot x = 1\n\n\tgong(x)\n}\n"
+
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lexer error: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	code, sourceMap, err := GenerateWithSourceMap(program, dict)
+	if err != nil {
+		t.Fatalf("GenerateWithSourceMap error: %v", err)
+	}
+
+	want := map[string]int{
+		"func main() {":    3,
+		"\tvar x = 1":      4,
+		"\tfmt.Println(x)": 6,
+		"}":                3,
+	}
+	for i, line := range strings.Split(code, "\n") {
+		wantLine, ok := want[line]
+		if !ok {
+			continue
+		}
+		if got, _ := sourceMap.Lookup(i + 1); got != wantLine {
+			t.Errorf("Lookup(%d) for %q = %d, want %d", i+1, line, got, wantLine)
+		}
+		delete(want, line)
+	}
+	if len(want) > 0 {
+		t.Errorf("generated code missing lines %v:\n%s", want, code)
+	}
+	if _, ok := sourceMap.Lookup(1000); ok {
+		t.Errorf("Lookup past the end should fail")
+	}
+}
+
+func TestLineDirectivesRoundTrip(t *testing.T) {
+	m := &SourceMap{}
+	m.mark(2, 4)
+	m.mark(3, 5)
+	m.mark(4, 9)
+	m.mark(5, 9)
+	m.mark(6, 9)
+	code := "package main\nfunc f() {\n\tx := 1\n\ts := `a\nb`\n}\n"
+
+	withDirectives := m.LineDirectives(code, "kopi.singlish")
+	want := "package main\n//line kopi.singlish:4\nfunc f() {\n\tx := 1\n//line kopi.singlish:9\n\ts := `a\nb`\n//line kopi.singlish:9\n}\n"
+	if withDirectives != want {
+		t.Fatalf("LineDirectives =\n%s\nwant\n%s", withDirectives, want)
+	}
+
+	stripped, parsed := ParseLineDirectives(withDirectives, true)
+	if stripped != code {
+		t.Fatalf("ParseLineDirectives stripped =\n%s\nwant\n%s", stripped, code)
+	}
+	for goLine, wantLine := range map[int]int{1: 0, 2: 4, 3: 5, 4: 9, 5: 10, 6: 9} {
+		if got, _ := parsed.Lookup(goLine); got != wantLine {
+			t.Errorf("Lookup(%d) = %d, want %d", goLine, got, wantLine)
+		}
+	}
+
+	kept, parsed := ParseLineDirectives(withDirectives, false)
+	if kept != withDirectives {
+		t.Fatalf("ParseLineDirectives without strip changed the code")
+	}
+	if got, _ := parsed.Lookup(3); got != 4 {
+		t.Errorf("Lookup(3) = %d, want 4", got)
+	}
+}
+
+func TestGenerateQualifiedAliasImports(t *testing.T) {
+	dictPath := createTempDictionaryFile(t, "kampung: package\naction: func\ngong: fmt.Println\nlepak: time.Sleep\ntikam: math/rand.Intn\n")
+	dict, err := dictionaries.LoadDictionary(dictPath)
+	if err != nil {
+		t.Fatalf("LoadDictionary failed: %v", err)
+	}
+	input := "kampung main\n\naction main() {\n\tlepak(1)\n\tgong(tikam(6))\n}\n"
+
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lexer error: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	code, err := Generate(program, dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	for _, want := range []string{"import (\n\t\"fmt\"\n\t\"math/rand\"\n\t\"time\"\n)", "\ttime.Sleep(1)", "\tfmt.Println(rand.Intn(6))"} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateFixesImports(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := "kampung main\n\ndapao \"os\"\ndapao \"fmt\"\n\naction boss() {\n\tgot s = strings.ToUpper(\"lah\")\n\tfmt.Println(s, rand.Intn(6))\n}\n"
+
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lexer error: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	fix, err := CheckImports(program, dict)
+	if err != nil {
+		t.Fatalf("CheckImports error: %v", err)
+	}
+	if !slices.Equal(fix.Missing, []string{"strings", "math/rand"}) || !slices.Equal(fix.Unused, []string{"os"}) {
+		t.Errorf("CheckImports() = %+v, want strings and math/rand missing and os unused", fix)
+	}
+
+	code, sourceMap, err := GenerateWithSourceMap(program, dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if want := "import (\n\t\"fmt\"\n\t\"math/rand\"\n\t\"strings\"\n)"; !strings.Contains(code, want) {
+		t.Errorf("generated code does not contain %q:\n%s", want, code)
+	}
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "strings.ToUpper") {
+			if got, _ := sourceMap.Lookup(i + 1); got != 7 {
+				t.Errorf("Lookup(%d) = %d, want 7", i+1, got)
+			}
+		}
+	}
+}
+
+func TestGenerateRenamesReservedNames(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := `kampung main
+
+pattern Item barang {
+	got type tar
+}
+
+got len = 3
+
+action pick(select nombor, map tar) tar {
+	balek map
+}
+
+action boss() {
+	got type = "kopi"
+	got it = Item{type: type}
+	gong(it.type, len, count("abc"), pick(1, type))
+	for i := 0; i < 3; i++ {
+		nasi i == 1 {
+			continue
+		}
+	}
+	got x interface{} = 1
+	switch v := x.(type) {
+	case int:
+		gong(v)
+	}
+}
+`
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lexer error: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	code, sourceMap, err := GenerateWithSourceMap(program, dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	for _, want := range []string{
+		"\ttype_ string\n",
+		"var len_ = 3\n",
+		"func pick(select_ int, map_ string) string {\n\treturn map_\n}",
+		"var it = Item{type_: type_}",
+		"fmt.Println(it.type_, len_, len(\"abc\"), pick(1, type_))",
+		"\t\t\tcontinue\n",
+		"switch v := x.(type) {",
+		"\n//singlish:rename type_ type\n//singlish:rename len_ len\n//singlish:rename select_ select\n//singlish:rename map_ map\n",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	want := []Rename{{"type", "type_"}, {"len", "len_"}, {"select", "select_"}, {"map", "map_"}}
+	if !slices.Equal(sourceMap.Renames, want) {
+		t.Errorf("Renames = %+v, want %+v", sourceMap.Renames, want)
+	}
+	if _, parsed := ParseLineDirectives(code, false); !slices.Equal(parsed.Renames, want) {
+		t.Errorf("ParseLineDirectives() renames = %+v, want %+v", parsed.Renames, want)
+	}
+}
+
+func TestGenerateRenamesLocalNames(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := `kampung main
+
+action grow(append []nombor) []nombor {
+	balek upsize(append, 1)
+}
+
+action boss() {
+	got len = []nombor{1}
+	gong(count(len))
+	for _, make := range grow(len) {
+		gong(make, buat([]nombor, 1))
+	}
+	panic := "lagi"
+	gong(panic)
+	gong(count("abc"))
+}
+`
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lexer error: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		t.Fatalf("Parser errors: %v", p.Errors())
+	}
+
+	code, sourceMap, err := GenerateWithSourceMap(program, dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	for _, want := range []string{
+		"func grow(append_ []int) []int {\n\treturn append(append_, 1)\n}",
+		"var len_ = []int{1}\n",
+		"fmt.Println(len(len_))",
+		"for _, make_ := range grow(len_) {",
+		"fmt.Println(make_, make([]int, 1))",
+		"panic_ := \"lagi\"",
+		"fmt.Println(panic_)",
+		"fmt.Println(len(\"abc\"))",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated code does not contain %q:\n%s", want, code)
+		}
+	}
+
+	want := []Rename{{"append", "append_"}, {"len", "len_"}, {"make", "make_"}, {"panic", "panic_"}}
+	if !slices.Equal(sourceMap.Renames, want) {
+		t.Errorf("Renames = %+v, want %+v", sourceMap.Renames, want)
+	}
+}
+
+func TestGenerateInPackage(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	var pkg []*ast.Program
+	for _, input := range []string{
+		"kampung main\n\naction size() nombor {\n\tbalek len + count(\"ab\")\n}\n",
+		"kampung main\n\ngot len = 3\n",
+	} {
+		tokens, diags := lexer.Lex(input, keywords)
+		if len(diags) > 0 {
+			t.Fatalf("Lexer error: %v", diags)
+		}
+		p := parser.New(tokens, dict)
+		pkg = append(pkg, p.ParseProgram())
+		if len(p.Errors()) > 0 {
+			t.Fatalf("Parser errors: %v", p.Errors())
+		}
+	}
+
+	code, sourceMap, err := GenerateInPackage(pkg[0], pkg, dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if want := "return (len_ + len(\"ab\"))"; !strings.Contains(code, want) {
+		t.Errorf("generated code does not contain %q:\n%s", want, code)
+	}
+	if want := []Rename{{"len", "len_"}}; !slices.Equal(sourceMap.Renames, want) {
+		t.Errorf("Renames = %+v, want %+v", sourceMap.Renames, want)
+	}
+
+	code, err = Generate(pkg[0], dict)
+	if err != nil {
+		t.Fatalf("Generate error: %v", err)
+	}
+	if want := "return (len + len(\"ab\"))"; !strings.Contains(code, want) {
+		t.Errorf("file on its own: generated code does not contain %q:\n%s", want, code)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	m := &SourceMap{Renames: []Rename{{"type", "type_"}, {"len", "len_"}}}
+	output := "# command-line-arguments\nmain.singlish:4: declared and not used: type_\nmain.singlish:6: undefined: my_type_\nmain.singlish:9: cannot call len_ (type_ int)"
+	want := "# command-line-arguments\nmain.singlish:4: declared and not used: type_\n" +
+		"\tnote: `type_` is `type` in the Singlish source, renamed because `type` is a Go keyword\n" +
+		"main.singlish:6: undefined: my_type_\n" +
+		"main.singlish:9: cannot call len_ (type_ int)\n" +
+		"\tnote: `type_` is `type` in the Singlish source, renamed because `type` is a Go keyword\n" +
+		"\tnote: `len_` is `len` in the Singlish source, renamed because `len` would hide the Go builtin"
+	if got := m.Explain(output); got != want {
+		t.Errorf("Explain() =\n%s\nwant\n%s", got, want)
+	}
+	var none *SourceMap
+	if got := none.Explain(output); got != output {
+		t.Errorf("nil Explain() = %q, want the output unchanged", got)
+	}
+}
@@ -0,0 +1,206 @@
+package codegen
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// SourceMap records which Singlish source line produced each line of
+// generated Go code, and which Singlish names had to be renamed in Go.
+type SourceMap struct {
+	lines []int // lines[i] is the Singlish line for Go line i+1; 0 if unknown
+
+	// Renames lists the identifiers written differently in Go, in the order
+	// they first appear.
+	Renames []Rename
+}
+
+// Rename is a Singlish identifier that Go reserves, such as a variable
+// called type, and the name it has in the generated Go.
+type Rename struct {
+	Singlish string // type
+	Go       string // type_
+}
+
+// Reason says why the identifier was renamed.
+func (r Rename) Reason() string {
+	if token.IsKeyword(r.Singlish) {
+		return fmt.Sprintf("`%s` is a Go keyword", r.Singlish)
+	}
+	return fmt.Sprintf("`%s` would hide the Go builtin", r.Singlish)
+}
+
+// renameDirective starts the comment lines that record renames at the end
+// of generated code, so that they can be read back from the code alone.
+const renameDirective = "//singlish:rename "
+
+// writeRenames returns the directive lines recording renames.
+func writeRenames(renames []Rename) string {
+	var b strings.Builder
+	for _, r := range renames {
+		fmt.Fprintf(&b, "%s%s %s\n", renameDirective, r.Go, r.Singlish)
+	}
+	return b.String()
+}
+
+// readRenames reads back the renames recorded in code.
+func readRenames(code string) []Rename {
+	var renames []Rename
+	for _, line := range strings.Split(code, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimRight(line, "\r"), renameDirective)
+		if !ok {
+			continue
+		}
+		if goName, singlish, ok := strings.Cut(rest, " "); ok {
+			renames = append(renames, Rename{Singlish: singlish, Go: goName})
+		}
+	}
+	return renames
+}
+
+// Explain adds a note below each line of Go compiler output that mentions
+// a renamed identifier, giving its Singlish name.
+func (m *SourceMap) Explain(output string) string {
+	if m == nil || len(m.Renames) == 0 {
+		return output
+	}
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(output, "\n") {
+		text, newline := strings.CutSuffix(line, "\n")
+		b.WriteString(text)
+		for _, r := range m.Renames {
+			if mentions(text, r.Go) {
+				fmt.Fprintf(&b, "\n\tnote: `%s` is `%s` in the Singlish source, renamed because %s", r.Go, r.Singlish, r.Reason())
+			}
+		}
+		if newline {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// mentions reports whether name appears in line as a whole word.
+func mentions(line, name string) bool {
+	for i := 0; ; {
+		j := strings.Index(line[i:], name)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		if (start == 0 || !isWordByte(line[start-1])) && (end == len(line) || !isWordByte(line[end])) {
+			return true
+		}
+		i = end
+	}
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// Lookup returns the Singlish line that produced the given 1-based Go line.
+func (m *SourceMap) Lookup(goLine int) (int, bool) {
+	if m == nil || goLine < 1 || goLine > len(m.lines) {
+		return 0, false
+	}
+	line := m.lines[goLine-1]
+	return line, line > 0
+}
+
+// mark attributes the Go line currently being written to the Singlish line
+// of the statement being generated, unless the line is already attributed.
+func (m *SourceMap) mark(goLine, srcLine int) {
+	for len(m.lines) < goLine {
+		m.lines = append(m.lines, 0)
+	}
+	if srcLine > 0 && m.lines[goLine-1] == 0 {
+		m.lines[goLine-1] = srcLine
+	}
+}
+
+// LineDirectives returns code with a //line directive in front of every Go
+// line whose Singlish line the Go toolchain could not otherwise work out, so
+// compiler errors and stack traces name filename and the Singlish line.
+// Lines inside multi-line raw strings are left alone.
+func (m *SourceMap) LineDirectives(code, filename string) string {
+	unsafe := rawStringLines(code)
+	lines := strings.SplitAfter(code, "\n")
+	var b strings.Builder
+	next := 0 // the Singlish line the toolchain assumes for this Go line
+	for i, line := range lines {
+		if src, ok := m.Lookup(i + 1); ok && src != next && !unsafe[i+1] {
+			fmt.Fprintf(&b, "//line %s:%d\n", filename, src)
+			next = src
+		}
+		b.WriteString(line)
+		if next > 0 {
+			next++
+		}
+	}
+	return b.String()
+}
+
+// ParseLineDirectives builds a source map from the //line directives in
+// code, typically written by LineDirectives before the code was reformatted.
+// If strip is set the directives are removed and the map describes the
+// remaining lines. Renames are read from the code as well.
+func ParseLineDirectives(code string, strip bool) (string, *SourceMap) {
+	m := &SourceMap{Renames: readRenames(code)}
+	var b strings.Builder
+	goLine, next := 0, 0
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if rest, ok := strings.CutPrefix(line, "//line "); ok {
+			rest = strings.TrimRight(rest, "\r\n")
+			if i := strings.LastIndexByte(rest, ':'); i >= 0 {
+				if n, err := strconv.Atoi(rest[i+1:]); err == nil {
+					next = n
+					if !strip {
+						goLine++
+						b.WriteString(line)
+						m.mark(goLine, 0)
+					}
+					continue
+				}
+			}
+		}
+		if line == "" {
+			continue
+		}
+		goLine++
+		b.WriteString(line)
+		if strings.TrimSpace(line) != "" {
+			m.mark(goLine, next)
+		}
+		if next > 0 {
+			next++
+		}
+	}
+	return b.String(), m
+}
+
+// rawStringLines reports the Go lines that lie inside, but do not start, a
+// multi-line raw string literal. A directive placed there would change the
+// string's value.
+func rawStringLines(code string) map[int]bool {
+	lines := make(map[int]bool)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(code))
+	var s scanner.Scanner
+	s.Init(file, []byte(code), nil, scanner.ScanComments)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return lines
+		}
+		if tok == token.STRING && strings.HasPrefix(lit, "`") {
+			start := file.Line(pos)
+			for l := start + 1; l <= start+strings.Count(lit, "\n"); l++ {
+				lines[l] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,342 @@
+// Package config reads singlish.toml, the per-project configuration file.
+//
+// A project keeps singlish.toml next to its sources, or in any directory
+// above them:
+//
+//	# singlish.toml
+//	entry = "main.singlish"
+//	dictionary = ["dict/team.txt", "dict/mine.txt"]
+//	insult-level = "mild"
+//
+//	[build]
+//	output-dir = "bin"
+//	ldflags = "-s -w"
+//	trimpath = true
+//	flags = ["-v"]
+//
+//	[fmt]
+//	indent = 4
+//
+//	[vet]
+//	disable = ["unused"]
+//
+// Only the part of TOML these settings need is understood: tables, and
+// keys set to strings, booleans, integers or arrays of strings.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileName is the name of the configuration file.
+const FileName = "singlish.toml"
+
+// Config is a project configuration. The zero value configures nothing.
+type Config struct {
+	Path string // the file it was read from, or "" if there is none
+
+	Entry        string   // file that run, build and transpile use when given none
+	Dictionaries []string // dictionary files, layered in order
+	InsultLevel  string   // "off", "mild" or "garang"
+	InsultPack   string   // file of insults to use instead of the built-in ones
+
+	Build Build
+	Fmt   Fmt
+	Vet   Vet
+}
+
+// Build configures singlish build.
+type Build struct {
+	OutputDir string   // directory binaries are written to
+	LDFlags   string   // passed to go build -ldflags
+	Tags      string   // comma-separated build tags
+	Race      bool     // enable the race detector
+	Trimpath  bool     // remove file system paths from binaries
+	Flags     []string // more flags for go build
+}
+
+// Fmt configures singlish fmt.
+type Fmt struct {
+	Indent int // spaces per indentation level; 0 indents with tabs
+}
+
+// Vet configures singlish vet. Rules are named by code or short name.
+type Vet struct {
+	Enable  []string // rules to run; none means all of them
+	Disable []string // rules not to run
+}
+
+// Find looks for FileName in dir and the directories above it and returns
+// its path, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Discover finds and loads the configuration for the file or directory at
+// start. It returns an empty Config if there is no configuration file.
+func Discover(start string) (*Config, error) {
+	dir := start
+	if info, err := os.Stat(start); err != nil || !info.IsDir() {
+		dir = filepath.Dir(start)
+	}
+	path, err := Find(dir)
+	if err != nil || path == "" {
+		return &Config{}, err
+	}
+	return Load(path)
+}
+
+// Load reads the configuration file at path. Relative paths in it are
+// taken relative to the directory holding the file.
+func Load(path string) (*Config, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+	c.Path = path
+
+	dir := filepath.Dir(path)
+	resolve := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	resolve(&c.Entry)
+	resolve(&c.InsultPack)
+	resolve(&c.Build.OutputDir)
+	for i := range c.Dictionaries {
+		resolve(&c.Dictionaries[i])
+	}
+	return c, nil
+}
+
+// Parse reads configuration source. Errors start with the line they are
+// on, as in "3: unknown key ...".
+func Parse(src string) (*Config, error) {
+	c := &Config{}
+	table := ""
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%d: invalid table header %q", lineNo, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if table != "build" && table != "fmt" && table != "vet" {
+				return nil, fmt.Errorf("%d: unknown table [%s]", lineNo, table)
+			}
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: expected key = value, got %q", lineNo, line)
+		}
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		start := lineNo
+		// Arrays may continue over several lines.
+		for strings.HasPrefix(raw, "[") && !strings.HasSuffix(raw, "]") && scanner.Scan() {
+			lineNo++
+			raw += " " + strings.TrimSpace(stripComment(scanner.Text()))
+		}
+		v, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", start, key, err)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		if err := c.set(key, v); err != nil {
+			return nil, fmt.Errorf("%d: %w", start, err)
+		}
+	}
+	return c, scanner.Err()
+}
+
+// set stores the value of key, written with its table as in "build.tags".
+func (c *Config) set(key string, v any) error {
+	switch key {
+	case "entry":
+		return setString(key, v, &c.Entry)
+	case "dictionary":
+		// A single dictionary may be given as a string.
+		if s, ok := v.(string); ok {
+			v = []string{s}
+		}
+		return setStrings(key, v, &c.Dictionaries)
+	case "insult-level":
+		return setString(key, v, &c.InsultLevel)
+	case "insult-pack":
+		return setString(key, v, &c.InsultPack)
+	case "build.output-dir":
+		return setString(key, v, &c.Build.OutputDir)
+	case "build.ldflags":
+		return setString(key, v, &c.Build.LDFlags)
+	case "build.tags":
+		return setString(key, v, &c.Build.Tags)
+	case "build.race":
+		return setBool(key, v, &c.Build.Race)
+	case "build.trimpath":
+		return setBool(key, v, &c.Build.Trimpath)
+	case "build.flags":
+		return setStrings(key, v, &c.Build.Flags)
+	case "fmt.indent":
+		n, ok := v.(int)
+		if !ok || n < 0 {
+			return fmt.Errorf("%s must be a number of spaces, or 0 for tabs", key)
+		}
+		c.Fmt.Indent = n
+		return nil
+	case "vet.enable":
+		return setStrings(key, v, &c.Vet.Enable)
+	case "vet.disable":
+		return setStrings(key, v, &c.Vet.Disable)
+	}
+	return fmt.Errorf("unknown key %s", key)
+}
+
+func setString(key string, v any, dst *string) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a string", key)
+	}
+	*dst = s
+	return nil
+}
+
+func setStrings(key string, v any, dst *[]string) error {
+	list, ok := v.([]string)
+	if !ok {
+		return fmt.Errorf("%s must be an array of strings", key)
+	}
+	*dst = list
+	return nil
+}
+
+func setBool(key string, v any, dst *bool) error {
+	b, ok := v.(bool)
+	if !ok {
+		return fmt.Errorf("%s must be true or false", key)
+	}
+	*dst = b
+	return nil
+}
+
+// parseValue reads a string, boolean, integer or array of strings.
+func parseValue(raw string) (any, error) {
+	switch {
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	case strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "'"):
+		s, rest, err := parseString(raw)
+		if err != nil {
+			return nil, err
+		}
+		if rest != "" {
+			return nil, fmt.Errorf("unexpected %q after string", rest)
+		}
+		return s, nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("unterminated array")
+		}
+		list := []string{}
+		rest := strings.TrimSpace(raw[1 : len(raw)-1])
+		for rest != "" {
+			s, after, err := parseString(rest)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, s)
+			after, comma := strings.CutPrefix(after, ",")
+			if !comma && after != "" {
+				return nil, fmt.Errorf("expected , between array elements")
+			}
+			rest = strings.TrimSpace(after)
+		}
+		return list, nil
+	}
+	n, err := strconv.Atoi(strings.ReplaceAll(raw, "_", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s", raw)
+	}
+	return n, nil
+}
+
+// parseString reads the quoted string at the start of s and returns it and
+// what follows it, trimmed.
+func parseString(s string) (value, rest string, err error) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], strings.TrimSpace(s[end+2:]), nil
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("invalid string %s", s[:i+1])
+				}
+				return value, strings.TrimSpace(s[i+1:]), nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated string")
+	}
+	return "", "", fmt.Errorf("expected a string, got %s", s)
+}
+
+// stripComment removes a # comment that is not inside a string.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# project settings
+entry = "main.singlish"
+dictionary = [
+  "dict/team.txt", # shared
+  'dict/mine.txt',
+]
+insult-level = "mild"
+
+[build]
+output-dir = "bin"
+ldflags = "-s -w -X main.version=1.2"
+trimpath = true
+flags = ["-v", "-gcflags=all=-N"]
+
+[fmt]
+indent = 4
+
+[vet]
+disable = ["unused", "SG6003"]
+`
+	c, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if c.Entry != "main.singlish" || c.InsultLevel != "mild" {
+		t.Errorf("Parse() = entry %q, insult level %q", c.Entry, c.InsultLevel)
+	}
+	if want := []string{"dict/team.txt", "dict/mine.txt"}; !slices.Equal(c.Dictionaries, want) {
+		t.Errorf("Dictionaries = %q, want %q", c.Dictionaries, want)
+	}
+	b := c.Build
+	if b.OutputDir != "bin" || b.LDFlags != "-s -w -X main.version=1.2" || !b.Trimpath || b.Race {
+		t.Errorf("Build = %+v", b)
+	}
+	if want := []string{"-v", "-gcflags=all=-N"}; !slices.Equal(b.Flags, want) {
+		t.Errorf("Build.Flags = %q, want %q", b.Flags, want)
+	}
+	if c.Fmt.Indent != 4 {
+		t.Errorf("Fmt.Indent = %d, want 4", c.Fmt.Indent)
+	}
+	if want := []string{"unused", "SG6003"}; !slices.Equal(c.Vet.Disable, want) || c.Vet.Enable != nil {
+		t.Errorf("Vet = %+v, want disable %q", c.Vet, want)
+	}
+
+	c, err = Parse(`dictionary = "only.txt"`)
+	if err != nil || !slices.Equal(c.Dictionaries, []string{"only.txt"}) {
+		t.Errorf("Parse(single dictionary) = %+v, %v", c, err)
+	}
+	c, err = Parse(`entry = "a # b.singlish" # comment`)
+	if err != nil || c.Entry != "a # b.singlish" {
+		t.Errorf("Parse(# in string) = %+v, %v", c, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"colour = \"red\"\n", "1: unknown key colour"},
+		{"\n[run]\n", "2: unknown table [run]"},
+		{"[build]\nrace = \"yes\"\n", "2: build.race must be true or false"},
+		{"[fmt]\nindent = -2\n", "2: fmt.indent must be"},
+		{"entry = main.singlish\n", "1: entry: invalid value"},
+		{"entry = \"main.singlish\n", "1: entry: unterminated string"},
+		{"dictionary = [\"a\" \"b\"]\n", "1: dictionary: expected ,"},
+		{"entry\n", "1: expected key = value"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.src)
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src", "shop")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	toml := "entry = \"src/main.singlish\"\ndictionary = [\"team.txt\", \"/etc/singlish.txt\"]\n[build]\noutput-dir = \"bin\"\n"
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(toml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := Discover(filepath.Join(src, "kopi.singlish"))
+	if err != nil {
+		t.Fatalf("Discover failed: %v", err)
+	}
+	if c.Path != filepath.Join(root, FileName) {
+		t.Errorf("Path = %q", c.Path)
+	}
+	if c.Entry != filepath.Join(root, "src", "main.singlish") || c.Build.OutputDir != filepath.Join(root, "bin") {
+		t.Errorf("relative paths not resolved: %+v", c)
+	}
+	if want := []string{filepath.Join(root, "team.txt"), "/etc/singlish.txt"}; !slices.Equal(c.Dictionaries, want) {
+		t.Errorf("Dictionaries = %q, want %q", c.Dictionaries, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, FileName), []byte("[fmt]\nwidth = 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Discover(src); err == nil || !strings.Contains(err.Error(), FileName+":2: unknown key fmt.width") {
+		t.Errorf("Discover() error = %v, want the nearest file's error", err)
+	}
+
+	c, err = Discover(t.TempDir())
+	if err != nil || c.Path != "" {
+		t.Errorf("Discover() without a file = %+v, %v; want an empty Config", c, err)
+	}
+}
@@ -1,88 +1,48 @@
 package dictionaries
 
+import (
+	_ "embed"
+	"sync"
+)
+
+// defaultJSON is the built-in dictionary, with a description and example
+// for every word.
+//
+//go:embed default.json
+var defaultJSON string
+
+var defaultEntries = sync.OnceValue(func() []Entry {
+	f, diags := ParseFile(defaultJSON)
+	entries := f.Entries
+	if len(diags) > 0 {
+		panic("dictionaries: invalid default.json: " + diags[0].Message)
+	}
+	// Built-in entries have no position a user could open.
+	for i := range entries {
+		entries[i].Line, entries[i].Col, entries[i].GoLine, entries[i].GoCol = 0, 0, 0, 0
+		entries[i].Origin = BuiltIn
+	}
+	return entries
+})
+
+// DefaultEntries returns the built-in entries, with their metadata, in the
+// order they are defined.
+func DefaultEntries() []Entry {
+	return append([]Entry(nil), defaultEntries()...)
+}
+
 // GetDefaultMappings returns the built-in Singlish to Go keyword mappings.
 // This allows the transpiler to run without an external dictionary file.
 func GetDefaultMappings() map[string]string {
-	return map[string]string{
-		"kampung": "package",
-		"dapao":   "import",
-		"action":  "func",
-		"boss":    "main",
-
-		"got":     "var",
-		"confirm": "const",
-		"auto":    "iota",
-		"pattern": "type",
-
-		"nasi":    "if",
-		"den":     "else",
-		"tikam":   "select",
-		"see_how": "switch",
-		"say":     "case",
-		"tompang": "fallthrough",
-		"anyhow":  "default",
-		"flykite": "goto",
-
-		"loop":  "for",
-		"all":   "range",
-		"cabut": "break",
-		"go":    "continue",
-
-		"balek": "return",
-		"nanti": "defer",
-
-		"chiong": "go",
-		"lobang": "chan",
-		"pass":   "<-",
-		"catch":  "<-",
-
-		"can":      "true",
-		"cannot":   "false",
-		"kosong":   "nil",
-		"bolehtak": "bool",
-		"nombor":   "int",
-		"banyak":   "int64",
-		"point":    "float64",
-		"cheem":    "complex128",
-		"tar":      "string",
-		"barang":   "struct",
-		"salah":    "error",
-		"gabra":    "panic",
-		"ki":       "*",
-		"zhi":      "rune",
-		"heng":     "recover",
-
-		"kaki": "interface",
-		"menu": "map",
-
-		"buat":   "make",
-		"upsize": "append",
-		"buang":  "delete",
-		"count":  "len",
-		"kwear":  "close",
-		"gong":   "fmt.Println",
-
-		"somemore": "&&",
-		"dun":      "!",
-		"or":       "||",
+	mappings := make(map[string]string)
+	for _, e := range defaultEntries() {
+		mappings[e.Word] = e.Target
 	}
+	return mappings
 }
 
-// NewDefaultDictionary creates a new Dictionary populated with the default Singlish mappings.
+// NewDefaultDictionary creates a new Dictionary populated with the default
+// Singlish mappings.
 func NewDefaultDictionary() *Dictionary {
-	defaults := GetDefaultMappings()
-	dict := &Dictionary{
-		mapping:        make(map[string]string),
-		reverseMapping: make(map[string]string),
-	}
-
-	for singlish, goKeyword := range defaults {
-		dict.mapping[singlish] = goKeyword
-
-		// First entry wins as canonical for reverse lookup
-		if _, exists := dict.reverseMapping[goKeyword]; !exists {
-			dict.reverseMapping[goKeyword] = singlish
-		}
-	}
-	return dict
+	return fromEntries(defaultEntries())
 }
@@ -1,9 +1,11 @@
 package dictionaries
 
 import (
-	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -11,49 +13,196 @@ import (
 type Dictionary struct {
 	mapping        map[string]string
 	reverseMapping map[string]string
+	imports        map[string]string // Go of qualified aliases -> package to import
+	entries        map[string]Entry
+	order          []string  // words in the order they were defined
+	files          []string  // files read to build the dictionary
+	removed        []Removal // words removed by overlays
 }
 
+// Removal records a word an overlay removed from the dictionaries below it.
+type Removal struct {
+	Word   string
+	Origin string // file of the @remove directive
+	Line   int
+}
+
+// BuiltIn is the Origin of entries from the built-in dictionary.
+const BuiltIn = "built-in"
+
 // LoadDictionary reads the dictionary file from the given filePath and returns a new Dictionary.
 func LoadDictionary(filePath string) (*Dictionary, error) {
-	file, err := os.Open(filePath)
+	return Load(filePath)
+}
+
+// Load reads dictionary files and merges them in order, each one layered
+// on those before it: its entries override theirs and its @remove
+// directives drop their words. A file that extends other dictionaries is
+// layered on them first; the name "default" extends the built-in
+// dictionary, and other names are files relative to the one extending
+// them. With no paths, Load returns the built-in dictionary.
+func Load(paths ...string) (*Dictionary, error) {
+	if len(paths) == 0 {
+		return NewDefaultDictionary(), nil
+	}
+	l := &loader{index: make(map[string]int)}
+	for _, path := range paths {
+		if err := l.load(path, nil); err != nil {
+			return nil, err
+		}
+	}
+	var entries []Entry
+	for _, e := range l.entries {
+		if e.Word != "" {
+			entries = append(entries, e)
+		}
+	}
+	dict := fromEntries(entries)
+	dict.files = l.files
+	dict.removed = l.removed
+	return dict, nil
+}
+
+// loader merges dictionary layers.
+type loader struct {
+	entries []Entry        // merged entries; removed ones have no Word
+	index   map[string]int // word -> its position in entries
+	files   []string
+	removed []Removal
+}
+
+// load layers the dictionary at path onto l. stack holds the files being
+// loaded, to catch a dictionary that extends itself.
+func (l *loader) load(path string, stack []string) error {
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open dictionary file %q: %w", filePath, err)
+		return err
 	}
-	defer file.Close()
+	if slices.Contains(stack, abs) {
+		return fmt.Errorf("dictionary %q extends itself", path)
+	}
+	stack = append(stack, abs)
 
-	dict := &Dictionary{
-		mapping:        make(map[string]string),
-		reverseMapping: make(map[string]string),
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to open dictionary file %q: %w", path, err)
+	}
+	f, diags := ParseFile(string(data))
+	if len(diags) > 0 {
+		return fmt.Errorf("%s:%d: %s", path, diags[0].Line, diags[0].Message)
+	}
+	if !slices.Contains(l.files, path) {
+		l.files = append(l.files, path)
 	}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
-			continue // Skip empty lines and comments
+	for _, ext := range f.Extends {
+		if ext.Name == DefaultName {
+			l.add(defaultEntries(), BuiltIn)
+			continue
 		}
+		if err := l.load(resolve(path, ext.Name), stack); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, ext.Line, err)
+		}
+	}
+	for _, r := range f.Remove {
+		if i, ok := l.index[r.Name]; ok {
+			l.entries[i] = Entry{}
+			delete(l.index, r.Name)
+		}
+		l.removed = append(l.removed, Removal{Word: r.Name, Origin: path, Line: r.Line})
+	}
+	l.add(f.Entries, path)
+	return nil
+}
 
-		parts := strings.SplitN(line, ":", 2)
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid dictionary entry in %q: %s", filePath, line)
+// add layers entries from origin onto l.
+func (l *loader) add(entries []Entry, origin string) {
+	for _, e := range entries {
+		e.Origin = origin
+		if i, ok := l.index[e.Word]; ok {
+			l.entries[i] = e
+			continue
 		}
+		l.index[e.Word] = len(l.entries)
+		l.entries = append(l.entries, e)
+	}
+}
 
-		singlish := strings.TrimSpace(parts[0])
-		goKeyword := strings.TrimSpace(parts[1])
-		dict.mapping[singlish] = goKeyword
+// resolve returns the path of the dictionary name extended by the file at
+// from.
+func resolve(from, name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(from), name)
+}
 
-		// First entry wins as canonical for reverse lookup
-		if _, exists := dict.reverseMapping[goKeyword]; !exists {
-			dict.reverseMapping[goKeyword] = singlish
+// fromEntries builds a dictionary from entries. A word defined twice takes
+// its last definition. For reverse lookup, the canonical word for a Go
+// keyword wins, or else the first word defined for it.
+func fromEntries(entries []Entry) *Dictionary {
+	dict := &Dictionary{
+		mapping:        make(map[string]string),
+		reverseMapping: make(map[string]string),
+		imports:        make(map[string]string),
+		entries:        make(map[string]Entry),
+	}
+	for _, e := range entries {
+		if _, exists := dict.entries[e.Word]; !exists {
+			dict.order = append(dict.order, e.Word)
+		}
+		dict.mapping[e.Word] = e.Target
+		if path, expr, ok := SplitQualified(e.Target); ok {
+			dict.mapping[e.Word] = expr
+			dict.imports[expr] = path
 		}
+		dict.entries[e.Word] = e
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading dictionary file %q: %w", filePath, err)
+	for _, canonicalOnly := range []bool{true, false} {
+		for _, word := range dict.order {
+			e := dict.entries[word]
+			if canonicalOnly && !e.Canonical {
+				continue
+			}
+			target := dict.mapping[word]
+			if _, exists := dict.reverseMapping[target]; !exists {
+				dict.reverseMapping[target] = word
+			}
+		}
 	}
+	return dict
+}
 
-	return dict, nil
+var (
+	importPath   = regexp.MustCompile(`^[A-Za-z0-9_.~-]+(/[A-Za-z0-9_.~-]+)*$`)
+	majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+)
+
+// SplitQualified splits the target of a qualified alias, such as
+// "strconv.Atoi" or "math/rand.Intn", into the path of the package to
+// import and the Go written in code: "strconv.Atoi" or "rand.Intn". The
+// package is named after the last element of its path, leaving out a
+// major version such as v2 and anything after a dot, as in gopkg.in/yaml.v3.
+// It reports false for targets that are not qualified.
+func SplitQualified(target string) (path, expr string, ok bool) {
+	dot := strings.LastIndex(target, ".")
+	if dot <= 0 {
+		return "", "", false
+	}
+	path, member := target[:dot], target[dot+1:]
+	if !importPath.MatchString(path) || !isIdentifier(member) || goKeywords[member] {
+		return "", "", false
+	}
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && majorVersion.MatchString(name) {
+		name = elems[len(elems)-2]
+	}
+	name, _, _ = strings.Cut(name, ".")
+	if !isIdentifier(name) || goKeywords[name] {
+		return "", "", false
+	}
+	return path, name + "." + member, true
 }
 
 // Lookup returns the Go keyword for a given Singlish keyword.
@@ -69,6 +218,14 @@ func (d *Dictionary) ReverseLookup(goKeyword string) (string, bool) {
 	return singlish, found
 }
 
+// Import returns the path of the package that the Go of a qualified alias,
+// as returned by Lookup, belongs to: "fmt" for fmt.Println, "math/rand" for
+// rand.Intn when a word stands for math/rand.Intn.
+func (d *Dictionary) Import(goExpr string) (string, bool) {
+	path, found := d.imports[goExpr]
+	return path, found
+}
+
 // Keys returns all Singlish keywords in the dictionary.
 func (d *Dictionary) Keys() []string {
 	keys := make([]string, 0, len(d.mapping))
@@ -77,3 +234,31 @@ func (d *Dictionary) Keys() []string {
 	}
 	return keys
 }
+
+// Entry returns the entry defining word, with its metadata.
+func (d *Dictionary) Entry(word string) (Entry, bool) {
+	e, found := d.entries[word]
+	return e, found
+}
+
+// Entries returns the entries of the dictionary in the order their words
+// were first defined, one per word.
+func (d *Dictionary) Entries() []Entry {
+	entries := make([]Entry, len(d.order))
+	for i, word := range d.order {
+		entries[i] = d.entries[word]
+	}
+	return entries
+}
+
+// Files returns the dictionary files read to build d, including those it
+// extends, in the order they were first read.
+func (d *Dictionary) Files() []string {
+	return slices.Clone(d.files)
+}
+
+// Removed returns the words overlays removed, in the order they were
+// removed.
+func (d *Dictionary) Removed() []Removal {
+	return slices.Clone(d.removed)
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"strings"
 )
 
 // Helper to create a temporary dictionary file for testing
@@ -139,3 +141,126 @@ nasi: if
 		})
 	}
 }
+
+func TestLoadLayers(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("base.txt", "@extends default\n@remove go tikam\nlepak: continue\ncakap: fmt.Println\n")
+	team := write("team.json", `{"extends": "base.txt", "entries": [{"word": "nasi", "go": "for"}]}`)
+	mine := write("mine.txt", "@remove cakap\nbising: fmt.Println\n")
+
+	dict, err := Load(team, mine)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for word, want := range map[string]string{
+		"kampung": "package",     // built-in
+		"lepak":   "continue",    // base.txt
+		"nasi":    "for",         // team.json overrides the built-in if
+		"bising":  "fmt.Println", // mine.txt
+	} {
+		if got, ok := dict.Lookup(word); !ok || got != want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", word, got, ok, want)
+		}
+	}
+	for _, word := range []string{"go", "tikam", "cakap"} {
+		if _, ok := dict.Lookup(word); ok {
+			t.Errorf("Lookup(%q) found a removed word", word)
+		}
+	}
+
+	origins := map[string]string{"kampung": BuiltIn, "lepak": filepath.Join(dir, "base.txt"), "nasi": team, "bising": mine}
+	for word, want := range origins {
+		if e, _ := dict.Entry(word); e.Origin != want {
+			t.Errorf("Entry(%q).Origin = %q, want %q", word, e.Origin, want)
+		}
+	}
+	if e, _ := dict.Entry("lepak"); e.Line != 3 {
+		t.Errorf("Entry(lepak).Line = %d, want 3", e.Line)
+	}
+
+	if got := dict.Files(); len(got) != 3 {
+		t.Errorf("Files() = %v, want the three files", got)
+	}
+	var removed []string
+	for _, r := range dict.Removed() {
+		removed = append(removed, r.Word)
+	}
+	if strings.Join(removed, " ") != "go tikam cakap" {
+		t.Errorf("Removed() = %v", removed)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	loop := filepath.Join(dir, "loop.txt")
+	if err := os.WriteFile(loop, []byte("@extends loop.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(loop); err == nil || !strings.Contains(err.Error(), "extends itself") {
+		t.Errorf("Load(loop) error = %v, want one about extending itself", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Errorf("Load(missing) succeeded")
+	}
+	if dict, err := Load(); err != nil || len(dict.Keys()) != len(GetDefaultMappings()) {
+		t.Errorf("Load() = %v, %v; want the built-in dictionary", dict, err)
+	}
+}
+
+func TestSplitQualified(t *testing.T) {
+	tests := []struct {
+		target, path, expr string
+		ok                 bool
+	}{
+		{"fmt.Println", "fmt", "fmt.Println", true},
+		{"math/rand.Intn", "math/rand", "rand.Intn", true},
+		{"github.com/google/uuid.New", "github.com/google/uuid", "uuid.New", true},
+		{"github.com/jackc/pgx/v5.Connect", "github.com/jackc/pgx/v5", "pgx.Connect", true},
+		{"gopkg.in/yaml.v3.Unmarshal", "gopkg.in/yaml.v3", "yaml.Unmarshal", true},
+		{"Println", "", "", false},
+		{"fmt.Println()", "", "", false},
+		{"fmt.func", "", "", false},
+		{"go.Run", "", "", false},
+		{"a//b.C", "", "", false},
+	}
+	for _, tt := range tests {
+		path, expr, ok := SplitQualified(tt.target)
+		if path != tt.path || expr != tt.expr || ok != tt.ok {
+			t.Errorf("SplitQualified(%q) = %q, %q, %v; want %q, %q, %v", tt.target, path, expr, ok, tt.path, tt.expr, tt.ok)
+		}
+	}
+}
+
+func TestQualifiedAliases(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.txt")
+	if err := os.WriteFile(path, []byte("lepak: time.Sleep\ntikam: math/rand.Intn\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for word, want := range map[string][2]string{"lepak": {"time.Sleep", "time"}, "tikam": {"rand.Intn", "math/rand"}} {
+		expr, _ := dict.Lookup(word)
+		pkg, ok := dict.Import(expr)
+		if expr != want[0] || !ok || pkg != want[1] {
+			t.Errorf("%s: Lookup = %q, Import = %q, %v; want %q from %q", word, expr, pkg, ok, want[0], want[1])
+		}
+		if back, _ := dict.ReverseLookup(expr); back != word {
+			t.Errorf("ReverseLookup(%q) = %q, want %q", expr, back, word)
+		}
+	}
+	if e, _ := dict.Entry("tikam"); e.Target != "math/rand.Intn" {
+		t.Errorf("Entry(tikam).Target = %q, want the target as written", e.Target)
+	}
+	if _, ok := dict.Import("package"); ok {
+		t.Errorf("Import(package) found a package")
+	}
+}
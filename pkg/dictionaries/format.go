@@ -0,0 +1,322 @@
+package dictionaries
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// Categories of entries.
+const (
+	CategoryKeyword  = "keyword"  // a Go keyword, such as kampung for package
+	CategoryType     = "type"     // a predeclared type, such as nombor for int
+	CategoryBuiltin  = "builtin"  // a predeclared value or function, or a library function
+	CategoryOperator = "operator" // an operator, such as somemore for &&
+)
+
+// Entry is one mapping of a dictionary as written, before duplicates are
+// resolved.
+type Entry struct {
+	Word   string // the Singlish word, or a phrase of words separated by single spaces
+	Target string // the Go it stands for
+	Line   int    // 1-based line of Word in the file, or 0 for built-in entries
+	Col    int    // column of Word
+	GoLine int    // line of Target
+	GoCol  int    // column of Target
+	Origin string // file the entry was read from, or "built-in"; set by Load
+
+	// Metadata. Only JSON dictionaries can set it; for the text format the
+	// category is inferred from the target and the rest is empty.
+	Category    string // one of the Category constants, or ""
+	Canonical   bool   // the word fmt writes when several stand for Target
+	Description string // what the word means, for help and documentation
+	Example     string // a line of Singlish using the word
+}
+
+// File is a parsed dictionary file.
+type File struct {
+	Extends []Reference // dictionaries this one is layered on, in order
+	Remove  []Reference // words to drop from the dictionaries below
+	Entries []Entry
+}
+
+// Reference is a dictionary or word named by an extends or remove
+// directive, with where it was named.
+type Reference struct {
+	Name      string
+	Line, Col int
+}
+
+// DefaultName is the name that extends the built-in dictionary.
+const DefaultName = "default"
+
+// ParseFile reads dictionary source. Source starting with { is a JSON
+// dictionary:
+//
+//	{
+//	  "extends": "default",
+//	  "remove": ["go"],
+//	  "entries": [
+//	    {"word": "kampung", "go": "package", "category": "keyword",
+//	     "description": "A package is a village.", "example": "kampung main"}
+//	  ]
+//	}
+//
+// Anything else is the text format of "word: go" lines, in which
+// "@extends name" and "@remove word..." lines are the directives. They may
+// also be written "extends: name" and "remove: word...", like the JSON
+// keys, so extends and remove cannot be defined as words. Lines that
+// cannot be read are reported as diagnostics and skipped.
+func ParseFile(src string) (*File, []lexer.Diagnostic) {
+	if strings.HasPrefix(strings.TrimSpace(src), "{") {
+		return parseJSON(src)
+	}
+	return parseText(src)
+}
+
+// ParseEntries reads the entries of dictionary source, ignoring its
+// directives. See ParseFile.
+func ParseEntries(src string) ([]Entry, []lexer.Diagnostic) {
+	f, diags := ParseFile(src)
+	return f.Entries, diags
+}
+
+func parseText(src string) (*File, []lexer.Diagnostic) {
+	f := &File{}
+	var diags []lexer.Diagnostic
+	scanner := bufio.NewScanner(strings.NewReader(src))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		indent := utf8.RuneCountInString(raw[:strings.Index(raw, line)])
+		word, target, ok := strings.Cut(line, ":")
+		if strings.HasPrefix(line, "@") || ok && isDirective(strings.TrimSpace(word)) {
+			diags = append(diags, f.directive(line, lineNo, indent+1)...)
+			continue
+		}
+		if !ok || strings.TrimSpace(word) == "" || strings.TrimSpace(target) == "" {
+			diags = append(diags, lexer.Diagnostic{
+				Message: fmt.Sprintf("invalid dictionary entry %q", line),
+				Line:    lineNo,
+				Col:     indent + 1,
+				Length:  utf8.RuneCountInString(line),
+				Code:    lexer.CodeDictSyntax,
+				Notes:   []string{"entries look like `kampung: package`"},
+			})
+			continue
+		}
+		goCol := indent + utf8.RuneCountInString(word) + 2
+		goCol += utf8.RuneCountInString(target) - utf8.RuneCountInString(strings.TrimLeftFunc(target, unicode.IsSpace))
+		e := Entry{
+			Word:   normalizeWord(word),
+			Target: strings.TrimSpace(target),
+			Line:   lineNo,
+			Col:    indent + 1,
+			GoLine: lineNo,
+			GoCol:  goCol,
+		}
+		e.Category = InferCategory(e.Target)
+		f.Entries = append(f.Entries, e)
+	}
+	return f, diags
+}
+
+// isDirective reports whether word names a directive.
+func isDirective(word string) bool {
+	return word == "extends" || word == "remove"
+}
+
+// directive records an "@extends name" or "@remove word..." line, or one
+// written "extends: name" or "remove: word...", starting at col.
+func (f *File) directive(line string, lineNo, col int) []lexer.Diagnostic {
+	bad := func(msg string) []lexer.Diagnostic {
+		return []lexer.Diagnostic{{
+			Message: msg,
+			Line:    lineNo,
+			Col:     col,
+			Length:  utf8.RuneCountInString(line),
+			Code:    lexer.CodeDictSyntax,
+			Notes:   []string{"directives are `@extends default`, `@extends other.txt` and `@remove word...`"},
+		}}
+	}
+	kind, args := strings.TrimPrefix(line, "@"), ""
+	if i := strings.IndexAny(kind, ": \t"); i >= 0 {
+		kind, args = kind[:i], kind[i+1:]
+	}
+	fields := append([]string{kind}, strings.Fields(args)...)
+	if len(fields) < 2 {
+		return bad(fmt.Sprintf("incomplete directive %q", line))
+	}
+	refs := make([]Reference, 0, len(fields)-1)
+	offset := len(line) - len(args)
+	for _, name := range fields[1:] {
+		i := strings.Index(line[offset:], name) + offset
+		refs = append(refs, Reference{Name: name, Line: lineNo, Col: col + utf8.RuneCountInString(line[:i])})
+		offset = i + len(name)
+	}
+	switch fields[0] {
+	case "extends":
+		if len(refs) > 1 {
+			return bad("@extends takes one dictionary; use one line for each")
+		}
+		f.Extends = append(f.Extends, refs...)
+	case "remove":
+		f.Remove = append(f.Remove, refs...)
+	default:
+		return bad(fmt.Sprintf("unknown directive @%s", fields[0]))
+	}
+	return nil
+}
+
+// jsonDictionary is the layout of a JSON dictionary.
+type jsonDictionary struct {
+	Extends string      `json:"extends,omitempty"`
+	Remove  []string    `json:"remove,omitempty"`
+	Entries []jsonEntry `json:"entries"`
+}
+
+type jsonEntry struct {
+	Word        string `json:"word"`
+	Go          string `json:"go"`
+	Category    string `json:"category,omitempty"`
+	Canonical   bool   `json:"canonical,omitempty"`
+	Description string `json:"description,omitempty"`
+	Example     string `json:"example,omitempty"`
+}
+
+// These find where values start, so that problems can be reported at the
+// right place.
+var (
+	jsonWordKey    = regexp.MustCompile(`"word"\s*:\s*"`)
+	jsonGoKey      = regexp.MustCompile(`"go"\s*:\s*"`)
+	jsonExtendsKey = regexp.MustCompile(`"extends"\s*:\s*"`)
+	jsonRemoveKey  = regexp.MustCompile(`"remove"\s*:\s*\[`)
+)
+
+func parseJSON(src string) (*File, []lexer.Diagnostic) {
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.DisallowUnknownFields()
+	var file jsonDictionary
+	if err := dec.Decode(&file); err != nil {
+		var offset int64
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			offset = syntaxErr.Offset
+		case errors.As(err, &typeErr):
+			offset = typeErr.Offset
+		default:
+			offset = dec.InputOffset()
+		}
+		line, col := position(src, int(offset))
+		return &File{}, []lexer.Diagnostic{{
+			Message: "invalid JSON dictionary: " + strings.TrimPrefix(err.Error(), "json: "),
+			Line:    line,
+			Col:     col,
+			Length:  1,
+			Code:    lexer.CodeDictSyntax,
+		}}
+	}
+
+	f := &File{}
+	if file.Extends != "" {
+		ref := Reference{Name: file.Extends}
+		if loc := jsonExtendsKey.FindStringIndex(src); loc != nil {
+			ref.Line, ref.Col = position(src, loc[1])
+		}
+		f.Extends = append(f.Extends, ref)
+	}
+	if loc := jsonRemoveKey.FindStringIndex(src); loc != nil {
+		offset := loc[1]
+		for _, word := range file.Remove {
+			ref := Reference{Name: word}
+			if i := strings.Index(src[offset:], `"`+word+`"`); i >= 0 {
+				ref.Line, ref.Col = position(src, offset+i+1)
+				offset += i + len(word) + 2
+			}
+			f.Remove = append(f.Remove, ref)
+		}
+	}
+
+	words := jsonWordKey.FindAllStringIndex(src, -1)
+	targets := jsonGoKey.FindAllStringIndex(src, -1)
+	var diags []lexer.Diagnostic
+	for i, je := range file.Entries {
+		e := Entry{
+			Word:        normalizeWord(je.Word),
+			Target:      strings.TrimSpace(je.Go),
+			Category:    je.Category,
+			Canonical:   je.Canonical,
+			Description: je.Description,
+			Example:     je.Example,
+		}
+		if i < len(words) {
+			e.Line, e.Col = position(src, words[i][1])
+		}
+		if i < len(targets) {
+			e.GoLine, e.GoCol = position(src, targets[i][1])
+		}
+		if e.Word == "" || e.Target == "" {
+			diags = append(diags, lexer.Diagnostic{
+				Message: fmt.Sprintf("dictionary entry %d needs both a word and its go", i+1),
+				Line:    e.Line,
+				Col:     e.Col,
+				Length:  1,
+				Code:    lexer.CodeDictSyntax,
+				Notes:   []string{`entries look like {"word": "kampung", "go": "package"}`},
+			})
+			continue
+		}
+		if e.Category == "" {
+			e.Category = InferCategory(e.Target)
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f, diags
+}
+
+// normalizeWord trims a word and separates the words of a phrase keyword
+// with single spaces, the form the lexer gives them.
+func normalizeWord(word string) string {
+	return strings.Join(strings.Fields(word), " ")
+}
+
+// position converts a byte offset in src into a 1-based line and column.
+func position(src string, offset int) (line, col int) {
+	offset = min(max(offset, 0), len(src))
+	before := src[:offset]
+	line = strings.Count(before, "\n") + 1
+	col = utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+	return line, col
+}
+
+// InferCategory guesses the category of a word from the Go it stands for,
+// returning "" if it cannot tell.
+func InferCategory(target string) string {
+	switch {
+	case goKeywords[target]:
+		return CategoryKeyword
+	case goTypes[target]:
+		return CategoryType
+	case goPredeclared[target]:
+		return CategoryBuiltin
+	case operators[target]:
+		return CategoryOperator
+	case strings.Contains(target, "."):
+		return CategoryBuiltin
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package dictionaries
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+
+	"slices"
+)
+
+func TestParseEntries(t *testing.T) {
+	src := "# comment\nkampung: package\n  gong :  fmt.Println\nbad line\nsee \t how: switch\n"
+	entries, diags := ParseEntries(src)
+
+	want := []Entry{
+		{Word: "kampung", Target: "package", Line: 2, Col: 1, GoLine: 2, GoCol: 10, Category: CategoryKeyword},
+		{Word: "gong", Target: "fmt.Println", Line: 3, Col: 3, GoLine: 3, GoCol: 11, Category: CategoryBuiltin},
+		{Word: "see how", Target: "switch", Line: 5, Col: 1, GoLine: 5, GoCol: 12, Category: CategoryKeyword},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("ParseEntries() = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+	if len(diags) != 1 || diags[0].Line != 4 || diags[0].Code != lexer.CodeDictSyntax {
+		t.Errorf("diagnostics = %+v, want one %s on line 4", diags, lexer.CodeDictSyntax)
+	}
+}
+
+func TestParseEntriesJSON(t *testing.T) {
+	src := `{
+  "entries": [
+    {"word": "kampung", "go": "package", "description": "A village.", "example": "kampung main"},
+    {
+      "word": "catch",
+      "go": "<-",
+      "category": "operator",
+      "canonical": true
+    }
+  ]
+}
+`
+	entries, diags := ParseEntries(src)
+	if len(diags) > 0 {
+		t.Fatalf("ParseEntries() diagnostics: %+v", diags)
+	}
+	want := []Entry{
+		{Word: "kampung", Target: "package", Line: 3, Col: 15, GoLine: 3, GoCol: 32,
+			Category: CategoryKeyword, Description: "A village.", Example: "kampung main"},
+		{Word: "catch", Target: "<-", Line: 5, Col: 16, GoLine: 6, GoCol: 14,
+			Category: CategoryOperator, Canonical: true},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("ParseEntries() = %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestParseEntriesJSONErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		line    int
+		message string
+	}{
+		{"syntax", "{\n  \"entries\": [\n    {\"word\": \"a\" \"go\": \"b\"}\n  ]\n}\n", 3, "invalid JSON dictionary"},
+		{"unknown field", "{\"entries\": [{\"word\": \"a\", \"go\": \"if\", \"colour\": \"red\"}]}", 1, "unknown field"},
+		{"wrong type", "{\"entries\": [{\"word\": \"a\", \"go\": 5}]}", 1, "invalid JSON dictionary"},
+		{"missing go", "{\"entries\": [\n{\"word\": \"a\"}]}", 2, "needs both a word and its go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, diags := ParseEntries(tt.src)
+			if len(diags) != 1 {
+				t.Fatalf("ParseEntries() = %+v, want one diagnostic", diags)
+			}
+			if diags[0].Line != tt.line || !strings.Contains(diags[0].Message, tt.message) {
+				t.Errorf("got %q on line %d, want %q on line %d", diags[0].Message, diags[0].Line, tt.message, tt.line)
+			}
+		})
+	}
+}
+
+func TestInferCategory(t *testing.T) {
+	for target, want := range map[string]string{
+		"package":     CategoryKeyword,
+		"int":         CategoryType,
+		"len":         CategoryBuiltin,
+		"nil":         CategoryBuiltin,
+		"fmt.Println": CategoryBuiltin,
+		"&&":          CategoryOperator,
+		"main":        "",
+	} {
+		if got := InferCategory(target); got != want {
+			t.Errorf("InferCategory(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestDefaultEntries(t *testing.T) {
+	entries := DefaultEntries()
+	if len(entries) != len(GetDefaultMappings()) {
+		t.Fatalf("DefaultEntries() has %d entries, GetDefaultMappings() %d", len(entries), len(GetDefaultMappings()))
+	}
+	for _, e := range entries {
+		if e.Category == "" || e.Description == "" || e.Example == "" {
+			t.Errorf("built-in entry %q is missing metadata: %+v", e.Word, e)
+		}
+	}
+	if word, _ := NewDefaultDictionary().ReverseLookup("<-"); word != "pass" {
+		t.Errorf("ReverseLookup(<-) = %q, want the canonical pass", word)
+	}
+}
+
+func TestParseFileDirectives(t *testing.T) {
+	text := "@extends default\n  @remove go  tikam\nlepak: continue\n"
+	f, diags := ParseFile(text)
+	if len(diags) > 0 {
+		t.Fatalf("ParseFile(text) diagnostics: %+v", diags)
+	}
+	wantExtends := []Reference{{Name: "default", Line: 1, Col: 10}}
+	wantRemove := []Reference{{Name: "go", Line: 2, Col: 11}, {Name: "tikam", Line: 2, Col: 15}}
+	if !slices.Equal(f.Extends, wantExtends) || !slices.Equal(f.Remove, wantRemove) {
+		t.Errorf("ParseFile(text) = extends %+v, remove %+v; want %+v, %+v", f.Extends, f.Remove, wantExtends, wantRemove)
+	}
+	if len(f.Entries) != 1 || f.Entries[0].Word != "lepak" {
+		t.Errorf("ParseFile(text) entries = %+v", f.Entries)
+	}
+
+	text = "extends: default\nremove: go  tikam\n"
+	f, diags = ParseFile(text)
+	if len(diags) > 0 {
+		t.Fatalf("ParseFile(keys) diagnostics: %+v", diags)
+	}
+	wantExtends = []Reference{{Name: "default", Line: 1, Col: 10}}
+	wantRemove = []Reference{{Name: "go", Line: 2, Col: 9}, {Name: "tikam", Line: 2, Col: 13}}
+	if !slices.Equal(f.Extends, wantExtends) || !slices.Equal(f.Remove, wantRemove) || len(f.Entries) != 0 {
+		t.Errorf("ParseFile(keys) = extends %+v, remove %+v, entries %+v; want %+v, %+v", f.Extends, f.Remove, f.Entries, wantExtends, wantRemove)
+	}
+
+	js := "{\n  \"extends\": \"team.txt\",\n  \"remove\": [\"go\", \"tikam\"],\n  \"entries\": []\n}\n"
+	f, diags = ParseFile(js)
+	if len(diags) > 0 {
+		t.Fatalf("ParseFile(json) diagnostics: %+v", diags)
+	}
+	wantExtends = []Reference{{Name: "team.txt", Line: 2, Col: 15}}
+	wantRemove = []Reference{{Name: "go", Line: 3, Col: 15}, {Name: "tikam", Line: 3, Col: 21}}
+	if !slices.Equal(f.Extends, wantExtends) || !slices.Equal(f.Remove, wantRemove) {
+		t.Errorf("ParseFile(json) = extends %+v, remove %+v; want %+v, %+v", f.Extends, f.Remove, wantExtends, wantRemove)
+	}
+
+	for _, bad := range []string{"@extends\n", "@extends a b\n", "@include x\n", "extends:\n"} {
+		if _, diags := ParseFile(bad); len(diags) != 1 || diags[0].Code != lexer.CodeDictSyntax {
+			t.Errorf("ParseFile(%q) = %+v, want one %s", bad, diags, lexer.CodeDictSyntax)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package dictionaries
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// Check parses dictionary source and validates it, returning every
+// problem found.
+func Check(src string) []lexer.Diagnostic {
+	entries, diags := ParseEntries(src)
+	diags = append(diags, Validate(entries)...)
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	return diags
+}
+
+// CheckFile checks the dictionary file at path. Besides the problems Check
+// finds, it reports dictionaries it extends that cannot be loaded, and
+// removals of words that the dictionaries it extends do not define. It
+// returns the source of the file for showing the problems.
+func CheckFile(path string) (string, []lexer.Diagnostic, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	src := string(data)
+	f, diags := ParseFile(src)
+	diags = append(diags, Validate(f.Entries)...)
+
+	var bases []*Dictionary
+	for _, ext := range f.Extends {
+		if ext.Name == DefaultName {
+			bases = append(bases, NewDefaultDictionary())
+			continue
+		}
+		base, err := Load(resolve(path, ext.Name))
+		if err != nil {
+			diags = append(diags, lexer.Diagnostic{
+				Message: fmt.Sprintf("cannot extend %q: %v", ext.Name, err),
+				Line:    ext.Line,
+				Col:     ext.Col,
+				Length:  utf8.RuneCountInString(ext.Name),
+				Code:    lexer.CodeDictLayer,
+			})
+			continue
+		}
+		bases = append(bases, base)
+	}
+	// Without @extends, removals apply to whatever --dictionary files come
+	// first, which cannot be known here.
+	if len(f.Extends) > 0 && len(bases) == len(f.Extends) {
+		for _, r := range f.Remove {
+			found := false
+			for _, base := range bases {
+				if _, ok := base.Lookup(r.Name); ok {
+					found = true
+				}
+			}
+			if !found {
+				diags = append(diags, lexer.Diagnostic{
+					Message:  fmt.Sprintf("cannot remove `%s`: the dictionaries this one extends do not define it", r.Name),
+					Line:     r.Line,
+					Col:      r.Col,
+					Length:   utf8.RuneCountInString(r.Name),
+					Severity: lexer.SeverityWarning,
+					Code:     lexer.CodeDictLayer,
+				})
+			}
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	return src, diags, nil
+}
+
+// goKeywords are the keywords of Go.
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true,
+	"default": true, "defer": true, "else": true, "fallthrough": true, "for": true,
+	"func": true, "go": true, "goto": true, "if": true, "import": true,
+	"interface": true, "map": true, "package": true, "range": true, "return": true,
+	"select": true, "struct": true, "switch": true, "type": true, "var": true,
+}
+
+// goTypes are Go's predeclared types.
+var goTypes = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true, "complex64": true,
+	"complex128": true, "error": true, "float32": true, "float64": true, "int": true,
+	"int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+	"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true,
+	"uint64": true, "uintptr": true,
+}
+
+// goPredeclared are the identifiers Go declares in the universe block.
+var goPredeclared = map[string]bool{
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+func init() {
+	for t := range goTypes {
+		goPredeclared[t] = true
+	}
+}
+
+// operators are the operators a word can stand for. The parser reads a
+// word between two operands as a binary operator; as a prefix it only
+// understands *, <- and !.
+var operators = map[string]bool{
+	"||": true, "&&": true, "==": true, "!=": true, "<": true, "<=": true,
+	">": true, ">=": true, "+": true, "-": true, "*": true, "/": true,
+	"%": true, "<-": true, "!": true,
+}
+
+// validCategories are the categories an entry may have; "" is uncategorised.
+var validCategories = map[string]bool{
+	"": true, CategoryKeyword: true, CategoryType: true, CategoryBuiltin: true, CategoryOperator: true,
+}
+
+// prefixOperators are Go's unary operators that cannot be spelled as words.
+var prefixOperators = map[string]bool{"^": true, "&": true}
+
+// Validate reports problems with entries:
+//
+//   - a word defined twice, which is an error if the definitions differ
+//     since the last one silently wins;
+//   - a word the lexer would not read as a single identifier;
+//   - a word that hides a Go keyword or predeclared identifier, which can
+//     then no longer be written directly;
+//   - Go the parser cannot handle as a word, such as punctuation or the
+//     prefix operator &;
+//   - a word that is also the Go of another word, as with go and chiong in
+//     the default dictionary, and several words standing for the same Go
+//     with none marked canonical, where fmt has to pick one;
+//   - an unknown category, a category that does not fit the Go, and two
+//     canonical words for the same Go.
+func Validate(entries []Entry) []lexer.Diagnostic {
+	var diags []lexer.Diagnostic
+	first := make(map[string]Entry)      // word -> its first entry
+	byTarget := make(map[string][]Entry) // target -> entries standing for it
+	for _, e := range entries {
+		wordSpan := func(d lexer.Diagnostic) lexer.Diagnostic {
+			d.Line, d.Col, d.Length = e.Line, e.Col, utf8.RuneCountInString(e.Word)
+			return d
+		}
+
+		if prev, ok := first[e.Word]; ok {
+			d := wordSpan(lexer.Diagnostic{Code: lexer.CodeDictDuplicate})
+			if prev.Target == e.Target {
+				d.Severity = lexer.SeverityWarning
+				d.Message = fmt.Sprintf("duplicate entry for `%s`", e.Word)
+			} else {
+				d.Message = fmt.Sprintf("`%s` is defined twice, as `%s` and `%s`", e.Word, prev.Target, e.Target)
+				d.Notes = []string{fmt.Sprintf("the last definition wins, so `%s` means `%s`", e.Word, e.Target)}
+			}
+			if prev.Line > 0 {
+				d.Labels = []lexer.Label{{Line: prev.Line, Col: prev.Col, Length: utf8.RuneCountInString(prev.Word), Message: "first defined here"}}
+			}
+			diags = append(diags, d)
+			continue
+		}
+		first[e.Word] = e
+		byTarget[e.Target] = append(byTarget[e.Target], e)
+
+		if !isWord(e.Word) {
+			d := wordSpan(lexer.Diagnostic{
+				Message: fmt.Sprintf("`%s` is not a valid word", e.Word),
+				Code:    lexer.CodeDictInvalidWord,
+				Notes: []string{
+					"words must start with a letter or _ and contain only letters, digits and _",
+					"a phrase keyword is several such words separated by spaces, like `see how`",
+				},
+			})
+			diags = append(diags, d)
+			continue
+		}
+
+		if e.Word != e.Target && (goKeywords[e.Word] || goPredeclared[e.Word]) {
+			kind := "predeclared identifier"
+			if goKeywords[e.Word] {
+				kind = "keyword"
+			}
+			diags = append(diags, wordSpan(lexer.Diagnostic{
+				Message:  fmt.Sprintf("`%s` hides the Go %s `%s`", e.Word, kind, e.Word),
+				Severity: lexer.SeverityWarning,
+				Code:     lexer.CodeDictShadowsGo,
+				Notes:    []string{fmt.Sprintf("`%s` now means `%s`, so Go's `%s` cannot be written directly", e.Word, e.Target, e.Word)},
+			}))
+		}
+
+		switch inferred := InferCategory(e.Target); {
+		case !validCategories[e.Category]:
+			diags = append(diags, wordSpan(lexer.Diagnostic{
+				Message: fmt.Sprintf("unknown category %q for `%s`", e.Category, e.Word),
+				Code:    lexer.CodeDictMetadata,
+				Notes:   []string{"categories are keyword, type, builtin and operator"},
+			}))
+		case e.Category != "" && inferred != "" && e.Category != inferred:
+			diags = append(diags, wordSpan(lexer.Diagnostic{
+				Message:  fmt.Sprintf("`%s` is marked as a %s, but `%s` is a %s", e.Word, e.Category, e.Target, inferred),
+				Severity: lexer.SeverityWarning,
+				Code:     lexer.CodeDictMetadata,
+			}))
+		}
+
+		if reason := unmappable(e.Target); reason != "" {
+			d := lexer.Diagnostic{
+				Message: fmt.Sprintf("`%s` cannot stand for `%s`", e.Word, e.Target),
+				Line:    e.GoLine,
+				Col:     e.GoCol,
+				Length:  utf8.RuneCountInString(e.Target),
+				Code:    lexer.CodeDictBadTarget,
+				Notes:   []string{reason},
+			}
+			diags = append(diags, d)
+		}
+	}
+
+	for _, e := range entries {
+		if first[e.Word] != e {
+			continue
+		}
+		for _, other := range byTarget[e.Word] {
+			if other.Word == e.Word {
+				continue
+			}
+			d := lexer.Diagnostic{
+				Message:  fmt.Sprintf("`%s` means `%s`, but `%s` stands for `%s`", e.Word, e.Target, other.Word, e.Word),
+				Line:     e.Line,
+				Col:      e.Col,
+				Length:   utf8.RuneCountInString(e.Word),
+				Severity: lexer.SeverityWarning,
+				Code:     lexer.CodeDictAmbiguous,
+				Notes:    []string{fmt.Sprintf("readers cannot tell whether `%s` is the Singlish word or the Go that `%s` translates to", e.Word, other.Word)},
+			}
+			if other.Line > 0 {
+				d.Labels = []lexer.Label{{Line: other.GoLine, Col: other.GoCol, Length: utf8.RuneCountInString(other.Target), Message: fmt.Sprintf("`%s` translates to `%s` here", other.Word, e.Word)}}
+			}
+			diags = append(diags, d)
+		}
+	}
+
+	targets := make([]string, 0, len(byTarget))
+	for target := range byTarget {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	for _, target := range targets {
+		aliases := byTarget[target]
+		if len(aliases) < 2 {
+			continue
+		}
+		words := make([]string, len(aliases))
+		var canonical []Entry
+		for i, a := range aliases {
+			words[i] = "`" + a.Word + "`"
+			if a.Canonical {
+				canonical = append(canonical, a)
+			}
+		}
+		if len(canonical) > 1 {
+			diags = append(diags, lexer.Diagnostic{
+				Message: fmt.Sprintf("`%s` and `%s` are both canonical for `%s`", canonical[0].Word, canonical[1].Word, target),
+				Line:    canonical[1].Line,
+				Col:     canonical[1].Col,
+				Length:  utf8.RuneCountInString(canonical[1].Word),
+				Code:    lexer.CodeDictMetadata,
+				Notes:   []string{"mark only one word for each Go keyword as canonical"},
+			})
+		}
+		if len(canonical) > 0 {
+			continue // fmt knows which word to write
+		}
+		all := "all"
+		if len(words) == 2 {
+			all = "both"
+		}
+		diags = append(diags, lexer.Diagnostic{
+			Message:  fmt.Sprintf("%s and %s %s stand for `%s`", strings.Join(words[:len(words)-1], ", "), words[len(words)-1], all, target),
+			Line:     aliases[1].Line,
+			Col:      aliases[1].Col,
+			Length:   utf8.RuneCountInString(aliases[1].Word),
+			Severity: lexer.SeverityNote,
+			Code:     lexer.CodeDictAmbiguous,
+			Notes:    []string{fmt.Sprintf("fmt writes `%s` for `%s`, the first of them; mark one as canonical to choose", aliases[0].Word, target)},
+		})
+	}
+	return diags
+}
+
+// unmappable explains why a word cannot stand for target, or returns "" if
+// it can. Targets are Go keywords, operators, identifiers and qualified
+// identifiers such as fmt.Println or math/rand.Intn.
+func unmappable(target string) string {
+	switch {
+	case goKeywords[target] || operators[target]:
+		return ""
+	case prefixOperators[target]:
+		return fmt.Sprintf("the parser reads words only as binary operators or as the prefix operators *, <- and !, so `%s` cannot be used", target)
+	}
+	if strings.Contains(target, ".") {
+		if _, _, ok := SplitQualified(target); ok {
+			return ""
+		}
+		return "qualified names look like `fmt.Println`, or `math/rand.Intn` for packages whose path is longer than their name"
+	}
+	if isIdentifier(target) {
+		return ""
+	}
+	return "a word can only stand for a Go keyword, a binary operator, an identifier or a qualified name such as `fmt.Println`"
+}
+
+// isWord reports whether s can be a keyword: an identifier, or a phrase of
+// identifiers separated by single spaces.
+func isWord(s string) bool {
+	for _, part := range strings.Split(s, " ") {
+		if !isIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentifier reports whether s is read by the lexer as one identifier.
+func isIdentifier(s string) bool {
+	if s == "" || s == "_" {
+		return false
+	}
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
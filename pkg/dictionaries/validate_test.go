@@ -0,0 +1,133 @@
+package dictionaries
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+
+	"os"
+
+	"path/filepath"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		code     string
+		severity lexer.Severity
+		line     int
+		col      int
+		message  string
+	}{
+		{"conflicting duplicate", "nasi: if\nnasi: for\n", lexer.CodeDictDuplicate, lexer.SeverityError, 2, 1, "`nasi` is defined twice"},
+		{"repeated duplicate", "nasi: if\nnasi: if\n", lexer.CodeDictDuplicate, lexer.SeverityWarning, 2, 1, "duplicate entry"},
+		{"invalid word", "my-word: func\n", lexer.CodeDictInvalidWord, lexer.SeverityError, 1, 1, "not a valid word"},
+		{"leading digit", "2nd: func\n", lexer.CodeDictInvalidWord, lexer.SeverityError, 1, 1, "not a valid word"},
+		{"invalid phrase", "see how!: switch\n", lexer.CodeDictInvalidWord, lexer.SeverityError, 1, 1, "not a valid word"},
+		{"shadows keyword", "for: if\n", lexer.CodeDictShadowsGo, lexer.SeverityWarning, 1, 1, "Go keyword `for`"},
+		{"shadows builtin", "len: cap\n", lexer.CodeDictShadowsGo, lexer.SeverityWarning, 1, 1, "predeclared identifier `len`"},
+		{"punctuation", "open: {\n", lexer.CodeDictBadTarget, lexer.SeverityError, 1, 7, "cannot stand for `{`"},
+		{"address of", "alamat: &\n", lexer.CodeDictBadTarget, lexer.SeverityError, 1, 9, "cannot stand for `&`"},
+		{"call", "gong: fmt.Println()\n", lexer.CodeDictBadTarget, lexer.SeverityError, 1, 7, "cannot stand for"},
+		{"bad import path", "tikam: math//rand.Intn\n", lexer.CodeDictBadTarget, lexer.SeverityError, 1, 8, "cannot stand for"},
+		{"chain", "go: continue\nchiong: go\n", lexer.CodeDictAmbiguous, lexer.SeverityWarning, 1, 1, "but `chiong` stands for `go`"},
+		{"aliases", "pass: <-\ncatch: <-\n", lexer.CodeDictAmbiguous, lexer.SeverityNote, 2, 1, "`pass` and `catch` both stand for `<-`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found *lexer.Diagnostic
+			diags := Check(tt.src)
+			for i, d := range diags {
+				if d.Code == tt.code {
+					found = &diags[i]
+				}
+			}
+			if found == nil {
+				t.Fatalf("Check() = %+v, want a %s diagnostic", diags, tt.code)
+			}
+			if found.Severity != tt.severity || found.Line != tt.line || found.Col != tt.col {
+				t.Errorf("got %s at %d:%d, want %s at %d:%d", found.Severity, found.Line, found.Col, tt.severity, tt.line, tt.col)
+			}
+			if !strings.Contains(found.Message, tt.message) {
+				t.Errorf("message %q does not contain %q", found.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestCheckClean(t *testing.T) {
+	src := "kampung: package\naction: func\ngong: fmt.Println\nsomemore: &&\ndun: !\nki: *\nboss: main\ncan or not: bool\ntikam: math/rand.Intn\n"
+	if diags := Check(src); len(diags) != 0 {
+		t.Errorf("Check() = %+v, want no problems", diags)
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	for _, d := range Validate(DefaultEntries()) {
+		if d.Severity == lexer.SeverityError {
+			t.Errorf("built-in dictionary: %s", d.Message)
+		}
+	}
+}
+
+func TestCheckMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		severity lexer.Severity
+		message  string
+	}{
+		{"unknown category", `{"entries": [{"word": "nasi", "go": "if", "category": "makan"}]}`, lexer.SeverityError, "unknown category"},
+		{"wrong category", `{"entries": [{"word": "nasi", "go": "if", "category": "type"}]}`, lexer.SeverityWarning, "marked as a type"},
+		{"two canonical", `{"entries": [
+			{"word": "pass", "go": "<-", "canonical": true},
+			{"word": "catch", "go": "<-", "canonical": true}]}`, lexer.SeverityError, "both canonical"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := Check(tt.src)
+			if len(diags) != 1 || diags[0].Code != lexer.CodeDictMetadata {
+				t.Fatalf("Check() = %+v, want one %s", diags, lexer.CodeDictMetadata)
+			}
+			if diags[0].Severity != tt.severity || !strings.Contains(diags[0].Message, tt.message) {
+				t.Errorf("got %s %q, want %s containing %q", diags[0].Severity, diags[0].Message, tt.severity, tt.message)
+			}
+		})
+	}
+
+	// A canonical word settles which alias fmt writes, so there is no note.
+	src := `{"entries": [{"word": "pass", "go": "<-", "canonical": true}, {"word": "catch", "go": "<-"}]}`
+	if diags := Check(src); len(diags) != 0 {
+		t.Errorf("Check() = %+v, want no problems", diags)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "team.txt")
+	src := "@extends default\n@extends missing.txt\n@remove go\nlepak: continue\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, diags, err := CheckFile(path)
+	if err != nil {
+		t.Fatalf("CheckFile failed: %v", err)
+	}
+	if got != src {
+		t.Errorf("CheckFile returned source %q", got)
+	}
+	if len(diags) != 1 || diags[0].Code != lexer.CodeDictLayer || diags[0].Line != 2 {
+		t.Fatalf("CheckFile() = %+v, want one %s on line 2", diags, lexer.CodeDictLayer)
+	}
+
+	src = "@extends default\n@remove go nope\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, diags, _ = CheckFile(path)
+	if len(diags) != 1 || diags[0].Severity != lexer.SeverityWarning || !strings.Contains(diags[0].Message, "`nope`") {
+		t.Errorf("CheckFile() = %+v, want a warning about removing nope", diags)
+	}
+}
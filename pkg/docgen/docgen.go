@@ -0,0 +1,432 @@
+// Package docgen documents Singlish packages, as go doc does for Go. It
+// collects the exported actions, patterns, got variables and confirm
+// constants of a package with the comments just above them, shows each
+// declaration as it is written in Singlish and, optionally, as the Go it
+// becomes, and renders the result as Markdown or a static HTML site.
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// File is one parsed source file of a package.
+type File struct {
+	Path    string
+	Source  string
+	Program *ast.Program
+}
+
+// Package is the documentation of a package.
+type Package struct {
+	Name   string
+	Path   string // directory of the package, as given to Collect
+	Doc    string // the comment above kampung, without comment markers
+	Consts []*Decl
+	Vars   []*Decl
+	Funcs  []*Decl
+	Types  []*Type
+}
+
+// Decl is one documented declaration.
+type Decl struct {
+	Name     string // Recv.Name for methods
+	Singlish string // the declaration as written, without the body of an action
+	Go       string // the Go it becomes; empty if the Go could not be generated
+	Doc      string
+	File     string
+	Line     int
+}
+
+// Type is a documented pattern and its methods.
+type Type struct {
+	Decl
+	Methods []*Decl
+}
+
+// Collect documents the package made of files, which live in the
+// directory path. Only exported names, those starting with a capital
+// letter, are documented, as in Go.
+func Collect(path string, files []File, dict *dictionaries.Dictionary) (*Package, error) {
+	pkg := &Package{Path: path}
+	types := make(map[string]*Type)
+	var methods []*Decl
+	recvOf := make(map[*Decl]string)
+
+	programs := make([]*ast.Program, len(files))
+	for i, f := range files {
+		programs[i] = f.Program
+	}
+	for _, f := range files {
+		c, err := newCollector(f, programs, dict)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range f.Program.Statements {
+			switch s := s.(type) {
+			case *ast.PackageStatement:
+				if s.Name == nil {
+					continue
+				}
+				if pkg.Name != "" && pkg.Name != s.Name.Value {
+					return nil, fmt.Errorf("%s: package %s, but other files are in package %s", f.Path, s.Name.Value, pkg.Name)
+				}
+				pkg.Name = s.Name.Value
+				if pkg.Doc == "" {
+					pkg.Doc = c.doc(s.Token.Line)
+				}
+			case *ast.LetStatement:
+				for _, name := range s.Names {
+					if name == nil || !token.IsExported(name.Value) {
+						continue
+					}
+					d := c.decl(name.Value, s.Token, lexer.Token{}, "value "+name.Value)
+					if c.isConst(s) {
+						pkg.Consts = append(pkg.Consts, d)
+					} else {
+						pkg.Vars = append(pkg.Vars, d)
+					}
+				}
+			case *ast.TypeStatement:
+				if s.Name == nil || !token.IsExported(s.Name.Value) {
+					continue
+				}
+				t := &Type{Decl: *c.decl(s.Name.Value, s.Token, lexer.Token{}, "type "+s.Name.Value)}
+				if st, ok := s.Value.(*ast.StructLiteral); ok {
+					t.Singlish = hideFields(t.Singlish, s.Token.Line, st)
+				}
+				types[t.Name] = t
+				pkg.Types = append(pkg.Types, t)
+			case *ast.FunctionStatement:
+				if s.Name == nil || s.Body == nil || !token.IsExported(s.Name.Value) {
+					continue
+				}
+				if s.Receiver == nil {
+					pkg.Funcs = append(pkg.Funcs, c.decl(s.Name.Value, s.Token, s.Body.Token, "func "+s.Name.Value))
+					continue
+				}
+				recv := strings.TrimLeft(s.Receiver.Type.String(), "*")
+				d := c.decl(recv+"."+s.Name.Value, s.Token, s.Body.Token, "method "+recv+"."+s.Name.Value)
+				methods = append(methods, d)
+				recvOf[d] = recv
+			}
+		}
+	}
+
+	// Methods of unexported patterns are left out with their pattern.
+	for _, m := range methods {
+		if t, ok := types[recvOf[m]]; ok {
+			t.Methods = append(t.Methods, m)
+		}
+	}
+	byName := func(list []*Decl) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	}
+	byName(pkg.Consts)
+	byName(pkg.Vars)
+	byName(pkg.Funcs)
+	sort.SliceStable(pkg.Types, func(i, j int) bool { return pkg.Types[i].Name < pkg.Types[j].Name })
+	for _, t := range pkg.Types {
+		byName(t.Methods)
+	}
+	return pkg, nil
+}
+
+// collector finds the text, comments and Go of the declarations in a file.
+type collector struct {
+	file     File
+	dict     *dictionaries.Dictionary
+	lines    []string
+	tokens   []lexer.Token
+	comments map[int]lexer.Token // line comments and block comments alone on a line, by last line
+	goDecls  map[string]string   // Go declarations by kind and name, as "func Open"
+}
+
+func newCollector(f File, pkg []*ast.Program, dict *dictionaries.Dictionary) (*collector, error) {
+	keywords := map[string]struct{}{"ki": {}}
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(f.Source, keywords)
+	if len(diags) > 0 {
+		return nil, fmt.Errorf("%s:%d:%d: %s", f.Path, diags[0].Line, diags[0].Col, diags[0].Message)
+	}
+	c := &collector{
+		file:     f,
+		dict:     dict,
+		lines:    strings.Split(strings.ReplaceAll(f.Source, "\r\n", "\n"), "\n"),
+		tokens:   tokens,
+		comments: make(map[int]lexer.Token),
+		goDecls:  goDecls(f.Program, pkg, dict),
+	}
+	code := make(map[int]bool) // lines with something other than comments
+	for _, tok := range tokens {
+		if tok.Type != lexer.TokenComment {
+			code[tok.Line] = true
+		}
+	}
+	for _, tok := range tokens {
+		if tok.Type == lexer.TokenComment && !code[tok.Line] {
+			c.comments[tok.Line+strings.Count(tok.Value, "\n")] = tok
+		}
+	}
+	return c, nil
+}
+
+// decl documents the declaration starting at start. For an action, body is
+// the brace its body starts at; the declaration ends before it.
+func (c *collector) decl(name string, start, body lexer.Token, goKey string) *Decl {
+	return &Decl{
+		Name:     name,
+		Singlish: c.text(start, body),
+		Go:       c.goDecls[goKey],
+		Doc:      c.doc(start.Line),
+		File:     c.file.Path,
+		Line:     start.Line,
+	}
+}
+
+// doc returns the comments on the lines just above line, without their
+// markers. Directives such as //singlish:ignore are left out.
+func (c *collector) doc(line int) string {
+	var group []string
+	for l := line - 1; ; {
+		tok, ok := c.comments[l]
+		if !ok {
+			break
+		}
+		group = append([]string{tok.Value}, group...)
+		l = tok.Line - 1 // the comment above must end on the line before
+	}
+	var out []string
+	for _, comment := range group {
+		if text, ok := strings.CutPrefix(comment, "//"); ok {
+			if strings.HasPrefix(text, "singlish:") || strings.HasPrefix(text, "go:") {
+				continue
+			}
+			out = append(out, strings.TrimPrefix(text, " "))
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+		out = append(out, strings.Split(strings.Trim(text, "\n"), "\n")...)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// text returns the source of the declaration starting at start: up to body
+// for an action, and otherwise to the end of the line on which its last
+// bracket closes.
+func (c *collector) text(start, body lexer.Token) string {
+	endLine, endCol := 0, 0
+	if body.Line > 0 {
+		endLine, endCol = body.Line, body.Col
+	} else {
+		depth, last := 0, start.Line
+		for _, tok := range c.tokens {
+			if tok.Line < start.Line || (tok.Line == start.Line && tok.Col < start.Col) || tok.Type == lexer.TokenComment {
+				continue
+			}
+			if tok.Line > last && depth == 0 {
+				break
+			}
+			last = tok.Line
+			if tok.Type == lexer.TokenPunctuation {
+				switch tok.Value {
+				case "(", "[", "{":
+					depth++
+				case ")", "]", "}":
+					depth--
+				}
+			}
+		}
+		endLine = last
+	}
+
+	var b strings.Builder
+	for l := start.Line; l <= endLine && l <= len(c.lines); l++ {
+		text := c.lines[l-1]
+		if l == endLine && endCol > 0 {
+			text = text[:byteOffset(text, endCol)]
+		}
+		if l == start.Line {
+			text = text[byteOffset(text, start.Col):]
+		} else {
+			b.WriteString("\n")
+		}
+		b.WriteString(text)
+	}
+	return strings.TrimRight(b.String(), " \t")
+}
+
+// unexportedNote stands in for the fields of a pattern that are left out,
+// as in go doc.
+const unexportedNote = "// contains filtered or unexported fields"
+
+// hideFields removes the unexported fields of st, with the comments just
+// above them, from text, the Singlish of a pattern starting on line start,
+// and notes that there were some before the closing brace. Fields that
+// share a line with the braces are left alone.
+func hideFields(text string, start int, st *ast.StructLiteral) string {
+	lines := strings.Split(text, "\n")
+	last := start + len(lines) - 1 // the line of the closing brace
+	line := func(l int) string { return strings.TrimSpace(lines[l-start]) }
+	hidden := make(map[int]bool)
+	for i, f := range st.Fields {
+		if f == nil || f.Name == nil || token.IsExported(f.Name.Value) {
+			continue
+		}
+		from, to := f.Name.Token.Line, last-1
+		if i+1 < len(st.Fields) && st.Fields[i+1] != nil && st.Fields[i+1].Name != nil {
+			to = st.Fields[i+1].Name.Token.Line - 1
+		}
+		for to > from && (line(to) == "" || strings.HasPrefix(line(to), "//")) {
+			to--
+		}
+		if from <= start || to >= last {
+			continue
+		}
+		for from-1 > start && strings.HasPrefix(line(from-1), "//") {
+			from--
+		}
+		for l := from; l <= to; l++ {
+			hidden[l] = true
+		}
+	}
+	if len(hidden) == 0 {
+		return text
+	}
+	indent := "\t"
+	if len(lines) > 2 {
+		indent = lines[1][:len(lines[1])-len(strings.TrimLeft(lines[1], " \t"))]
+	}
+	var kept []string
+	for i, l := range lines {
+		if i == len(lines)-1 {
+			kept = append(kept, indent+unexportedNote)
+		}
+		if !hidden[start+i] {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// hideGoFields removes the unexported fields of st and reports whether
+// there were any.
+func hideGoFields(st *goast.StructType) bool {
+	hid := false
+	var kept []*goast.Field
+	for _, f := range st.Fields.List {
+		var names []*goast.Ident
+		for _, n := range f.Names {
+			if n.IsExported() {
+				names = append(names, n)
+			}
+		}
+		if len(names) == 0 && len(f.Names) > 0 {
+			hid = true
+			continue
+		}
+		hid = hid || len(names) < len(f.Names)
+		f.Names = names
+		kept = append(kept, f)
+	}
+	st.Fields.List = kept
+	return hid
+}
+
+// byteOffset returns the offset in s of the 1-based rune column col.
+func byteOffset(s string, col int) int {
+	offset := 0
+	for i := 1; i < col && offset < len(s); i++ {
+		_, size := utf8.DecodeRuneInString(s[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// isConst reports whether ls is written with the word for const.
+func (c *collector) isConst(ls *ast.LetStatement) bool {
+	if ls.Token.Value == "const" {
+		return true
+	}
+	target, ok := c.dict.Lookup(ls.Token.Value)
+	return ok && target == "const"
+}
+
+// goDecls returns the top-level Go declarations program, one of the files
+// in pkg, translates to, printed without bodies, by kind and name: "func
+// Open", "method Kedai.Close", "type Kedai" and "value MaxOrders". It
+// returns nothing if the Go cannot be generated.
+func goDecls(program *ast.Program, pkg []*ast.Program, dict *dictionaries.Dictionary) map[string]string {
+	decls := make(map[string]string)
+	code, _, err := codegen.GenerateInPackage(program, pkg, dict)
+	if err != nil {
+		return decls
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.SkipObjectResolution)
+	if err != nil {
+		return decls
+	}
+	show := func(node goast.Node) string {
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, fset, node); err != nil {
+			return ""
+		}
+		return buf.String()
+	}
+	for _, d := range file.Decls {
+		switch d := d.(type) {
+		case *goast.FuncDecl:
+			sig := *d
+			sig.Body, sig.Doc = nil, nil
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				decls["func "+d.Name.Name] = show(&sig)
+				continue
+			}
+			recv := d.Recv.List[0].Type
+			if star, ok := recv.(*goast.StarExpr); ok {
+				recv = star.X
+			}
+			if id, ok := recv.(*goast.Ident); ok {
+				decls["method "+id.Name+"."+d.Name.Name] = show(&sig)
+			}
+		case *goast.GenDecl:
+			for _, spec := range d.Specs {
+				one := &goast.GenDecl{Tok: d.Tok, Specs: []goast.Spec{spec}}
+				switch s := spec.(type) {
+				case *goast.TypeSpec:
+					st, ok := s.Type.(*goast.StructType)
+					hid := ok && hideGoFields(st)
+					text := show(one)
+					if hid {
+						// Hidden fields leave their lines empty, and the
+						// closing brace is printed on a line of its own.
+						text = strings.ReplaceAll(text, "\n\n", "\n")
+						i := strings.LastIndex(text, "}")
+						text = text[:i] + "\t" + unexportedNote + "\n" + text[i:]
+					}
+					decls["type "+s.Name.Name] = text
+				case *goast.ValueSpec:
+					for _, n := range s.Names {
+						decls["value "+n.Name] = show(one)
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
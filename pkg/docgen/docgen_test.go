@@ -0,0 +1,162 @@
+package docgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+const kedai = `// Package kedai runs a kopi shop.
+kampung kedai
+
+// MaxOrders is how many orders fit.
+confirm MaxOrders = 10
+
+//singlish:ignore unused
+// Greeting is said first.
+got Greeting tar = "hello"
+
+got secret = 1
+
+// Kedai is a shop.
+pattern Kedai barang {
+	got Name tar
+	// stock is counted in cups.
+	got stock nombor
+	got Owner tar
+}
+
+// Till holds the money.
+pattern Till barang {
+	got cash nombor
+}
+
+// Open opens a shop.
+action Open(name tar) (ki Kedai, salah) {
+	balek &Kedai{Name: name}, kosong
+}
+
+// Close closes it.
+action (k ki Kedai) Close() salah {
+	balek kosong
+}
+
+action (k ki Kedai) restock() {}
+
+pattern stall barang {
+	got Name tar
+}
+
+action (s stall) Show() {}
+
+action helper() {}
+`
+
+// collect documents the package made of sources with the default
+// dictionary.
+func collect(t *testing.T, sources ...string) *Package {
+	t.Helper()
+	dict := dictionaries.NewDefaultDictionary()
+	var files []File
+	for i, src := range sources {
+		program, err := transpiler.ParseFile(src, dict)
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+		files = append(files, File{Path: string(rune('a'+i)) + ".singlish", Source: src, Program: program})
+	}
+	pkg, err := Collect("kedai", files, dict)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	return pkg
+}
+
+func names(decls []*Decl) string {
+	var out []string
+	for _, d := range decls {
+		out = append(out, d.Name)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestCollect(t *testing.T) {
+	pkg := collect(t, kedai)
+	if pkg.Name != "kedai" || pkg.Doc != "Package kedai runs a kopi shop." {
+		t.Fatalf("package = %q, doc %q", pkg.Name, pkg.Doc)
+	}
+	if got := names(pkg.Consts); got != "MaxOrders" {
+		t.Errorf("Consts = %q", got)
+	}
+	if got := names(pkg.Vars); got != "Greeting" {
+		t.Errorf("Vars = %q", got)
+	}
+	if got := names(pkg.Funcs); got != "Open" {
+		t.Errorf("Funcs = %q", got)
+	}
+	if len(pkg.Types) != 2 || pkg.Types[0].Name != "Kedai" || pkg.Types[1].Name != "Till" {
+		t.Fatalf("Types = %v", pkg.Types)
+	}
+	if got := names(pkg.Types[0].Methods); got != "Kedai.Close" {
+		t.Errorf("Kedai methods = %q", got)
+	}
+
+	tests := []struct {
+		decl             *Decl
+		singlish, goDecl string
+		doc              string
+		line             int
+	}{
+		{pkg.Consts[0], "confirm MaxOrders = 10", "const MaxOrders = 10", "MaxOrders is how many orders fit.", 5},
+		{pkg.Vars[0], `got Greeting tar = "hello"`, `var Greeting string = "hello"`, "Greeting is said first.", 9},
+		{pkg.Funcs[0], "action Open(name tar) (ki Kedai, salah)", "func Open(name string) (*Kedai, error)", "Open opens a shop.", 27},
+		{&pkg.Types[0].Decl, "pattern Kedai barang {\n\tgot Name tar\n\tgot Owner tar\n\t// contains filtered or unexported fields\n}", "type Kedai struct {\n\tName\tstring\n\tOwner\tstring\n\t// contains filtered or unexported fields\n}", "Kedai is a shop.", 14},
+		{&pkg.Types[1].Decl, "pattern Till barang {\n\t// contains filtered or unexported fields\n}", "type Till struct {\n\t// contains filtered or unexported fields\n}", "Till holds the money.", 22},
+		{pkg.Types[0].Methods[0], "action (k ki Kedai) Close() salah", "func (k *Kedai) Close() error", "Close closes it.", 32},
+	}
+	for _, tt := range tests {
+		d := tt.decl
+		if d.Singlish != tt.singlish {
+			t.Errorf("%s: Singlish = %q, want %q", d.Name, d.Singlish, tt.singlish)
+		}
+		if d.Go != tt.goDecl {
+			t.Errorf("%s: Go = %q, want %q", d.Name, d.Go, tt.goDecl)
+		}
+		if d.Doc != tt.doc {
+			t.Errorf("%s: Doc = %q, want %q", d.Name, d.Doc, tt.doc)
+		}
+		if d.File != "a.singlish" || d.Line != tt.line {
+			t.Errorf("%s: at %s:%d, want a.singlish:%d", d.Name, d.File, d.Line, tt.line)
+		}
+	}
+}
+
+func TestCollectFiles(t *testing.T) {
+	pkg := collect(t, kedai, "kampung kedai\n\n/* Zero is\nnothing. */\nconfirm Zero = 0\n\nconfirm One = 1 // one\n")
+	if got := names(pkg.Consts); got != "MaxOrders One Zero" {
+		t.Fatalf("Consts = %q", got)
+	}
+	if pkg.Consts[1].Doc != "" {
+		t.Errorf("One: Doc = %q, want none", pkg.Consts[1].Doc)
+	}
+	if pkg.Consts[2].Doc != "Zero is\nnothing." || pkg.Consts[2].File != "b.singlish" {
+		t.Errorf("Zero: Doc = %q in %s", pkg.Consts[2].Doc, pkg.Consts[2].File)
+	}
+}
+
+func TestCollectPackageMismatch(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	var files []File
+	for _, src := range []string{"kampung kedai\n", "kampung pasar\n"} {
+		program, err := transpiler.ParseFile(src, dict)
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+		files = append(files, File{Path: "x.singlish", Source: src, Program: program})
+	}
+	if _, err := Collect("kedai", files, dict); err == nil || !strings.Contains(err.Error(), "package pasar") {
+		t.Fatalf("Collect() error = %v, want one naming package pasar", err)
+	}
+}
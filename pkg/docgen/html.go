@@ -0,0 +1,200 @@
+package docgen
+
+import (
+	"fmt"
+	"go/doc/comment"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HTML writes the documentation of pkgs as a static site in dir: an
+// index.html listing the packages and a page for each one. With opts.Go the
+// Singlish and Go of each declaration are shown side by side.
+func HTML(dir string, pkgs []*Package, opts Options) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	pages := pageNames(pkgs)
+
+	var index []indexEntry
+	for i, pkg := range pkgs {
+		index = append(index, indexEntry{Name: pkg.Name, Path: pkg.Path, Page: pages[i], Synopsis: synopsis(pkg.Doc)})
+		page := packagePage{Package: pkg, Doc: docHTML(pkg.Doc, 2)}
+		for _, s := range []struct {
+			title string
+			decls []*Decl
+		}{{"Constants", pkg.Consts}, {"Variables", pkg.Vars}, {"Functions", pkg.Funcs}} {
+			if len(s.decls) > 0 {
+				page.Sections = append(page.Sections, section{Title: s.title, Decls: htmlDecls(s.decls, opts)})
+			}
+		}
+		for _, t := range pkg.Types {
+			page.Types = append(page.Types, htmlType{Type: htmlDecls([]*Decl{&t.Decl}, opts)[0], Methods: htmlDecls(t.Methods, opts)})
+		}
+		if err := writeTemplate(filepath.Join(dir, pages[i]), pageTemplate, page); err != nil {
+			return err
+		}
+	}
+	return writeTemplate(filepath.Join(dir, "index.html"), indexTemplate, index)
+}
+
+type indexEntry struct {
+	Name, Path, Page, Synopsis string
+}
+
+type packagePage struct {
+	*Package
+	Doc      template.HTML
+	Sections []section
+	Types    []htmlType
+}
+
+type section struct {
+	Title string
+	Decls []htmlDecl
+}
+
+type htmlDecl struct {
+	*Decl
+	DocHTML template.HTML
+	ShowGo  bool // the Go is shown next to the Singlish
+}
+
+type htmlType struct {
+	Type    htmlDecl
+	Methods []htmlDecl
+}
+
+func htmlDecls(decls []*Decl, opts Options) []htmlDecl {
+	out := make([]htmlDecl, len(decls))
+	for i, d := range decls {
+		out[i] = htmlDecl{Decl: d, DocHTML: docHTML(d.Doc, 4), ShowGo: opts.Go && d.Go != ""}
+	}
+	return out
+}
+
+// docHTML renders comment text as HTML, with any headings in it at level.
+func docHTML(text string, level int) template.HTML {
+	if text == "" {
+		return ""
+	}
+	var p comment.Parser
+	printer := &comment.Printer{HeadingLevel: level}
+	return template.HTML(printer.HTML(p.Parse(text)))
+}
+
+// synopsis returns the first sentence of a comment.
+func synopsis(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if i := strings.Index(text, ". "); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
+// pageNames returns the file name of the page of each package: its
+// directory with slashes turned into dashes, or its name for the current
+// directory, made unique.
+func pageNames(pkgs []*Package) []string {
+	names := make([]string, len(pkgs))
+	used := map[string]bool{"index.html": true}
+	for i, pkg := range pkgs {
+		base := strings.Trim(strings.ReplaceAll(filepath.ToSlash(filepath.Clean(pkg.Path)), "/", "-"), ".-")
+		if base == "" {
+			base = pkg.Name
+		}
+		name := base + ".html"
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d.html", base, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+func writeTemplate(path string, t *template.Template, data any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Execute(f, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+const style = `
+body { font-family: system-ui, sans-serif; max-width: 72rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; color: #222; }
+pre { background: #f6f6f4; padding: 0.75rem; overflow-x: auto; border-radius: 4px; }
+.decl { display: grid; gap: 0.75rem; }
+.decl.side-by-side { grid-template-columns: 1fr 1fr; }
+.decl pre { margin: 0; }
+.lang { font-size: 0.75rem; color: #777; margin: 0 0 0.25rem; }
+a { color: #0b5cad; }
+`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Singlish packages</title>
+<style>` + style + `</style>
+</head>
+<body>
+<h1>Packages</h1>
+<dl>
+{{- range .}}
+<dt><a href="{{.Page}}">{{.Name}}</a> <code>{{.Path}}</code></dt>
+<dd>{{.Synopsis}}</dd>
+{{- end}}
+</dl>
+</body>
+</html>
+`))
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Package {{.Name}}</title>
+<style>` + style + `</style>
+</head>
+<body>
+<p><a href="index.html">Packages</a></p>
+<h1>Package {{.Name}}</h1>
+<p>Directory: <code>{{.Path}}</code></p>
+{{.Doc}}
+{{- define "decl"}}
+<div class="decl{{if .ShowGo}} side-by-side{{end}}">
+<div>{{if .ShowGo}}<p class="lang">Singlish</p>{{end}}<pre><code>{{.Singlish}}</code></pre></div>
+{{- if .ShowGo}}
+<div><p class="lang">Go</p><pre><code>{{.Decl.Go}}</code></pre></div>
+{{- end}}
+</div>
+{{.DocHTML}}
+{{- end}}
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+{{- range .Decls}}
+<h3 id="{{.Name}}">{{.Name}}</h3>
+{{template "decl" .}}
+{{- end}}
+{{- end}}
+{{- if .Types}}
+<h2>Types</h2>
+{{- range .Types}}
+<h3 id="{{.Type.Name}}">{{.Type.Name}}</h3>
+{{template "decl" .Type}}
+{{- range .Methods}}
+<h4 id="{{.Name}}">{{.Name}}</h4>
+{{template "decl" .}}
+{{- end}}
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,55 @@
+package docgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	pkg := collect(t, kedai)
+	for _, opts := range []Options{{}, {Go: true}} {
+		dir := t.TempDir()
+		if err := HTML(dir, []*Package{pkg}, opts); err != nil {
+			t.Fatalf("HTML failed: %v", err)
+		}
+		index, err := os.ReadFile(filepath.Join(dir, "index.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(index), `<a href="kedai.html">kedai</a>`) || !strings.Contains(string(index), "Package kedai runs a kopi shop.") {
+			t.Errorf("index.html does not link the package:\n%s", index)
+		}
+		page, err := os.ReadFile(filepath.Join(dir, "kedai.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{
+			"<h1>Package kedai</h1>",
+			`<h3 id="Open">Open</h3>`,
+			`<h4 id="Kedai.Close">Kedai.Close</h4>`,
+			`got Greeting tar = &#34;hello&#34;`,
+			"<p>Open opens a shop.",
+		}
+		if opts.Go {
+			want = append(want, `<div class="decl side-by-side">`, "<pre><code>func Open(name string) (*Kedai, error)</code></pre>")
+		}
+		for _, s := range want {
+			if !strings.Contains(string(page), s) {
+				t.Errorf("HTML(%+v) page does not contain %q", opts, s)
+			}
+		}
+		if !opts.Go && strings.Contains(string(page), `class="decl side-by-side"`) {
+			t.Errorf("HTML(%+v) page shows Go", opts)
+		}
+	}
+}
+
+func TestPageNames(t *testing.T) {
+	pkgs := []*Package{{Name: "main", Path: "."}, {Name: "kedai", Path: "shop/kedai"}, {Name: "x", Path: "shop-kedai"}, {Name: "index", Path: "index"}}
+	got := strings.Join(pageNames(pkgs), " ")
+	if want := "main.html shop-kedai.html shop-kedai-2.html index-2.html"; got != want {
+		t.Errorf("pageNames() = %q, want %q", got, want)
+	}
+}
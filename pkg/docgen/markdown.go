@@ -0,0 +1,81 @@
+package docgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/doc/comment"
+	"io"
+	"strings"
+)
+
+// Options control how documentation is rendered.
+type Options struct {
+	Go bool // show the Go each declaration becomes next to its Singlish
+}
+
+// Markdown writes the documentation of pkgs as one Markdown document, with
+// a top-level heading for each package.
+func Markdown(w io.Writer, pkgs []*Package, opts Options) error {
+	var b bytes.Buffer
+	for i, pkg := range pkgs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "# Package %s\n\n", pkg.Name)
+		if pkg.Path != "" {
+			fmt.Fprintf(&b, "Directory: `%s`\n\n", pkg.Path)
+		}
+		writeDoc(&b, pkg.Doc, 2)
+
+		sections := []struct {
+			title string
+			decls []*Decl
+		}{
+			{"Constants", pkg.Consts},
+			{"Variables", pkg.Vars},
+			{"Functions", pkg.Funcs},
+		}
+		for _, s := range sections {
+			if len(s.decls) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "## %s\n\n", s.title)
+			for _, d := range s.decls {
+				markdownDecl(&b, d, 3, opts)
+			}
+		}
+		if len(pkg.Types) > 0 {
+			b.WriteString("## Types\n\n")
+			for _, t := range pkg.Types {
+				markdownDecl(&b, &t.Decl, 3, opts)
+				for _, m := range t.Methods {
+					markdownDecl(&b, m, 4, opts)
+				}
+			}
+		}
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// markdownDecl writes a heading for d at level, its code and its comment.
+func markdownDecl(b *bytes.Buffer, d *Decl, level int, opts Options) {
+	fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", level), d.Name)
+	fmt.Fprintf(b, "```singlish\n%s\n```\n\n", d.Singlish)
+	if opts.Go && d.Go != "" {
+		fmt.Fprintf(b, "```go\n%s\n```\n\n", d.Go)
+	}
+	writeDoc(b, d.Doc, level+1)
+}
+
+// writeDoc writes the comment text as Markdown, with any headings in it at
+// level.
+func writeDoc(b *bytes.Buffer, text string, level int) {
+	if text == "" {
+		return
+	}
+	var p comment.Parser
+	printer := &comment.Printer{HeadingLevel: level}
+	b.Write(printer.Markdown(p.Parse(text)))
+	b.WriteString("\n")
+}
@@ -0,0 +1,50 @@
+package docgen
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	pkg := collect(t, kedai)
+	tests := []struct {
+		opts          Options
+		want, notWant []string
+	}{
+		{
+			opts: Options{},
+			want: []string{
+				"# Package kedai\n\nDirectory: `kedai`\n\nPackage kedai runs a kopi shop.\n",
+				"## Constants\n\n### MaxOrders\n\n```singlish\nconfirm MaxOrders = 10\n```\n\nMaxOrders is how many orders fit.\n",
+				"## Functions\n\n### Open\n",
+				"## Types\n\n### Kedai\n",
+				"#### Kedai.Close\n\n```singlish\naction (k ki Kedai) Close() salah\n```\n",
+			},
+			notWant: []string{"```go", "helper", "restock", "stall", "Show"},
+		},
+		{
+			opts: Options{Go: true},
+			want: []string{
+				"```singlish\naction Open(name tar) (ki Kedai, salah)\n```\n\n```go\nfunc Open(name string) (*Kedai, error)\n```\n\nOpen opens a shop.\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Markdown(&b, []*Package{pkg}, tt.opts); err != nil {
+			t.Fatalf("Markdown failed: %v", err)
+		}
+		out := b.String()
+		for _, s := range tt.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("Markdown(%+v) does not contain %q:\n%s", tt.opts, s, out)
+			}
+		}
+		for _, s := range tt.notWant {
+			if strings.Contains(out, s) {
+				t.Errorf("Markdown(%+v) contains %q:\n%s", tt.opts, s, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Package examples checks Singlish example programs against expectations
+// written next to them.
+//
+// Expectations come from directives in line comments of the example:
+//
+//	// expect: Hello Singapore!     one line of expected stdout, in order
+//	// expect-error: index out of range   the run must fail with this text on stderr
+//	// skip: starts a server that never exits
+//
+// or from a golden file holding the exact expected stdout: foo.golden next
+// to foo.singlish. A golden file takes precedence over expect directives.
+// Examples with no expectations pass as long as they exit successfully.
+package examples
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rickchow/singlish/pkg/textdiff"
+)
+
+// Spec describes what an example is expected to do.
+type Spec struct {
+	Path       string
+	Skip       string   // reason to skip the example, if any
+	Stdout     string   // expected stdout, valid if HasStdout
+	HasStdout  bool     // whether stdout is checked at all
+	Golden     string   // golden file Stdout was read from, if any
+	Errors     []string // text expected on stderr; the run must fail
+	expectSeen bool     // expect directives were present
+}
+
+// GoldenPath returns the golden file path for an example: foo.singlish -> foo.golden.
+func GoldenPath(path string) string {
+	return strings.TrimSuffix(path, ".singlish") + ".golden"
+}
+
+// Load reads the example at path and collects its expectations.
+func Load(path string) (*Spec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read example: %w", err)
+	}
+	spec := ParseDirectives(string(content))
+	spec.Path = path
+
+	golden := GoldenPath(path)
+	data, err := os.ReadFile(golden)
+	switch {
+	case err == nil:
+		spec.Stdout = string(data)
+		spec.HasStdout = true
+		spec.Golden = golden
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to read golden file: %w", err)
+	}
+	return spec, nil
+}
+
+// ParseDirectives collects the expect, expect-error and skip directives in source.
+func ParseDirectives(source string) *Spec {
+	spec := &Spec{}
+	var stdout strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(source))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "//") {
+			continue
+		}
+		comment := strings.TrimPrefix(strings.TrimPrefix(line, "//"), " ")
+		name, value, ok := strings.Cut(comment, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimPrefix(value, " ")
+		switch name {
+		case "expect":
+			stdout.WriteString(value + "\n")
+			spec.expectSeen = true
+		case "expect-error":
+			spec.Errors = append(spec.Errors, value)
+		case "skip":
+			spec.Skip = value
+			if spec.Skip == "" {
+				spec.Skip = "skipped"
+			}
+		}
+	}
+	if spec.expectSeen {
+		spec.Stdout = stdout.String()
+		spec.HasStdout = true
+	}
+	return spec
+}
+
+// HasExpectDirectives reports whether the example spells out its output
+// with // expect: lines.
+func (s *Spec) HasExpectDirectives() bool {
+	return s.expectSeen
+}
+
+// Outcome is what happened when an example was run.
+type Outcome struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	TimedOut bool
+	Err      error // the example could not be started at all
+}
+
+// Status is the verdict for one example.
+type Status string
+
+const (
+	Pass Status = "PASS"
+	Fail Status = "FAIL"
+	Skip Status = "SKIP"
+)
+
+// Result is the verdict for one example together with how it was reached.
+type Result struct {
+	Spec     *Spec
+	Status   Status
+	Reason   string // why the example failed or was skipped
+	Diff     string // unified diff of expected against actual stdout
+	Duration time.Duration
+	Outcome  Outcome
+}
+
+// Check compares an outcome against the spec's expectations.
+func Check(spec *Spec, out Outcome) Result {
+	r := Result{Spec: spec, Status: Fail, Outcome: out}
+	switch {
+	case out.Err != nil:
+		r.Reason = out.Err.Error()
+		return r
+	case out.TimedOut:
+		r.Reason = "timed out"
+		return r
+	case len(spec.Errors) > 0:
+		if out.ExitCode == 0 {
+			r.Reason = fmt.Sprintf("expected failure with %q, but it succeeded", spec.Errors[0])
+			return r
+		}
+		for _, want := range spec.Errors {
+			if !strings.Contains(out.Stderr, want) {
+				r.Reason = fmt.Sprintf("stderr does not contain %q", want)
+				return r
+			}
+		}
+	case out.ExitCode != 0:
+		r.Reason = fmt.Sprintf("exit status %d", out.ExitCode)
+		return r
+	}
+
+	if spec.HasStdout {
+		got := strings.ReplaceAll(out.Stdout, "\r\n", "\n")
+		if got != spec.Stdout {
+			r.Reason = "output mismatch"
+			r.Diff = textdiff.Unified("expected", "actual", spec.Stdout, got)
+			return r
+		}
+	}
+	r.Status = Pass
+	return r
+}
+
+// RunFunc runs one example and reports what happened. Enforcing a time
+// limit is up to the RunFunc, which reports it through Outcome.TimedOut.
+type RunFunc func(ctx context.Context, path string) Outcome
+
+// RunAll runs the examples with up to parallel at a time and returns the
+// results in the order of specs.
+func RunAll(ctx context.Context, specs []*Spec, run RunFunc, parallel int) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]Result, len(specs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		if spec.Skip != "" {
+			results[i] = Result{Spec: spec, Status: Skip, Reason: spec.Skip}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[i] = Check(spec, run(ctx, spec.Path))
+			results[i].Duration = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	return results
+}
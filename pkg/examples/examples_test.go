@@ -0,0 +1,119 @@
+package examples
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	source := `// Greeting example
+// expect: hello
+//expect:   two spaces
+kampung main
+// expect-error: boom
+// skip: needs network
+action boss() {
+    // expect: indented
+}
+`
+	spec := ParseDirectives(source)
+	if !spec.HasStdout || spec.Stdout != "hello\n  two spaces\nindented\n" {
+		t.Errorf("Stdout = %q", spec.Stdout)
+	}
+	if len(spec.Errors) != 1 || spec.Errors[0] != "boom" {
+		t.Errorf("Errors = %q", spec.Errors)
+	}
+	if spec.Skip != "needs network" {
+		t.Errorf("Skip = %q", spec.Skip)
+	}
+
+	if spec := ParseDirectives("kampung main\n"); spec.HasStdout || spec.Skip != "" || len(spec.Errors) != 0 {
+		t.Errorf("expected no expectations, got %+v", spec)
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.singlish")
+	if err := os.WriteFile(path, []byte("// expect: ignored\nkampung main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hello.golden"), []byte("from golden\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if spec.Stdout != "from golden\n" || spec.Golden == "" {
+		t.Errorf("golden file should take precedence, got %+v", spec)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   Spec
+		out    Outcome
+		status Status
+		reason string
+	}{
+		{"exit zero without expectations", Spec{}, Outcome{Stdout: "anything"}, Pass, ""},
+		{"non-zero exit", Spec{}, Outcome{ExitCode: 2}, Fail, "exit status 2"},
+		{"timeout", Spec{}, Outcome{TimedOut: true}, Fail, "timed out"},
+		{"matching output", Spec{Stdout: "a\nb\n", HasStdout: true}, Outcome{Stdout: "a\r\nb\r\n"}, Pass, ""},
+		{"output mismatch", Spec{Stdout: "a\nb\n", HasStdout: true}, Outcome{Stdout: "a\nc\n"}, Fail, "output mismatch"},
+		{"expected error", Spec{Errors: []string{"deadlock"}}, Outcome{ExitCode: 2, Stderr: "fatal error: deadlock!"}, Pass, ""},
+		{"expected error but succeeded", Spec{Errors: []string{"deadlock"}}, Outcome{}, Fail, "but it succeeded"},
+		{"wrong error", Spec{Errors: []string{"deadlock"}}, Outcome{ExitCode: 1, Stderr: "panic"}, Fail, "does not contain"},
+		{"could not start", Spec{}, Outcome{Err: errors.New("no go")}, Fail, "no go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Check(&tt.spec, tt.out)
+			if r.Status != tt.status || !strings.Contains(r.Reason, tt.reason) {
+				t.Errorf("Check = %s (%s), want %s (%s)", r.Status, r.Reason, tt.status, tt.reason)
+			}
+			if tt.reason == "output mismatch" && !strings.Contains(r.Diff, "-b\n+c\n") {
+				t.Errorf("missing diff, got:\n%s", r.Diff)
+			}
+		})
+	}
+}
+
+func TestRunAllAndReport(t *testing.T) {
+	specs := []*Spec{
+		{Path: "a.singlish", Stdout: "ok\n", HasStdout: true},
+		{Path: "b.singlish", Skip: "sleeps forever"},
+		{Path: "c.singlish", Stdout: "ok\n", HasStdout: true},
+	}
+	run := func(ctx context.Context, path string) Outcome {
+		if path == "c.singlish" {
+			return Outcome{Stdout: "not ok\n"}
+		}
+		return Outcome{Stdout: "ok\n"}
+	}
+	results := RunAll(context.Background(), specs, run, 2)
+	want := []Status{Pass, Skip, Fail}
+	for i, r := range results {
+		if r.Status != want[i] || r.Spec != specs[i] {
+			t.Errorf("result %d = %s for %s, want %s", i, r.Status, r.Spec.Path, want[i])
+		}
+	}
+	if !Failed(results) {
+		t.Errorf("Failed should report the failing example")
+	}
+
+	var out bytes.Buffer
+	Report(&out, results)
+	for _, want := range []string{"SKIP    b.singlish", "--- FAIL: c.singlish (output mismatch)", "    +not ok", "1 passed, 1 failed, 1 skipped"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, out.String())
+		}
+	}
+}
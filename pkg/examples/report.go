@@ -0,0 +1,70 @@
+package examples
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// maxStderrLines caps how much of a failing example's stderr is echoed.
+const maxStderrLines = 20
+
+// Report prints a pass/fail table for results followed by the details of
+// every failure, and a one-line summary.
+func Report(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "RESULT\tEXAMPLE\tTIME\tNOTE")
+	for _, r := range results {
+		elapsed := ""
+		if r.Status != Skip {
+			elapsed = r.Duration.Round(10 * time.Millisecond).String()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Status, r.Spec.Path, elapsed, r.Reason)
+	}
+	tw.Flush()
+
+	counts := map[Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		if r.Status != Fail {
+			continue
+		}
+		fmt.Fprintf(w, "\n--- FAIL: %s (%s)\n", r.Spec.Path, r.Reason)
+		if r.Diff != "" {
+			fmt.Fprint(w, indent(r.Diff))
+		}
+		if r.Diff == "" && strings.TrimSpace(r.Outcome.Stderr) != "" {
+			fmt.Fprint(w, indent(tail(r.Outcome.Stderr, maxStderrLines)))
+		}
+	}
+
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d skipped\n", counts[Pass], counts[Fail], counts[Skip])
+}
+
+// Failed reports whether any result failed.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func indent(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = "    " + l
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func tail(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = append([]string{"..."}, lines[len(lines)-n:]...)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -2,27 +2,101 @@ package formatter
 
 import (
 	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
 	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/imports"
+	"github.com/rickchow/singlish/pkg/lexer"
 )
 
+// Options controls the layout of formatted code.
+type Options struct {
+	// Indent is the number of spaces per indentation level. Zero indents
+	// with tabs.
+	Indent int
+
+	// Imports rewrites the import block as codegen would import: standard
+	// packages used without a dapao are added and unused ones removed.
+	Imports bool
+}
+
 // Format converts AST back to canonical Singlish source code.
 func Format(program *ast.Program, dict *dictionaries.Dictionary) (string, error) {
+	return FormatWith(program, dict, Options{})
+}
+
+// FormatWith is like Format, laying the code out as opts says.
+func FormatWith(program *ast.Program, dict *dictionaries.Dictionary, opts Options) (string, error) {
 	f := &formatter{
 		dict:        dict,
 		indentLevel: 0,
+		indentUnit:  "\t",
+	}
+	if opts.Indent > 0 {
+		f.indentUnit = strings.Repeat(" ", opts.Indent)
+	}
+	if opts.Imports {
+		fix, err := codegen.CheckImports(program, dict)
+		if err != nil {
+			return "", fmt.Errorf("cannot fix imports: %w", err)
+		}
+		program = fixImports(program, fix)
 	}
 
 	f.format(program)
 	return f.out.String(), nil
 }
 
+// fixImports returns program with the imports fix reports unused removed
+// and the missing ones added, sorted by path after the package clause.
+func fixImports(program *ast.Program, fix imports.Fix) *ast.Program {
+	if fix.Empty() {
+		return program
+	}
+	var stmts []ast.Statement
+	var paths []string
+	at := 0
+	for _, s := range program.Statements {
+		is, ok := s.(*ast.ImportStatement)
+		if !ok {
+			if _, ok := s.(*ast.PackageStatement); ok {
+				at = len(stmts) + 1
+			}
+			stmts = append(stmts, s)
+			continue
+		}
+		path, err := strconv.Unquote(is.Path.Value)
+		if err != nil {
+			path = strings.Trim(is.Path.Value, "\"`")
+		}
+		if !slices.Contains(fix.Unused, path) && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	paths = append(paths, fix.Missing...)
+	slices.Sort(paths)
+
+	var block []ast.Statement
+	for _, path := range paths {
+		quoted := strconv.Quote(path)
+		block = append(block, &ast.ImportStatement{
+			Token: lexer.Token{Type: lexer.TokenKeyword, Value: "import"},
+			Path:  &ast.StringLiteral{Token: lexer.Token{Type: lexer.TokenString, Value: quoted}, Value: quoted},
+		})
+	}
+	return &ast.Program{Statements: slices.Insert(stmts, at, block...)}
+}
+
 type formatter struct {
 	dict        *dictionaries.Dictionary
 	out         bytes.Buffer
 	indentLevel int
+	indentUnit  string
 }
 
 // canonicalize takes a token value (keyword) and returns the canonical Singlish keyword.
@@ -250,6 +324,11 @@ func (f *formatter) visitExpression(expr ast.Expression) {
 	case *ast.Identifier:
 		// Identifiers (variables) shouldn't be canonicalized usually, unless they are types or keywords used as identifiers?
 		// But here it's likely a variable name.
+		// Qualified aliases (gong for fmt.Println) are written as their word.
+		if alias, ok := f.qualifiedAlias(e.Value); ok {
+			f.write(alias)
+			return
+		}
 		f.write(e.Value)
 	case *ast.FloatLiteral:
 		f.write(e.Token.Value)
@@ -266,6 +345,16 @@ func (f *formatter) visitExpression(expr ast.Expression) {
 		if e.Operator == "." {
 			// Method call e.g. fmt.Println
 			// Should we canonicalize "fmt"? Probably not if it's a Go package.
+			// But a package function with an alias, such as
+			// strconv.Atoi for kira, is written as the alias.
+			left, okLeft := e.Left.(*ast.Identifier)
+			right, okRight := e.Right.(*ast.Identifier)
+			if okLeft && okRight {
+				if alias, ok := f.qualifiedAlias(left.Value + "." + right.Value); ok {
+					f.write(alias)
+					return
+				}
+			}
 			f.visitExpression(e.Left)
 			f.write(".")
 			f.visitExpression(e.Right)
@@ -291,13 +380,22 @@ func (f *formatter) visitExpression(expr ast.Expression) {
 	}
 }
 
+// qualifiedAlias returns the word for a qualified name such as
+// fmt.Println, if the dictionary has one.
+func (f *formatter) qualifiedAlias(name string) (string, bool) {
+	if _, ok := f.dict.Import(name); !ok {
+		return "", false
+	}
+	return f.dict.ReverseLookup(name)
+}
+
 func (f *formatter) write(s string) {
 	f.out.WriteString(s)
 }
 
 func (f *formatter) writeIndent() {
 	for i := 0; i < f.indentLevel; i++ {
-		f.out.WriteString("\t")
+		f.out.WriteString(f.indentUnit)
 	}
 }
 
@@ -7,6 +7,9 @@ import (
 	"github.com/rickchow/singlish/pkg/ast"
 	"github.com/rickchow/singlish/pkg/dictionaries"
 	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/transpiler"
+
+	"strings"
 )
 
 func TestFormat(t *testing.T) {
@@ -84,3 +87,112 @@ action main() {
 		t.Errorf("Format output mismatch.\nExpected:\n%q\nGot:\n%q", expected, output)
 	}
 }
+
+func TestFormatWithIndent(t *testing.T) {
+	src := "kampung main\n\naction main() {\ngot x nombor = 1\nbalek\n}\n"
+	dict := dictionaries.NewDefaultDictionary()
+	program, err := transpiler.Parse(src, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	output, err := FormatWith(program, dict, Options{Indent: 2})
+	if err != nil {
+		t.Fatalf("FormatWith failed: %v", err)
+	}
+	if want := "kampung main\n\naction main() {\n  got x nombor = 1\n  balek\n}\n"; output != want {
+		t.Errorf("FormatWith(Indent: 2) = %q, want %q", output, want)
+	}
+
+	tabbed, _ := Format(program, dict)
+	if !strings.Contains(tabbed, "\n\tgot x") {
+		t.Errorf("Format() = %q, want tabs", tabbed)
+	}
+}
+
+func TestFormatPhraseKeywords(t *testing.T) {
+	path := t.TempDir() + "/dict.txt"
+	content := "kampung: package\naction: func\nbalek: return\nbalek   lah: return\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := dictionaries.LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary failed: %v", err)
+	}
+	src := "kampung main\n\naction main() {\n\tbalek\tlah\n}\n"
+	program, err := transpiler.Parse(src, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	output, err := Format(program, dict)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if want := "kampung main\n\naction main() {\n\tbalek\n}\n"; output != want {
+		t.Errorf("Format() = %q, want %q", output, want)
+	}
+
+	// A canonical phrase is written with single spaces.
+	if err := os.WriteFile(path, []byte("kampung: package\naction: func\nbalek   lah: return\nbalek: return\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dict, err = dictionaries.LoadDictionary(path); err != nil {
+		t.Fatalf("LoadDictionary failed: %v", err)
+	}
+	output, _ = Format(program, dict)
+	if want := "kampung main\n\naction main() {\n\tbalek lah\n}\n"; output != want {
+		t.Errorf("Format() = %q, want %q", output, want)
+	}
+}
+
+func TestFormatQualifiedAliases(t *testing.T) {
+	path := t.TempDir() + "/dict.txt"
+	content := "kampung: package\naction: func\ngot: var\ngong: fmt.Println\nkira: strconv.Atoi\ntikam: math/rand.Intn\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := dictionaries.LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary failed: %v", err)
+	}
+	src := "kampung main\n\naction main() {\n\tgot n = strconv.Atoi(\"1\")\n\tgot r = rand.Intn(n)\n\tgot s = strings.ToUpper(\"a\")\n\tgong(r, s)\n}\n"
+	program, err := transpiler.Parse(src, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	output, err := Format(program, dict)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := "kampung main\n\naction main() {\n\tgot n = kira(\"1\")\n\tgot r = tikam(n)\n\tgot s = strings.ToUpper(\"a\")\n\tgong(r, s)\n}\n"
+	if output != want {
+		t.Errorf("Format() = %q, want %q", output, want)
+	}
+}
+
+func TestFormatImports(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := "kampung main\n\ndapao \"os\"\ndapao \"fmt\"\n\naction boss() {\n\tgot s = strings.ToUpper(\"a\")\n\tfmt.Println(s, time.Second)\n}\n"
+	program, err := transpiler.Parse(src, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	output, err := FormatWith(program, dict, Options{Imports: true})
+	if err != nil {
+		t.Fatalf("FormatWith failed: %v", err)
+	}
+	want := "kampung main\n\ndapao (\n\t\"fmt\"\n\t\"strings\"\n\t\"time\"\n)\n\naction boss() {\n\tgot s = strings.ToUpper(\"a\")\n\tgong(s, time.Second)\n}\n"
+	if output != want {
+		t.Errorf("FormatWith() = %q, want %q", output, want)
+	}
+
+	// The block fmt writes reads back the same.
+	program, err = transpiler.Parse(output, dict)
+	if err != nil {
+		t.Fatalf("Parse(formatted) failed: %v", err)
+	}
+	if again, _ := FormatWith(program, dict, Options{Imports: true}); again != output {
+		t.Errorf("FormatWith() again = %q, want %q", again, output)
+	}
+}
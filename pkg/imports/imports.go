@@ -0,0 +1,304 @@
+// Package imports works out which packages Go source is missing and which
+// of its imports it never uses, as goimports does. Only the standard library
+// is considered, and it is read from the local GOROOT, so nothing needs the
+// network.
+package imports
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Index maps package names to the standard library packages that have them.
+type Index struct {
+	src    string              // GOROOT/src
+	byName map[string][]string // package name to import paths, preferred first
+	names  map[string]string   // import path to package name
+
+	mu      sync.Mutex
+	exports map[string]map[string]bool // import path to exported names, read when needed
+}
+
+// Std returns the index of the standard library of the local Go
+// installation. It is built once and shared.
+var Std = sync.OnceValues(func() (*Index, error) {
+	root := goroot()
+	if root == "" {
+		return nil, fmt.Errorf("cannot find GOROOT; is go installed?")
+	}
+	return Load(root)
+})
+
+// goroot returns $GOROOT, or else what the go command says it is.
+func goroot() string {
+	if root := os.Getenv("GOROOT"); root != "" {
+		return root
+	}
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Load indexes the standard library in the Go tree at goroot. Commands,
+// internal packages and test data are left out.
+func Load(goroot string) (*Index, error) {
+	x := &Index{
+		src:     filepath.Join(goroot, "src"),
+		byName:  make(map[string][]string),
+		names:   make(map[string]string),
+		exports: make(map[string]map[string]bool),
+	}
+	err := filepath.WalkDir(x.src, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(x.src, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		base := d.Name()
+		if rel == "cmd" || rel == "builtin" || base == "internal" || base == "vendor" || base == "testdata" ||
+			strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+			return filepath.SkipDir
+		}
+		if name := packageName(p); name != "" && name != "main" {
+			x.names[rel] = name
+			x.byName[name] = append(x.byName[name], rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading the standard library: %w", err)
+	}
+	for _, paths := range x.byName {
+		slices.SortFunc(paths, func(a, b string) int {
+			if n, m := strings.Count(a, "/"), strings.Count(b, "/"); n != m {
+				return n - m
+			}
+			return strings.Compare(a, b)
+		})
+	}
+	return x, nil
+}
+
+// packageName returns the name of the package in dir, or "" if there is
+// none. It reads only the package clause of the first file that counts.
+func packageName(dir string) string {
+	fset := token.NewFileSet()
+	for _, file := range goFiles(dir) {
+		f, err := parser.ParseFile(fset, file, nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil || ignored(f) {
+			continue
+		}
+		return f.Name.Name
+	}
+	return ""
+}
+
+// goFiles returns the Go files in dir, leaving out tests.
+func goFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// ignored reports whether f is kept out of every build, as generators are.
+func ignored(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if c.Text == "//go:build ignore" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Name returns the name of the standard package with the import path, and
+// whether there is one.
+func (x *Index) Name(importPath string) (string, bool) {
+	name, ok := x.names[importPath]
+	return name, ok
+}
+
+// Resolve returns the import path of the standard package called name that
+// exports every one of members. When several do, or none do, the one with
+// the shortest path wins, as math/rand does over math/rand/v2.
+func (x *Index) Resolve(name string, members []string) (string, bool) {
+	paths := x.byName[name]
+	if len(paths) == 0 {
+		return "", false
+	}
+	if len(paths) > 1 {
+		for _, p := range paths {
+			if x.exportsAll(p, members) {
+				return p, true
+			}
+		}
+	}
+	return paths[0], true
+}
+
+func (x *Index) exportsAll(importPath string, members []string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	exports, ok := x.exports[importPath]
+	if !ok {
+		exports = readExports(filepath.Join(x.src, filepath.FromSlash(importPath)))
+		x.exports[importPath] = exports
+	}
+	for _, m := range members {
+		if !exports[m] {
+			return false
+		}
+	}
+	return true
+}
+
+// readExports returns the exported top-level names declared in dir.
+func readExports(dir string) map[string]bool {
+	exports := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range goFiles(dir) {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil && d.Name.IsExported() {
+					exports[d.Name.Name] = true
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if s.Name.IsExported() {
+							exports[s.Name.Name] = true
+						}
+					case *ast.ValueSpec:
+						for _, n := range s.Names {
+							if n.IsExported() {
+								exports[n.Name] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return exports
+}
+
+// Fix is what Check finds wrong with the imports of a Go file.
+type Fix struct {
+	Missing []string // standard packages the file uses without importing
+	Unused  []string // standard packages the file imports without using
+}
+
+// Empty reports whether there is nothing to fix.
+func (f Fix) Empty() bool {
+	return len(f.Missing) == 0 && len(f.Unused) == 0
+}
+
+// Check compares the imports of the Go source src with the packages it
+// refers to. A package is referred to by a selector such as strings.ToUpper
+// whose left side is declared nowhere in the file. Imports from outside
+// the standard library are never reported unused, since their names cannot
+// be known without reading them.
+func Check(src string, x *Index) (Fix, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return Fix{}, err
+	}
+
+	used := make(map[string][]string)
+	var order []string
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		id, ok := sel.X.(*ast.Ident)
+		if !ok || id.Obj != nil {
+			return true
+		}
+		if _, seen := used[id.Name]; !seen {
+			order = append(order, id.Name)
+		}
+		if !slices.Contains(used[id.Name], sel.Sel.Name) {
+			used[id.Name] = append(used[id.Name], sel.Sel.Name)
+		}
+		return true
+	})
+
+	var fix Fix
+	imported := make(map[string]bool)
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name, std := x.Name(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if !std {
+			name = guessName(importPath)
+		}
+		imported[name] = true
+		if _, ok := used[name]; !ok && std && name != "_" && name != "." {
+			fix.Unused = append(fix.Unused, importPath)
+		}
+	}
+	for _, name := range order {
+		if imported[name] {
+			continue
+		}
+		if importPath, ok := x.Resolve(name, used[name]); ok {
+			fix.Missing = append(fix.Missing, importPath)
+		}
+	}
+	return fix, nil
+}
+
+// guessName returns the usual name of the package at importPath: the last
+// element of the path that is not a major version, up to any dot.
+func guessName(importPath string) string {
+	name := path.Base(importPath)
+	if len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = path.Base(path.Dir(importPath))
+	}
+	name, _, _ = strings.Cut(name, ".")
+	return strings.TrimPrefix(name, "go-")
+}
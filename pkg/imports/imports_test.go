@@ -0,0 +1,134 @@
+package imports
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// fakeGOROOT writes a small standard library and returns its root.
+func fakeGOROOT(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"fmt/print.go":             "package fmt\n\nfunc Println(a ...any) {}\n",
+		"strings/strings.go":       "package strings\n\nfunc ToUpper(s string) string { return s }\n",
+		"strings/gen.go":           "//go:build ignore\n\npackage main\n",
+		"strings/strings_test.go":  "package strings_test\n",
+		"math/rand/rand.go":        "package rand\n\nfunc Intn(n int) int { return 0 }\n\ntype Rand struct{}\n\nfunc (r *Rand) Read() {}\n",
+		"math/rand/v2/rand.go":     "package rand\n\nfunc IntN(n int) int { return 0 }\n",
+		"crypto/rand/rand.go":      "package rand\n\nvar Reader, other any\n\nfunc Read(b []byte) {}\n",
+		"crypto/internal/x/x.go":   "package x\n",
+		"cmd/go/main.go":           "package main\n",
+		"text/template/exec.go":    "package template\n",
+		"os/testdata/data/data.go": "package data\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, "src", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestLoad(t *testing.T) {
+	x, err := Load(fakeGOROOT(t))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for path, want := range map[string]string{"fmt": "fmt", "strings": "strings", "math/rand/v2": "rand", "text/template": "template"} {
+		if got, ok := x.Name(path); !ok || got != want {
+			t.Errorf("Name(%q) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+	for _, path := range []string{"crypto/internal/x", "cmd/go", "os/testdata/data", "math"} {
+		if got, ok := x.Name(path); ok {
+			t.Errorf("Name(%q) = %q, want no package", path, got)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		members []string
+		want    string
+	}{
+		{"fmt", []string{"Println"}, "fmt"},
+		{"rand", []string{"Intn"}, "math/rand"},
+		{"rand", []string{"Read"}, "crypto/rand"},
+		{"rand", []string{"Reader"}, "crypto/rand"},
+		{"rand", []string{"IntN"}, "math/rand/v2"},
+		{"rand", []string{"Nope"}, "crypto/rand"},
+		{"template", nil, "text/template"},
+	}
+	for _, tt := range tests {
+		if got, ok := x.Resolve(tt.name, tt.members); !ok || got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %q, %v; want %q", tt.name, tt.members, got, ok, tt.want)
+		}
+	}
+	if got, ok := x.Resolve("kopi", nil); ok {
+		t.Errorf("Resolve(kopi) = %q, want no package", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	x, err := Load(fakeGOROOT(t))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	src := `package main
+
+import (
+	"fmt"
+	"text/template"
+	"example.com/kopi/v2"
+	_ "crypto/rand"
+)
+
+type point struct{ x int }
+
+func main() {
+	p := point{}
+	fmt.Println(p.x, strings.ToUpper("a"), rand.Intn(2), kopi.Brew())
+	var strings point
+	_ = strings.x
+}
+`
+	fix, err := Check(src, x)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !slices.Equal(fix.Missing, []string{"strings", "math/rand"}) {
+		t.Errorf("Missing = %q, want strings and math/rand", fix.Missing)
+	}
+	if !slices.Equal(fix.Unused, []string{"text/template"}) {
+		t.Errorf("Unused = %q, want text/template", fix.Unused)
+	}
+
+	fix, err = Check("package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n", x)
+	if err != nil || !fix.Empty() {
+		t.Errorf("Check(tidy) = %+v, %v; want nothing to fix", fix, err)
+	}
+	if _, err := Check("package main\nfunc {", x); err == nil {
+		t.Error("Check(invalid Go) succeeded, want an error")
+	}
+}
+
+func TestGuessName(t *testing.T) {
+	for path, want := range map[string]string{
+		"example.com/kopi":       "kopi",
+		"example.com/kopi/v2":    "kopi",
+		"gopkg.in/yaml.v3":       "yaml",
+		"github.com/x/go-isatty": "isatty",
+		"example.com/v2/vroom":   "vroom",
+	} {
+		if got := guessName(path); got != want {
+			t.Errorf("guessName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
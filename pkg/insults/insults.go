@@ -0,0 +1,188 @@
+// Package insults picks the Singlish line printed when something goes wrong.
+//
+// Lines come in packs. Each line is either mild, fit for a demo to
+// management, or garang (fierce). The Level chosen by the user caps how
+// harsh the lines may be, and the Severity of the error decides which
+// lines are picked at that level: at the garang level, trouble around the
+// code such as a missing file still gets a mild line, while broken code
+// gets a garang one.
+package insults
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SeedEnvVar makes the choice of lines reproducible, for tests and
+// recorded demos.
+const SeedEnvVar = "SINGLISH_SEED"
+
+// Level is how harsh the lines may be.
+type Level int
+
+const (
+	Off    Level = iota // no lines at all
+	Mild                // polite lines only
+	Garang              // anything goes
+)
+
+// Levels lists the names accepted by ParseLevel.
+var Levels = []string{"off", "mild", "garang"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(Levels) {
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+	return Levels[l]
+}
+
+// ParseLevel returns the Level named s.
+func ParseLevel(s string) (Level, error) {
+	for i, name := range Levels {
+		if s == name {
+			return Level(i), nil
+		}
+	}
+	return Off, fmt.Errorf("unknown insult level %q (want %s)", s, strings.Join(Levels, ", "))
+}
+
+// Severity is how bad the error being insulted is.
+type Severity int
+
+const (
+	Minor Severity = iota // trouble around the code: a missing file, a bad flag, a failed write
+	Major                 // the code itself is wrong: it does not compile, crashes or fails its tests
+)
+
+// Insult is one line of a pack.
+type Insult struct {
+	Text  string
+	Level Level // Mild or Garang
+}
+
+// Pack is a set of lines to choose from.
+type Pack []Insult
+
+// Default returns the pack built into singlish.
+func Default() Pack {
+	return Pack{
+		{"Simi sai is this?", Mild},
+		{"Catch no ball sia", Mild},
+		{"Why you so liddat?", Mild},
+		{"Aiyo, cannot make it lah", Mild},
+		{"Wake up your idea", Mild},
+		{"Eh bodoh", Garang},
+		{"Go fly kite lah", Garang},
+		{"You think this one playground ah?", Garang},
+		{"Blur like sotong", Garang},
+	}
+}
+
+// LoadPack reads a pack from a file. Each line of the file is a level and
+// the insult, separated by a colon:
+//
+//	# for demos
+//	mild: Aiyo, cannot make it lah
+//	garang: Eh bodoh
+//
+// Blank lines and lines starting with # or // are skipped.
+func LoadPack(path string) (Pack, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open insult pack %q: %w", path, err)
+	}
+	defer f.Close()
+	return ParsePack(f, path)
+}
+
+// ParsePack reads a pack in the format described at LoadPack. name is used
+// in error messages.
+func ParsePack(r io.Reader, name string) (Pack, error) {
+	var pack Pack
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		tag, text, ok := strings.Cut(line, ":")
+		text = strings.TrimSpace(text)
+		if !ok || text == "" {
+			return nil, fmt.Errorf("%s:%d: want \"mild: insult\" or \"garang: insult\", got %q", name, lineNo, line)
+		}
+		level, err := ParseLevel(strings.TrimSpace(tag))
+		if err != nil || level == Off {
+			return nil, fmt.Errorf("%s:%d: unknown level %q (want mild or garang)", name, lineNo, strings.TrimSpace(tag))
+		}
+		pack = append(pack, Insult{Text: text, Level: level})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading insult pack %q: %w", name, err)
+	}
+	if len(pack) == 0 {
+		return nil, fmt.Errorf("insult pack %q has no insults", name)
+	}
+	return pack, nil
+}
+
+// Engine picks lines from a pack.
+type Engine struct {
+	Level Level
+	Pack  Pack
+	rand  *rand.Rand
+}
+
+// New returns an engine picking from pack at level, in an order fixed by
+// seed.
+func New(level Level, pack Pack, seed int64) *Engine {
+	return &Engine{Level: level, Pack: pack, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Seed returns the seed in $SINGLISH_SEED or, if it is not set, one taken
+// from the clock.
+func Seed() (int64, error) {
+	s := os.Getenv(SeedEnvVar)
+	if s == "" {
+		return time.Now().UnixNano(), nil
+	}
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: want an integer", SeedEnvVar, s)
+	}
+	return seed, nil
+}
+
+// Pick returns a line for an error of severity sev, or "" if the engine is
+// off. Major errors at the garang level get garang lines; everything else
+// gets mild ones. When the pack has no line of the wanted kind, a garang
+// pick falls back to mild lines, but a mild pick never falls back to
+// garang ones and returns "" instead.
+func (e *Engine) Pick(sev Severity) string {
+	if e == nil || e.Level == Off {
+		return ""
+	}
+	want := Mild
+	if e.Level == Garang && sev == Major {
+		want = Garang
+	}
+	for ; want >= Mild; want-- {
+		var lines []string
+		for _, in := range e.Pack {
+			if in.Level == want {
+				lines = append(lines, in.Text)
+			}
+		}
+		if len(lines) > 0 {
+			return lines[e.rand.Intn(len(lines))]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package insults
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range Levels {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Fatalf("ParseLevel(%q) failed: %v", name, err)
+		}
+		if level.String() != name {
+			t.Errorf("ParseLevel(%q).String() = %q", name, level.String())
+		}
+	}
+	if _, err := ParseLevel("sibeh"); err == nil {
+		t.Errorf("ParseLevel(sibeh) succeeded, want error")
+	}
+}
+
+func TestParsePack(t *testing.T) {
+	input := `# demo pack
+mild: Aiyo, try again lah
+
+// fierce ones
+garang: Wah lau eh: what is this
+`
+	pack, err := ParsePack(strings.NewReader(input), "demo.txt")
+	if err != nil {
+		t.Fatalf("ParsePack failed: %v", err)
+	}
+	want := Pack{{"Aiyo, try again lah", Mild}, {"Wah lau eh: what is this", Garang}}
+	if len(pack) != len(want) {
+		t.Fatalf("ParsePack() = %v, want %v", pack, want)
+	}
+	for i := range want {
+		if pack[i] != want[i] {
+			t.Errorf("pack[%d] = %v, want %v", i, pack[i], want[i])
+		}
+	}
+
+	errs := map[string]string{
+		"no level":      "Eh bodoh\n",
+		"unknown level": "fierce: Eh bodoh\n",
+		"off level":     "off: Eh bodoh\n",
+		"empty insult":  "mild:\n",
+		"empty pack":    "# nothing here\n",
+	}
+	for name, input := range errs {
+		if _, err := ParsePack(strings.NewReader(input), "bad.txt"); err == nil {
+			t.Errorf("%s: ParsePack succeeded, want error", name)
+		}
+	}
+}
+
+func TestLoadPack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pack.txt")
+	if err := os.WriteFile(path, []byte("mild: Steady lah\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pack, err := LoadPack(path)
+	if err != nil {
+		t.Fatalf("LoadPack failed: %v", err)
+	}
+	if len(pack) != 1 || pack[0].Text != "Steady lah" {
+		t.Errorf("LoadPack() = %v", pack)
+	}
+	if _, err := LoadPack(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("LoadPack of a missing file succeeded")
+	}
+}
+
+func TestPick(t *testing.T) {
+	pack := Default()
+	levelOf := make(map[string]Level)
+	for _, in := range pack {
+		levelOf[in.Text] = in.Level
+	}
+
+	tests := []struct {
+		level Level
+		sev   Severity
+		want  Level
+	}{
+		{Mild, Minor, Mild},
+		{Mild, Major, Mild},
+		{Garang, Minor, Mild},
+		{Garang, Major, Garang},
+	}
+	for _, tt := range tests {
+		e := New(tt.level, pack, 1)
+		for i := 0; i < 20; i++ {
+			line := e.Pick(tt.sev)
+			if got, ok := levelOf[line]; !ok || got != tt.want {
+				t.Fatalf("level %v, severity %d: picked %q, want a %v line", tt.level, tt.sev, line, tt.want)
+			}
+		}
+	}
+
+	if line := New(Off, pack, 1).Pick(Major); line != "" {
+		t.Errorf("Off picked %q", line)
+	}
+	var nilEngine *Engine
+	if line := nilEngine.Pick(Major); line != "" {
+		t.Errorf("nil engine picked %q", line)
+	}
+}
+
+func TestPickFallback(t *testing.T) {
+	mildOnly := Pack{{"Steady lah", Mild}}
+	if line := New(Garang, mildOnly, 1).Pick(Major); line != "Steady lah" {
+		t.Errorf("garang pick from a mild pack = %q, want the mild line", line)
+	}
+	garangOnly := Pack{{"Eh bodoh", Garang}}
+	if line := New(Mild, garangOnly, 1).Pick(Major); line != "" {
+		t.Errorf("mild pick from a garang pack = %q, want nothing", line)
+	}
+}
+
+func TestPickIsReproducible(t *testing.T) {
+	a, b := New(Garang, Default(), 42), New(Garang, Default(), 42)
+	for i := 0; i < 10; i++ {
+		if x, y := a.Pick(Major), b.Pick(Major); x != y {
+			t.Fatalf("pick %d: %q != %q with the same seed", i, x, y)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	t.Setenv(SeedEnvVar, "42")
+	if seed, err := Seed(); err != nil || seed != 42 {
+		t.Errorf("Seed() = %d, %v, want 42", seed, err)
+	}
+	t.Setenv(SeedEnvVar, "lah")
+	if _, err := Seed(); err == nil {
+		t.Errorf("Seed() with a bad %s succeeded", SeedEnvVar)
+	}
+	t.Setenv(SeedEnvVar, "")
+	if _, err := Seed(); err != nil {
+		t.Errorf("Seed() without %s failed: %v", SeedEnvVar, err)
+	}
+}
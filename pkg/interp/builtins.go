@@ -0,0 +1,171 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+)
+
+// builtinArgs evaluates the arguments of a builtin call. make and new take a
+// type as their first argument, and append(xs, ys...) is flattened here.
+func (it *Interpreter) builtinArgs(name string, exprs []ast.Expression, scope *env) []any {
+	var args []any
+	for i, e := range exprs {
+		if i == 0 && (name == "make" || name == "new") {
+			args = append(args, typeRef(it.typeString(e)))
+			continue
+		}
+		if ident, ok := e.(*ast.Identifier); ok && name == "append" && strings.HasSuffix(ident.Value, "...") {
+			rest := it.eval(it.spreadOperand(ident), scope)
+			switch r := unwrap(rest).(type) {
+			case []any:
+				args = append(args, r...)
+			case string:
+				for j := 0; j < len(r); j++ {
+					args = append(args, int(r[j]))
+				}
+			}
+			continue
+		}
+		v := it.eval(e, scope)
+		if i == 0 && name != "panic" && name != "min" && name != "max" {
+			// len, append, ... operate on the underlying slice, map or string.
+			v = unwrap(v)
+		}
+		args = append(args, v)
+	}
+	return args
+}
+
+func (it *Interpreter) callBuiltin(name string, args []any, scope *env) any {
+	arg := func(i int) any {
+		if i >= len(args) {
+			panic(runtimeError("not enough arguments in call to %s", name))
+		}
+		return args[i]
+	}
+
+	switch name {
+	case "len":
+		switch v := arg(0).(type) {
+		case nil:
+			return 0
+		case string:
+			return len(v)
+		case []any:
+			return len(v)
+		case *Map:
+			return len(v.m)
+		case *Pointer:
+			if arr, ok := v.load().([]any); ok {
+				return len(arr)
+			}
+		default:
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Chan || rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map {
+				return rv.Len()
+			}
+		}
+		panic(runtimeError("invalid argument %v (%s) for len", args[0], typeName(args[0])))
+	case "cap":
+		switch v := arg(0).(type) {
+		case nil:
+			return 0
+		case []any:
+			return cap(v)
+		default:
+			rv := reflect.ValueOf(v)
+			if rv.Kind() == reflect.Chan {
+				return rv.Cap()
+			}
+		}
+		panic(runtimeError("invalid argument %v (%s) for cap", args[0], typeName(args[0])))
+	case "append":
+		base, ok := arg(0).([]any)
+		if !ok && arg(0) != nil {
+			panic(runtimeError("first argument to append must be a slice, not %s", typeName(args[0])))
+		}
+		for _, v := range args[1:] {
+			base = append(base, copyValue(v))
+		}
+		return base
+	case "make":
+		return it.makeValue(string(arg(0).(typeRef)), args[1:])
+	case "new":
+		return it.pointerTo(it.zero(string(arg(0).(typeRef))))
+	case "delete":
+		if m, ok := arg(0).(*Map); ok {
+			delete(m.m, m.keyOf(arg(1)))
+		}
+		return nil
+	case "close":
+		it.chanValue(arg(0)).Close()
+		return nil
+	case "copy":
+		dst, _ := arg(0).([]any)
+		switch src := arg(1).(type) {
+		case []any:
+			return copy(dst, src)
+		case string:
+			n := 0
+			for ; n < len(dst) && n < len(src); n++ {
+				dst[n] = src[n]
+			}
+			return n
+		}
+		return 0
+	case "panic":
+		panic(userPanic{value: arg(0)})
+	case "min", "max":
+		best := arg(0)
+		for _, v := range args[1:] {
+			if (name == "min" && it.less(v, best)) || (name == "max" && it.less(best, v)) {
+				best = v
+			}
+		}
+		return best
+	case "print", "println":
+		parts := make([]string, len(args))
+		for i, a := range args {
+			parts[i] = fmt.Sprint(a)
+		}
+		if name == "println" {
+			fmt.Fprintln(it.Stderr, strings.Join(parts, " "))
+		} else {
+			fmt.Fprint(it.Stderr, strings.Join(parts, ""))
+		}
+		return nil
+	}
+	panic(runtimeError("unsupported builtin %s", name))
+}
+
+func (it *Interpreter) makeValue(t string, args []any) any {
+	size := 0
+	if len(args) > 0 {
+		size = it.toInt(args[0])
+	}
+	switch {
+	case strings.HasPrefix(t, "[]"):
+		capacity := size
+		if len(args) > 1 {
+			capacity = it.toInt(args[1])
+		}
+		out := make([]any, size, capacity)
+		for i := range out {
+			out[i] = it.zero(t[2:])
+		}
+		return out
+	case strings.HasPrefix(t, "map["):
+		key, elem := mapTypes(t)
+		return &Map{it: it, m: make(map[any]any, size), key: key, elem: elem}
+	case strings.HasPrefix(t, "chan"):
+		return make(chan any, size)
+	}
+	if decl, ok := it.types[t]; ok && decl.underlying != "" {
+		return it.makeValue(decl.underlying, args)
+	}
+	panic(runtimeError("cannot make %s", strconv.Quote(t)))
+}
@@ -0,0 +1,710 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+// builtin is a predeclared function such as len or append.
+type builtin string
+
+// typeRef is a type name used as a value, e.g. the callee of a conversion.
+type typeRef string
+
+// boundMethod is a method value such as d.Speak taken without calling it.
+type boundMethod struct {
+	recv any
+	fn   *Function
+}
+
+// methodExpr is a method expression such as Dog.Speak; the receiver is the first argument.
+type methodExpr struct {
+	fn *Function
+}
+
+var builtins = map[string]bool{
+	"len": true, "cap": true, "append": true, "make": true, "delete": true, "close": true,
+	"copy": true, "panic": true, "recover": true, "new": true, "min": true, "max": true,
+	"print": true, "println": true,
+}
+
+func (it *Interpreter) eval(expr ast.Expression, scope *env) any {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ast.IntegerLiteral:
+		return int(e.Value)
+	case *ast.FloatLiteral:
+		return e.Value
+	case *ast.StringLiteral:
+		s, err := strconv.Unquote(e.Token.Value)
+		if err != nil {
+			if r, _, _, rerr := strconv.UnquoteChar(strings.Trim(e.Token.Value, "'"), '\''); rerr == nil && strings.HasPrefix(e.Token.Value, "'") {
+				return int(r)
+			}
+			return e.Value
+		}
+		if strings.HasPrefix(e.Token.Value, "'") {
+			r := []rune(s)
+			if len(r) == 1 {
+				return int(r[0])
+			}
+		}
+		return s
+	case *ast.Identifier:
+		return it.evalIdentifier(e, scope)
+	case *ast.PrefixExpression:
+		return it.evalPrefix(e, scope)
+	case *ast.InfixExpression:
+		return it.evalInfix(e, scope)
+	case *ast.CallExpression:
+		return it.evalCall(e, scope)
+	case *ast.IndexExpression:
+		return it.evalIndex(e, scope)
+	case *ast.SliceExpression:
+		return it.evalSlice(e, scope)
+	case *ast.CompositeLiteral:
+		return it.evalComposite(it.typeString(e.Type), e.Elements, scope)
+	case *ast.FunctionLiteral:
+		return &Function{params: e.Parameters, results: it.typeString(e.ReturnType), body: e.Body, closure: scope}
+	case *ast.TypeAssertionExpression:
+		v := it.eval(e.Left, scope)
+		t := it.typeString(e.Type)
+		if !it.matchesType(v, t) {
+			panic(runtimeError("interface conversion: interface {} is %s, not %s", typeName(v), t))
+		}
+		return v
+	case *ast.IncDecStatement:
+		it.execIncDec(e, scope)
+		return nil
+	}
+	panic(runtimeError("unsupported expression %s", expr.String()))
+}
+
+func (it *Interpreter) evalIdentifier(e *ast.Identifier, scope *env) any {
+	name := it.ident(e.Value)
+	switch name {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "nil":
+		return nil
+	}
+	if c, ok := scope.lookup(name); ok {
+		return c.value
+	}
+	if c, ok := scope.lookup(e.Value); ok {
+		return c.value
+	}
+	if builtins[name] {
+		return builtin(name)
+	}
+	if dot := strings.Index(name, "."); dot > 0 && !strings.ContainsAny(name, "[]*( ") {
+		return it.member(name[:dot], name[dot+1:])
+	}
+	if it.isType(name) {
+		return typeRef(name)
+	}
+	panic(runtimeError("undefined: %s", e.Value))
+}
+
+// member returns an exported member of an imported package.
+func (it *Interpreter) member(pkg, name string) any {
+	path, ok := it.packages[pkg]
+	if !ok {
+		panic(runtimeError("undefined: %s (missing %s?)", pkg, it.keyword("import")))
+	}
+	if v, ok := stdlib[path][name]; ok {
+		return v
+	}
+	if path == "os" && name == "Args" {
+		return it.osArgs()
+	}
+	if _, ok := stdlibTypes[pkg+"."+name]; ok {
+		return typeRef(pkg + "." + name)
+	}
+	return builtin(pkg + "." + name)
+}
+
+// isPackage reports whether name refers to an imported package rather than a variable.
+func (it *Interpreter) isPackage(name string, scope *env) bool {
+	if _, ok := it.packages[name]; !ok {
+		return false
+	}
+	_, shadowed := scope.lookup(name)
+	return !shadowed
+}
+
+func (it *Interpreter) evalPrefix(e *ast.PrefixExpression, scope *env) any {
+	switch e.Operator {
+	case "&":
+		return it.addressOf(e.Right, scope)
+	case "<-":
+		v, _ := it.receive(it.eval(e.Right, scope))
+		return v
+	}
+	v := it.eval(e.Right, scope)
+	switch e.Operator {
+	case "-":
+		return it.binary("-", 0, v)
+	case "+":
+		return v
+	case "!":
+		return !it.truthy(v)
+	case "^":
+		return it.binary("^", -1, v)
+	case "*":
+		p, ok := v.(*Pointer)
+		if !ok || p == nil {
+			if isNil(v) {
+				panic(runtimeError("invalid memory address or nil pointer dereference"))
+			}
+			// *T in a type position, e.g. new(*T); treat as the pointed-to value.
+			return v
+		}
+		return p.load()
+	}
+	panic(runtimeError("unsupported operator %s", e.Operator))
+}
+
+func (it *Interpreter) addressOf(target ast.Expression, scope *env) any {
+	switch t := target.(type) {
+	case *ast.Identifier:
+		c, ok := scope.lookup(it.ident(t.Value))
+		if !ok {
+			panic(runtimeError("undefined: %s", t.Value))
+		}
+		if s, ok := c.value.(*Struct); ok {
+			return &Pointer{Struct: s}
+		}
+		return &Pointer{Cell: c}
+	case *ast.CompositeLiteral:
+		return it.pointerTo(it.eval(t, scope))
+	}
+	return it.pointerTo(it.eval(target, scope))
+}
+
+func (it *Interpreter) pointerTo(v any) *Pointer {
+	if s, ok := v.(*Struct); ok {
+		return &Pointer{Struct: s}
+	}
+	return &Pointer{Cell: &cell{value: v}}
+}
+
+func (it *Interpreter) evalInfix(e *ast.InfixExpression, scope *env) any {
+	switch e.Operator {
+	case ".":
+		return it.evalSelector(e, scope)
+	case "&&":
+		return it.truthy(it.eval(e.Left, scope)) && it.truthy(it.eval(e.Right, scope))
+	case "||":
+		return it.truthy(it.eval(e.Left, scope)) || it.truthy(it.eval(e.Right, scope))
+	case "=", ":=", "<-":
+		it.execExpression(e, scope)
+		return nil
+	}
+	return it.binary(e.Operator, it.eval(e.Left, scope), it.eval(e.Right, scope))
+}
+
+func (it *Interpreter) selectorName(e ast.Expression) string {
+	if ident, ok := e.(*ast.Identifier); ok {
+		return ident.Value
+	}
+	panic(runtimeError("unsupported selector %s", e.String()))
+}
+
+func (it *Interpreter) evalSelector(e *ast.InfixExpression, scope *env) any {
+	// ch.pass(v) is a channel send written as a method call.
+	if pref, ok := e.Right.(*ast.PrefixExpression); ok && pref.Operator == "<-" {
+		ch := it.eval(e.Left, scope)
+		if call, ok := pref.Right.(*ast.CallExpression); ok && len(call.Arguments) > 0 {
+			it.send(ch, it.eval(call.Arguments[0], scope))
+		} else {
+			it.send(ch, it.eval(pref.Right, scope))
+		}
+		return nil
+	}
+	if ta, ok := e.Right.(*ast.TypeAssertionExpression); ok {
+		return it.eval(&ast.TypeAssertionExpression{Token: ta.Token, Left: e.Left, Type: ta.Type}, scope)
+	}
+
+	name := it.selectorName(e.Right)
+	if left, ok := e.Left.(*ast.Identifier); ok && it.isPackage(left.Value, scope) {
+		return it.member(left.Value, name)
+	}
+	return it.field(it.eval(e.Left, scope), name)
+}
+
+// field returns a struct field or a bound method of recv.
+func (it *Interpreter) field(recv any, name string) any {
+	if tr, ok := recv.(typeRef); ok {
+		return it.methodExpression(string(tr), name)
+	}
+	if p, ok := recv.(*Pointer); ok && p != nil && p.Struct == nil {
+		if n, ok := p.load().(Named); ok {
+			if m, ok := n.typ.methods[name]; ok && m.pointerReceiver() {
+				return &boundMethod{recv: p, fn: m}
+			}
+		}
+		recv = p.load()
+	}
+	if n, ok := recv.(Named); ok {
+		if m, ok := n.typ.methods[name]; ok {
+			return &boundMethod{recv: n, fn: m}
+		}
+		recv = n.value
+	}
+	if _, ok := recv.(*Struct); ok || isStructPointer(recv) {
+		s := it.structOf(recv)
+		if i, ok := s.field(name); ok {
+			return s.fields[i]
+		}
+		if m, ok := s.typ.methods[name]; ok {
+			return &boundMethod{recv: s.receiverFor(m), fn: m}
+		}
+		panic(runtimeError("%s has no field or method %s", s.typ.name, name))
+	}
+	if isNil(recv) {
+		panic(runtimeError("invalid memory address or nil pointer dereference"))
+	}
+	rv := reflect.ValueOf(recv)
+	if m := rv.MethodByName(name); m.IsValid() {
+		return m
+	}
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct {
+		if f := rv.FieldByName(name); f.IsValid() && f.CanInterface() {
+			return fromReflect(f)
+		}
+	}
+	panic(runtimeError("%s has no field or method %s", typeName(recv), name))
+}
+
+func (it *Interpreter) methodExpression(t, name string) any {
+	if decl, ok := it.types[strings.TrimPrefix(t, "*")]; ok {
+		methods := decl.methods
+		if decl.strct != nil {
+			methods = decl.strct.methods
+		}
+		if m, ok := methods[name]; ok {
+			return &methodExpr{fn: m}
+		}
+	}
+	panic(runtimeError("%s has no method %s", t, name))
+}
+
+func isStructPointer(v any) bool {
+	p, ok := v.(*Pointer)
+	return ok && p != nil && p.Struct != nil
+}
+
+func (it *Interpreter) evalIndex(e *ast.IndexExpression, scope *env) any {
+	container := it.eval(e.Left, scope)
+	if p, ok := container.(*Pointer); ok && p != nil {
+		container = p.load()
+	}
+	container = unwrap(container)
+	index := it.eval(e.Index, scope)
+	switch c := container.(type) {
+	case []any:
+		return c[it.toInt(index)]
+	case string:
+		return c[it.toInt(index)]
+	case *Map:
+		v, _ := c.get(index)
+		return v
+	}
+	panic(runtimeError("cannot index %v (%s)", container, typeName(container)))
+}
+
+func (it *Interpreter) evalSlice(e *ast.SliceExpression, scope *env) any {
+	container := it.eval(e.Left, scope)
+	if p, ok := container.(*Pointer); ok && p != nil {
+		container = p.load()
+	}
+	container = unwrap(container)
+	low, high := 0, -1
+	if e.Low != nil {
+		low = it.toInt(it.eval(e.Low, scope))
+	}
+	if e.High != nil {
+		high = it.toInt(it.eval(e.High, scope))
+	}
+	switch c := container.(type) {
+	case []any:
+		if high < 0 {
+			high = len(c)
+		}
+		return c[low:high]
+	case string:
+		if high < 0 {
+			high = len(c)
+		}
+		return c[low:high]
+	case nil:
+		return []any(nil)
+	}
+	panic(runtimeError("cannot slice %v (%s)", container, typeName(container)))
+}
+
+func (it *Interpreter) evalComposite(t string, elements []ast.Expression, scope *env) any {
+	elem := func(e ast.Expression, elemType string) any {
+		if lit, ok := e.(*ast.CompositeLiteral); ok && lit.Type == nil {
+			ptr := strings.HasPrefix(elemType, "*")
+			v := it.evalComposite(strings.TrimPrefix(elemType, "*"), lit.Elements, scope)
+			if ptr {
+				return it.pointerTo(v)
+			}
+			return v
+		}
+		return it.convertForAssign(copyValue(it.eval(e, scope)), elemType)
+	}
+
+	switch {
+	case strings.HasPrefix(t, "map["):
+		key, val := mapTypes(t)
+		m := &Map{it: it, m: make(map[any]any, len(elements)), key: key, elem: val}
+		for _, e := range elements {
+			kv, ok := e.(*ast.KeyValueExpression)
+			if !ok {
+				panic(runtimeError("missing key in map literal"))
+			}
+			m.m[elem(kv.Key, key)] = elem(kv.Value, val)
+		}
+		return m
+	case strings.HasPrefix(t, "["):
+		elemType := t[strings.Index(t, "]")+1:]
+		out := make([]any, 0, len(elements))
+		for _, e := range elements {
+			out = append(out, elem(e, elemType))
+		}
+		if n, err := strconv.Atoi(t[1:strings.Index(t, "]")]); err == nil {
+			for len(out) < n {
+				out = append(out, it.zero(elemType))
+			}
+		}
+		return out
+	}
+
+	if rt, ok := stdlibTypes[t]; ok && rt.Kind() == reflect.Struct {
+		return it.nativeComposite(rt, elements, scope)
+	}
+	decl, ok := it.types[t]
+	if !ok || decl.strct == nil {
+		panic(runtimeError("unsupported composite literal of type %s", t))
+	}
+	s := it.zero(t).(*Struct)
+	for i, e := range elements {
+		if kv, ok := e.(*ast.KeyValueExpression); ok {
+			name := it.selectorName(kv.Key)
+			idx, ok := s.field(name)
+			if !ok {
+				panic(runtimeError("unknown field %s in %s", name, t))
+			}
+			s.fields[idx] = elem(kv.Value, s.typ.types[idx])
+			continue
+		}
+		if i < len(s.fields) {
+			s.fields[i] = elem(e, s.typ.types[i])
+		}
+	}
+	return s
+}
+
+// nativeComposite builds a standard library struct such as sync.Pool{New: ...}.
+func (it *Interpreter) nativeComposite(rt reflect.Type, elements []ast.Expression, scope *env) any {
+	ptr := reflect.New(rt)
+	for _, e := range elements {
+		kv, ok := e.(*ast.KeyValueExpression)
+		if !ok {
+			panic(runtimeError("%s literals need field names", rt))
+		}
+		f := ptr.Elem().FieldByName(it.selectorName(kv.Key))
+		if !f.IsValid() || !f.CanSet() {
+			panic(runtimeError("unknown field %s in %s", it.selectorName(kv.Key), rt))
+		}
+		f.Set(it.toReflect(it.eval(kv.Value, scope), f.Type()))
+	}
+	if rt == reflect.TypeOf(time.Time{}) {
+		return ptr.Elem().Interface()
+	}
+	return ptr.Interface()
+}
+
+// evalArgs evaluates call arguments. spread reports whether the last argument was written as xs...
+func (it *Interpreter) evalArgs(exprs []ast.Expression, scope *env) (args []any, spread bool) {
+	for i, e := range exprs {
+		if ident, ok := e.(*ast.Identifier); ok && i == len(exprs)-1 && strings.HasSuffix(ident.Value, "...") {
+			args = append(args, unwrap(it.eval(it.spreadOperand(ident), scope)))
+			spread = true
+			continue
+		}
+		args = append(args, it.eval(e, scope))
+	}
+	if len(args) == 1 {
+		if tup, ok := args[0].(tuple); ok {
+			args = tup
+		}
+	}
+	return args, spread
+}
+
+// spreadOperand returns the expression before "..." in f(xs...). The parser keeps
+// it as an Identifier holding the expression's source, so anything more complex
+// than a name is parsed again.
+func (it *Interpreter) spreadOperand(ident *ast.Identifier) ast.Expression {
+	src := strings.TrimSuffix(ident.Value, "...")
+	if !strings.ContainsAny(src, "[]().") {
+		return &ast.Identifier{Token: ident.Token, Value: src}
+	}
+	if cached, ok := it.spreadCache.Load(src); ok {
+		return cached.(ast.Expression)
+	}
+	program, err := transpiler.Parse(src, it.dict)
+	if err != nil || len(program.Statements) != 1 {
+		panic(runtimeError("cannot evaluate %s", ident.Value))
+	}
+	es, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		panic(runtimeError("cannot evaluate %s", ident.Value))
+	}
+	it.spreadCache.Store(src, es.Expression)
+	return es.Expression
+}
+
+func (it *Interpreter) evalCall(call *ast.CallExpression, scope *env) any {
+	return it.prepareCall(call, scope, nil)()
+}
+
+// prepareCall evaluates the callee and arguments of call and returns a function
+// that performs the call, as chiong and tahan need. deferredFrom is the frame a
+// deferred call belongs to, so recover can find the panic it is unwinding.
+func (it *Interpreter) prepareCall(call *ast.CallExpression, scope *env, deferredFrom *frame) func() any {
+	// Builtins see their arguments unevaluated because make and new take types.
+	if ident, ok := call.Function.(*ast.Identifier); ok {
+		name := it.ident(ident.Value)
+		if _, shadowed := scope.lookup(name); !shadowed && builtins[name] {
+			if name == "recover" {
+				return func() any { return recoverValue(scope.frame) }
+			}
+			args := it.builtinArgs(name, call.Arguments, scope)
+			return func() any { return it.callBuiltin(name, args, scope) }
+		}
+	}
+
+	var recv any
+	var callee any
+	if sel, ok := call.Function.(*ast.InfixExpression); ok && sel.Operator == "." {
+		if _, isSend := sel.Right.(*ast.PrefixExpression); !isSend {
+			name := it.selectorName(sel.Right)
+			if left, ok := sel.Left.(*ast.Identifier); ok && it.isPackage(left.Value, scope) {
+				callee = it.member(left.Value, name)
+			} else {
+				recv = it.eval(sel.Left, scope)
+				callee = it.field(recv, name)
+				// A pointer method called on an addressable Named value needs its address.
+				if bm, ok := callee.(*boundMethod); ok && bm.fn.pointerReceiver() {
+					if _, named := recv.(Named); named {
+						bm.recv = it.addressOf(sel.Left, scope)
+					}
+				}
+			}
+		}
+	}
+	if callee == nil {
+		callee = it.eval(call.Function, scope)
+	}
+	args, spread := it.evalArgs(call.Arguments, scope)
+	return func() any { return it.callValue(callee, args, spread, deferredFrom) }
+}
+
+func (it *Interpreter) callValue(callee any, args []any, spread bool, deferredFrom *frame) any {
+	switch fn := callee.(type) {
+	case *Function:
+		return it.call(fn, nil, args, spread, deferredFrom)
+	case *boundMethod:
+		return it.call(fn.fn, fn.recv, args, spread, deferredFrom)
+	case *methodExpr:
+		if len(args) == 0 {
+			panic(runtimeError("not enough arguments in call to %s", fn.fn))
+		}
+		return it.call(fn.fn, copyValue(args[0]), args[1:], spread, deferredFrom)
+	case typeRef:
+		if len(args) != 1 {
+			panic(runtimeError("conversion to %s takes exactly one argument", fn))
+		}
+		return it.convert(args[0], string(fn))
+	case builtin:
+		return it.callPackageFunc(string(fn), args, spread)
+	case reflect.Value:
+		return it.callNative(fn, args, spread)
+	case nil:
+		panic(runtimeError("invalid memory address or nil pointer dereference"))
+	}
+	rv := reflect.ValueOf(callee)
+	if rv.Kind() == reflect.Func {
+		return it.callNative(rv, args, spread)
+	}
+	panic(runtimeError("cannot call non-action %v (%s)", callee, typeName(callee)))
+}
+
+// callNative calls a standard library function or method through reflection.
+func (it *Interpreter) callNative(fv reflect.Value, args []any, spread bool) any {
+	ft := fv.Type()
+	n := ft.NumIn()
+	if (!ft.IsVariadic() && len(args) != n) || (ft.IsVariadic() && len(args) < n-1) {
+		panic(runtimeError("wrong number of arguments: have %d, want %d", len(args), n))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		var t reflect.Type
+		switch {
+		case ft.IsVariadic() && i >= n-1 && spread:
+			t = ft.In(n - 1)
+		case ft.IsVariadic() && i >= n-1:
+			t = ft.In(n - 1).Elem()
+		default:
+			t = ft.In(i)
+		}
+		in[i] = it.toReflect(a, t)
+	}
+	var out []reflect.Value
+	if spread && ft.IsVariadic() {
+		out = fv.CallSlice(in)
+	} else {
+		out = fv.Call(in)
+	}
+	// Slices were converted to fresh Go slices; copy back what the callee wrote,
+	// e.g. the buffer passed to Read.
+	for i, a := range args {
+		if items, ok := a.([]any); ok && in[i].Kind() == reflect.Slice {
+			for j := 0; j < len(items) && j < in[i].Len(); j++ {
+				items[j] = fromReflect(in[i].Index(j))
+			}
+		}
+	}
+	switch len(out) {
+	case 0:
+		return nil
+	case 1:
+		return fromReflect(out[0])
+	}
+	res := make(tuple, len(out))
+	for i, o := range out {
+		res[i] = fromReflect(o)
+	}
+	return res
+}
+
+// callPackageFunc handles package functions that need the interpreter's help:
+// printing to Interpreter.Stdout, sorting []any in place, atomics and os.Exit.
+func (it *Interpreter) callPackageFunc(name string, args []any, spread bool) any {
+	if spread && len(args) > 0 {
+		if rest, ok := args[len(args)-1].([]any); ok {
+			args = append(args[:len(args)-1:len(args)-1], rest...)
+		}
+	}
+	switch name {
+	case "fmt.Println":
+		fmt.Fprintln(it.Stdout, args...)
+		return nil
+	case "fmt.Print":
+		fmt.Fprint(it.Stdout, args...)
+		return nil
+	case "fmt.Printf":
+		format, rest := it.format(args[0], args[1:])
+		fmt.Fprintf(it.Stdout, format, rest...)
+		return nil
+	case "fmt.Sprintf":
+		format, rest := it.format(args[0], args[1:])
+		return fmt.Sprintf(format, rest...)
+	case "fmt.Errorf":
+		format, rest := it.format(args[0], args[1:])
+		return fmt.Errorf(format, rest...)
+	case "fmt.Fprintln", "fmt.Fprint", "fmt.Fprintf":
+		w := it.writer(args[0])
+		switch name {
+		case "fmt.Fprintln":
+			fmt.Fprintln(w, args[1:]...)
+		case "fmt.Fprint":
+			fmt.Fprint(w, args[1:]...)
+		default:
+			format, rest := it.format(args[1], args[2:])
+			fmt.Fprintf(w, format, rest...)
+		}
+		return nil
+	case "os.Exit":
+		panic(exitSignal{code: it.toInt(args[0])})
+	case "os.Args":
+		return it.osArgs()
+	case "sort.Ints", "sort.Strings", "sort.Float64s":
+		list, _ := args[0].([]any)
+		it.sortSlice(list, func(i, j int) bool { return it.less(list[i], list[j]) })
+		return nil
+	case "sort.Slice", "sort.SliceStable":
+		list, _ := args[0].([]any)
+		less := args[1]
+		it.sortSlice(list, func(i, j int) bool {
+			return it.truthy(it.callValue(less, []any{i, j}, false, nil))
+		})
+		return nil
+	case "atomic.AddInt32", "atomic.AddInt64", "atomic.AddUint32", "atomic.AddUint64":
+		it.atomicMu.Lock()
+		defer it.atomicMu.Unlock()
+		c := it.atomicCell(args[0])
+		c.value = it.toInt(c.value) + it.toInt(args[1])
+		return c.value
+	case "atomic.LoadInt32", "atomic.LoadInt64", "atomic.LoadUint32", "atomic.LoadUint64":
+		it.atomicMu.Lock()
+		defer it.atomicMu.Unlock()
+		return it.atomicCell(args[0]).value
+	case "atomic.StoreInt32", "atomic.StoreInt64", "atomic.StoreUint32", "atomic.StoreUint64":
+		it.atomicMu.Lock()
+		defer it.atomicMu.Unlock()
+		it.atomicCell(args[0]).value = it.toInt(args[1])
+		return nil
+	}
+	panic(runtimeError("%s is not supported by the interpreter; run without --interp", name))
+}
+
+func (it *Interpreter) writer(v any) io.Writer {
+	switch v {
+	case os.Stdout:
+		return it.Stdout
+	case os.Stderr:
+		return it.Stderr
+	}
+	if p, ok := v.(*Pointer); ok && p.Cell != nil {
+		v = p.Cell.value
+	}
+	if w, ok := v.(io.Writer); ok {
+		return w
+	}
+	panic(runtimeError("%s is not an io.Writer", typeName(v)))
+}
+
+func (it *Interpreter) atomicCell(v any) *cell {
+	if p, ok := v.(*Pointer); ok && p.Cell != nil {
+		return p.Cell
+	}
+	panic(runtimeError("atomic operations need a pointer to a variable, got %s", typeName(v)))
+}
+
+func (it *Interpreter) osArgs() []any {
+	out := make([]any, len(it.Args))
+	for i, a := range it.Args {
+		out[i] = a
+	}
+	return out
+}
@@ -0,0 +1,525 @@
+package interp
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+)
+
+func (it *Interpreter) execBlock(stmts []ast.Statement, scope *env) control {
+	for _, stmt := range stmts {
+		if c := it.exec(stmt, scope); c != ctrlNone {
+			return c
+		}
+	}
+	return ctrlNone
+}
+
+func (it *Interpreter) exec(stmt ast.Statement, scope *env) control {
+	switch st := stmt.(type) {
+	case nil:
+		return ctrlNone
+	case *ast.LetStatement:
+		it.execLet(st, scope)
+	case *ast.ExpressionStatement:
+		return it.execExpression(st.Expression, scope)
+	case *ast.IncDecStatement:
+		it.execIncDec(st, scope)
+	case *ast.ReturnStatement:
+		fr := scope.frame
+		fr.results = fr.results[:0]
+		for _, e := range st.ReturnValues {
+			v := it.eval(e, scope)
+			if tup, ok := v.(tuple); ok && len(st.ReturnValues) == 1 {
+				fr.results = append(fr.results, tup...)
+				continue
+			}
+			fr.results = append(fr.results, copyValue(v))
+		}
+		return ctrlReturn
+	case *ast.BlockStatement:
+		return it.execBlock(st.Statements, newEnv(scope))
+	case *ast.IfStatement:
+		return it.execIf(st, scope)
+	case *ast.ForStatement:
+		return it.execFor(st, scope)
+	case *ast.SwitchStatement:
+		return it.execSwitch(st, scope)
+	case *ast.SelectStatement:
+		return it.execSelect(st, scope)
+	case *ast.GoStatement:
+		call := it.prepareCall(st.Call, scope, nil)
+		it.goroutine(func() { call() })
+	case *ast.DeferStatement:
+		fr := scope.frame
+		call := it.prepareCall(st.Call, scope, fr)
+		fr.defers = append(fr.defers, func() { call() })
+	case *ast.TypeStatement:
+		it.declareType(st)
+	case *ast.FunctionStatement:
+		fn := it.newFunction(st, scope)
+		scope.define(fn.name, fn)
+	case *ast.PackageStatement, *ast.ImportStatement:
+	default:
+		panic(runtimeError("unsupported statement %s", stmt.String()))
+	}
+	return ctrlNone
+}
+
+func (it *Interpreter) execExpression(expr ast.Expression, scope *env) control {
+	switch e := expr.(type) {
+	case nil:
+		return ctrlNone
+	case *ast.Identifier:
+		switch it.ident(e.Value) {
+		case "break":
+			return ctrlBreak
+		case "continue":
+			return ctrlContinue
+		case "fallthrough":
+			return ctrlFallthrough
+		}
+	case *ast.IncDecStatement:
+		it.execIncDec(e, scope)
+		return ctrlNone
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "=", ":=":
+			it.assign(e.Left, it.eval(e.Right, scope), scope, e.Operator == ":=")
+			return ctrlNone
+		case "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<=", ">>=":
+			op := strings.TrimSuffix(e.Operator, "=")
+			cur := it.eval(e.Left, scope)
+			it.assign(e.Left, it.binary(op, cur, it.eval(e.Right, scope)), scope, false)
+			return ctrlNone
+		case "<-":
+			it.send(it.eval(e.Left, scope), it.eval(e.Right, scope))
+			return ctrlNone
+		}
+	}
+	it.eval(expr, scope)
+	return ctrlNone
+}
+
+func (it *Interpreter) execLet(st *ast.LetStatement, scope *env) {
+	t := ""
+	if st.Type != nil {
+		t = it.typeString(st.Type)
+	}
+	if st.Value == nil {
+		for _, n := range st.Names {
+			scope.define(n.Value, it.zero(t))
+		}
+		return
+	}
+
+	letScope := scope
+	if it.ident(st.Token.Value) == "const" {
+		letScope = newEnv(scope)
+		letScope.define("iota", 0)
+	}
+	var v any
+	if len(st.Names) == 2 {
+		v = it.evalCommaOk(st.Value, letScope)
+	} else {
+		v = it.eval(st.Value, letScope)
+	}
+	values := []any{v}
+	if tup, ok := v.(tuple); ok {
+		values = tup
+	}
+	if len(values) != len(st.Names) {
+		panic(runtimeError("assignment mismatch: %d variables but %d values", len(st.Names), len(values)))
+	}
+	for i, n := range st.Names {
+		scope.define(n.Value, it.convertForAssign(copyValue(values[i]), t))
+	}
+}
+
+// evalCommaOk evaluates the two-value forms v, ok = m[k], v, ok = <-ch and
+// v, ok = x.(T). Any other expression is evaluated normally.
+func (it *Interpreter) evalCommaOk(expr ast.Expression, scope *env) any {
+	switch e := expr.(type) {
+	case *ast.IndexExpression:
+		if m, ok := unwrap(it.eval(e.Left, scope)).(*Map); ok {
+			v, found := m.get(it.eval(e.Index, scope))
+			return tuple{v, found}
+		}
+	case *ast.PrefixExpression:
+		if e.Operator == "<-" {
+			v, ok := it.receive(it.eval(e.Right, scope))
+			return tuple{v, ok}
+		}
+	case *ast.TypeAssertionExpression:
+		v := it.eval(e.Left, scope)
+		t := it.typeString(e.Type)
+		if it.matchesType(v, t) {
+			return tuple{v, true}
+		}
+		return tuple{it.zero(t), false}
+	}
+	return it.eval(expr, scope)
+}
+
+func (it *Interpreter) execIncDec(st *ast.IncDecStatement, scope *env) {
+	op := "+"
+	if st.Operator == "--" {
+		op = "-"
+	}
+	it.assign(st.Left, it.binary(op, it.eval(st.Left, scope), 1), scope, false)
+}
+
+func (it *Interpreter) execIf(st *ast.IfStatement, scope *env) control {
+	if it.truthy(it.eval(st.Condition, scope)) {
+		return it.execBlock(st.Consequence.Statements, newEnv(scope))
+	}
+	if st.AlternativeStmt != nil {
+		return it.exec(st.AlternativeStmt, scope)
+	}
+	if st.Alternative != nil {
+		return it.execBlock(st.Alternative.Statements, newEnv(scope))
+	}
+	return ctrlNone
+}
+
+// loopBody runs one iteration and reports whether the loop should stop, plus
+// the control value to propagate when it stops because of balek.
+func (it *Interpreter) loopBody(body *ast.BlockStatement, scope *env) (stop bool, c control) {
+	switch c := it.execBlock(body.Statements, scope); c {
+	case ctrlBreak:
+		return true, ctrlNone
+	case ctrlReturn:
+		return true, ctrlReturn
+	}
+	return false, ctrlNone
+}
+
+func (it *Interpreter) execFor(st *ast.ForStatement, scope *env) control {
+	if st.IsRange {
+		return it.execRange(st, scope)
+	}
+
+	// Like Go 1.22+, every iteration gets fresh copies of the loop variables so
+	// closures started inside the body capture that iteration's values.
+	cur := newEnv(scope)
+	if st.Init != nil {
+		it.exec(st.Init, cur)
+	}
+	for {
+		if st.Condition != nil && !it.truthy(it.eval(st.Condition, cur)) {
+			return ctrlNone
+		}
+		if stop, c := it.loopBody(st.Body, newEnv(cur)); stop {
+			return c
+		}
+		next := newEnv(scope)
+		cur.mu.RLock()
+		for name, c := range cur.vars {
+			next.vars[name] = &cell{value: c.value}
+		}
+		cur.mu.RUnlock()
+		cur = next
+		if st.Post != nil {
+			it.exec(st.Post, cur)
+		}
+	}
+}
+
+func (it *Interpreter) execRange(st *ast.ForStatement, scope *env) control {
+	iteration := func(key, value any) (bool, control) {
+		iter := newEnv(scope)
+		if st.Key != nil {
+			iter.define(st.Key.Value, key)
+		}
+		if st.Value != nil {
+			iter.define(st.Value.Value, copyValue(value))
+		}
+		return it.loopBody(st.Body, iter)
+	}
+
+	switch coll := unwrap(it.eval(st.Iterable, scope)).(type) {
+	case nil:
+	case int:
+		for i := 0; i < coll; i++ {
+			if stop, c := iteration(i, nil); stop {
+				return c
+			}
+		}
+	case []any:
+		for i, v := range coll {
+			if stop, c := iteration(i, v); stop {
+				return c
+			}
+		}
+	case string:
+		for i, r := range coll {
+			if stop, c := iteration(i, r); stop {
+				return c
+			}
+		}
+	case *Map:
+		for k, v := range coll.m {
+			if stop, c := iteration(k, v); stop {
+				return c
+			}
+		}
+	case chan any:
+		for v := range coll {
+			if stop, c := iteration(v, nil); stop {
+				return c
+			}
+		}
+	default:
+		rv := reflect.ValueOf(coll)
+		if rv.Kind() != reflect.Chan {
+			panic(runtimeError("cannot range over %v (%s)", coll, typeName(coll)))
+		}
+		for {
+			v, ok := rv.Recv()
+			if !ok {
+				return ctrlNone
+			}
+			if stop, c := iteration(fromReflect(v), nil); stop {
+				return c
+			}
+		}
+	}
+	return ctrlNone
+}
+
+func (it *Interpreter) execSwitch(st *ast.SwitchStatement, scope *env) control {
+	outer := newEnv(scope)
+
+	// Type switch: see_how v.(type) or see_how x := v.(type)
+	var bind string
+	assertion, _ := st.Expression.(*ast.TypeAssertionExpression)
+	if infix, ok := st.Expression.(*ast.InfixExpression); ok && infix.Operator == ":=" {
+		if ta, ok := infix.Right.(*ast.TypeAssertionExpression); ok {
+			assertion = ta
+			if ident, ok := infix.Left.(*ast.Identifier); ok {
+				bind = ident.Value
+			}
+		}
+	}
+	isTypeSwitch := assertion != nil && it.typeString(assertion.Type) == "type"
+
+	var subject any
+	switch {
+	case isTypeSwitch:
+		subject = it.eval(assertion.Left, outer)
+	case st.Expression != nil:
+		subject = it.eval(st.Expression, outer)
+	default:
+		subject = true
+	}
+
+	matched := -1
+	for i, c := range st.Cases {
+		if c.Default {
+			continue
+		}
+		for _, e := range c.Expressions {
+			if isTypeSwitch {
+				if it.matchesType(subject, it.typeString(e)) {
+					matched = i
+				}
+			} else if it.equal(subject, it.eval(e, outer)) {
+				matched = i
+			}
+			if matched >= 0 {
+				break
+			}
+		}
+		if matched >= 0 {
+			break
+		}
+	}
+	if matched < 0 {
+		for i, c := range st.Cases {
+			if c.Default {
+				matched = i
+			}
+		}
+	}
+	if matched < 0 {
+		return ctrlNone
+	}
+
+	for i := matched; i < len(st.Cases); i++ {
+		body := newEnv(outer)
+		if bind != "" {
+			body.define(bind, subject)
+		}
+		switch c := it.execBlock(st.Cases[i].Body.Statements, body); c {
+		case ctrlFallthrough:
+			continue
+		case ctrlBreak:
+			return ctrlNone
+		default:
+			return c
+		}
+	}
+	return ctrlNone
+}
+
+func (it *Interpreter) execSelect(st *ast.SelectStatement, scope *env) control {
+	cases := make([]reflect.SelectCase, len(st.Cases))
+	binds := make([]*ast.InfixExpression, len(st.Cases))
+	for i, c := range st.Cases {
+		if c.Default {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectDefault}
+			continue
+		}
+		es, ok := c.Comm.(*ast.ExpressionStatement)
+		if !ok {
+			panic(runtimeError("unsupported select case %s", c.Comm.String()))
+		}
+		switch e := es.Expression.(type) {
+		case *ast.PrefixExpression:
+			if e.Operator == "<-" {
+				cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: it.chanValue(it.eval(e.Right, scope))}
+				continue
+			}
+		case *ast.InfixExpression:
+			if e.Operator == "<-" {
+				ch := it.chanValue(it.eval(e.Left, scope))
+				cases[i] = reflect.SelectCase{Dir: reflect.SelectSend, Chan: ch, Send: it.toReflect(it.eval(e.Right, scope), ch.Type().Elem())}
+				continue
+			}
+			if recv, ok := e.Right.(*ast.PrefixExpression); ok && recv.Operator == "<-" && (e.Operator == ":=" || e.Operator == "=") {
+				cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: it.chanValue(it.eval(recv.Right, scope))}
+				binds[i] = e
+				continue
+			}
+		}
+		panic(runtimeError("unsupported select case %s", es.String()))
+	}
+
+	chosen, recv, _ := reflect.Select(cases)
+	body := newEnv(scope)
+	if b := binds[chosen]; b != nil {
+		it.assign(b.Left, fromReflect(recv), body, b.Operator == ":=")
+	}
+	if st.Cases[chosen].Body == nil {
+		return ctrlNone
+	}
+	if c := it.execBlock(st.Cases[chosen].Body.Statements, body); c != ctrlBreak {
+		return c
+	}
+	return ctrlNone
+}
+
+// chanValue returns v as a reflect.Value of kind chan, treating nil as a channel that never fires.
+func (it *Interpreter) chanValue(v any) reflect.Value {
+	if v == nil {
+		return reflect.ValueOf((chan any)(nil))
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Chan {
+		panic(runtimeError("%v (%s) is not a lobang", v, typeName(v)))
+	}
+	return rv
+}
+
+func (it *Interpreter) send(ch, v any) {
+	rv := it.chanValue(ch)
+	rv.Send(it.toReflect(v, rv.Type().Elem()))
+}
+
+func (it *Interpreter) receive(ch any) (any, bool) {
+	v, ok := it.chanValue(ch).Recv()
+	if !ok {
+		return nil, false
+	}
+	return fromReflect(v), true
+}
+
+// assign stores v into the location described by target. With define set,
+// identifiers are declared in scope instead of being looked up.
+func (it *Interpreter) assign(target ast.Expression, v any, scope *env, define bool) {
+	v = copyValue(v)
+	switch t := target.(type) {
+	case *ast.Identifier:
+		if t.Value == "_" {
+			return
+		}
+		if define {
+			scope.define(t.Value, v)
+			return
+		}
+		c, ok := scope.lookup(it.ident(t.Value))
+		if !ok {
+			panic(runtimeError("undefined: %s", t.Value))
+		}
+		c.value = it.convertLike(v, c.value)
+		return
+	case *ast.IndexExpression:
+		container := unwrap(it.eval(t.Left, scope))
+		index := it.eval(t.Index, scope)
+		switch c := container.(type) {
+		case []any:
+			i := it.toInt(index)
+			c[i] = it.convertLike(v, c[i])
+		case *Map:
+			if c.m == nil {
+				panic(runtimeError("assignment to entry in nil map"))
+			}
+			c.m[c.keyOf(index)] = it.convertForAssign(v, c.elem)
+		case *Pointer:
+			if arr, ok := c.load().([]any); ok {
+				i := it.toInt(index)
+				arr[i] = it.convertLike(v, arr[i])
+				return
+			}
+			panic(runtimeError("cannot index %s", typeName(container)))
+		default:
+			panic(runtimeError("cannot index %s", typeName(container)))
+		}
+		return
+	case *ast.InfixExpression:
+		if t.Operator != "." {
+			break
+		}
+		s := it.structOf(it.eval(t.Left, scope))
+		name := it.selectorName(t.Right)
+		i, ok := s.field(name)
+		if !ok {
+			panic(runtimeError("%s has no field %s", s.typ.name, name))
+		}
+		s.fields[i] = it.convertForAssign(v, s.typ.types[i])
+		return
+	case *ast.PrefixExpression:
+		if t.Operator != "*" {
+			break
+		}
+		p, ok := it.eval(t.Right, scope).(*Pointer)
+		if !ok || p == nil {
+			panic(runtimeError("invalid memory address or nil pointer dereference"))
+		}
+		if p.Struct != nil {
+			src := it.structOf(v)
+			p.Struct.fields = src.copy().fields
+			return
+		}
+		p.Cell.value = it.convertLike(v, p.Cell.value)
+		return
+	}
+	panic(runtimeError("cannot assign to %s", target.String()))
+}
+
+// structOf returns the struct behind v, following pointers.
+func (it *Interpreter) structOf(v any) *Struct {
+	switch s := v.(type) {
+	case *Struct:
+		return s
+	case *Pointer:
+		if s != nil && s.Struct != nil {
+			return s.Struct
+		}
+	}
+	if isNil(v) {
+		panic(runtimeError("invalid memory address or nil pointer dereference"))
+	}
+	panic(runtimeError("%v (%s) is not a barang", v, typeName(v)))
+}
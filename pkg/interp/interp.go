@@ -0,0 +1,528 @@
+// Package interp runs Singlish programs by walking the AST directly, without
+// generating Go code or invoking the Go toolchain. It trades completeness for
+// start-up time: programs that only use the standard library members listed in
+// stdlib.go run instantly. A program that imports any other package is
+// rejected before it starts, but code the interpreter cannot run, such as a
+// call to an unlisted member of a supported package, stops the program with a
+// RuntimeError only when it is reached, after whatever it printed before.
+package interp
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+)
+
+// Interpreter executes a parsed Singlish program.
+type Interpreter struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Args   []string // os.Args as seen by the program; Args[0] is the program name
+
+	dict     *dictionaries.Dictionary
+	globals  *env
+	session  *env              // the top level of boss for Exec and Eval
+	packages map[string]string // local package name -> import path
+	types    map[string]*typeDecl
+
+	atomicMu    sync.Mutex
+	spreadCache sync.Map // source of xs in f(xs...) -> parsed expression
+	exit        func(code int)
+}
+
+// typeDecl is a type declared with pattern.
+type typeDecl struct {
+	name       string
+	underlying string // for named non-struct types, e.g. "int"
+	strct      *structType
+	iface      *ast.InterfaceLiteral
+	methods    map[string]*Function // methods of named non-struct types
+	alias      bool                 // declared with =, so values have the underlying type
+	it         *Interpreter
+}
+
+// ExitError is returned by Run when the program calls os.Exit.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// PanicError is returned by Run when a panic escapes boss.
+type PanicError struct {
+	Value any
+}
+
+func (e *PanicError) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return "panic: " + err.Error()
+	}
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// RuntimeError reports a program the interpreter cannot execute, e.g. a call into an
+// unsupported package or a type mismatch Go would have rejected at compile time.
+type RuntimeError struct {
+	Message string
+}
+
+func (e *RuntimeError) Error() string {
+	return "runtime error: " + e.Message
+}
+
+func runtimeError(format string, args ...any) *RuntimeError {
+	return &RuntimeError{Message: fmt.Sprintf(format, args...)}
+}
+
+// userPanic wraps a value passed to panic so it can be told apart from interpreter failures.
+type userPanic struct {
+	value any
+}
+
+type exitSignal struct {
+	code int
+}
+
+// control tells enclosing statements how a statement finished.
+type control int
+
+const (
+	ctrlNone control = iota
+	ctrlBreak
+	ctrlContinue
+	ctrlReturn
+	ctrlFallthrough
+)
+
+// frame holds the per-call state needed for balek, defer and recover.
+type frame struct {
+	results      []any
+	defers       []func()
+	panic        *panicState
+	deferredFrom *frame // set when this call is a deferred call of another frame
+}
+
+type panicState struct {
+	value     any
+	recovered bool
+}
+
+// New creates an interpreter that writes to the process's stdout and stderr.
+func New(dict *dictionaries.Dictionary) *Interpreter {
+	return &Interpreter{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Args:   os.Args,
+		dict:   dict,
+		exit:   os.Exit,
+	}
+}
+
+// Run executes program's init actions and then boss.
+func (it *Interpreter) Run(program *ast.Program) (err error) {
+	it.reset()
+	if err := it.declare(program); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = it.toError(r)
+		}
+	}()
+
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			it.execLet(let, it.globals)
+		}
+	}
+	for _, stmt := range program.Statements {
+		if fn, ok := stmt.(*ast.FunctionStatement); ok && fn.Receiver == nil && it.ident(fn.Name.Value) == "init" {
+			it.callFunction(it.newFunction(fn, it.globals), nil, nil)
+		}
+	}
+
+	c, ok := it.globals.lookup("main")
+	if !ok {
+		return fmt.Errorf("no %s action to run", it.keyword("main"))
+	}
+	fn, ok := c.value.(*Function)
+	if !ok {
+		return fmt.Errorf("%s is not an action", it.keyword("main"))
+	}
+	it.callFunction(fn, nil, nil)
+	return nil
+}
+
+// reset forgets everything declared, for a fresh run.
+func (it *Interpreter) reset() {
+	it.globals = newEnv(nil)
+	it.globals.frame = &frame{}
+	it.session = nil
+	// Standard packages are imported when they are used, as in compiled
+	// programs, and qualified aliases such as gong work without dapao, so
+	// every package the interpreter supports is always available. An
+	// explicit dapao still decides between packages that share a name.
+	it.packages = make(map[string]string)
+	for _, path := range slices.Sorted(maps.Keys(stdlib)) {
+		if name := packageName(path); it.packages[name] == "" {
+			it.packages[name] = path
+		}
+	}
+	it.packages["atomic"] = "sync/atomic"
+	it.types = make(map[string]*typeDecl)
+}
+
+// Exec runs program as the next piece of a session that lasts across
+// calls, as the REPL does. Its imports, patterns and actions are added to
+// those of earlier calls, and its other statements run as if at the top of
+// boss, in a scope that keeps the variables of earlier calls. Nothing is
+// run again, so each statement takes effect once.
+func (it *Interpreter) Exec(program *ast.Program) (err error) {
+	if it.session == nil {
+		it.reset()
+		it.session = newEnv(it.globals)
+	}
+	if err := it.declare(program); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = it.toError(r)
+		}
+	}()
+	for _, stmt := range program.Statements {
+		switch stmt.(type) {
+		case *ast.FunctionStatement, *ast.TypeStatement:
+			// Declared above.
+		default:
+			it.exec(stmt, it.session)
+		}
+	}
+	return nil
+}
+
+// Eval evaluates expr in the session of Exec and returns its values: one
+// for most expressions, and one for each result of a call that returns
+// several.
+func (it *Interpreter) Eval(expr ast.Expression) (values []any, err error) {
+	if it.session == nil {
+		it.reset()
+		it.session = newEnv(it.globals)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = it.toError(r)
+		}
+	}()
+	v := it.eval(expr, it.session)
+	if tup, ok := v.(tuple); ok {
+		return tup, nil
+	}
+	return []any{v}, nil
+}
+
+// declare registers imports, types, actions and methods before anything runs,
+// so declarations may appear in any order like they can in Go.
+func (it *Interpreter) declare(program *ast.Program) error {
+	for _, stmt := range program.Statements {
+		switch st := stmt.(type) {
+		case *ast.ImportStatement:
+			path, err := strconv.Unquote(st.Path.Token.Value)
+			if err != nil {
+				path = strings.Trim(st.Path.Value, "\"`")
+			}
+			if !supportedPackage(path) {
+				return fmt.Errorf("line %d: package %q is not supported by the interpreter; run without --interp", st.Token.Line, path)
+			}
+			it.packages[packageName(path)] = path
+		case *ast.TypeStatement:
+			it.declareType(st)
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		st, ok := stmt.(*ast.FunctionStatement)
+		if !ok {
+			continue
+		}
+		fn := it.newFunction(st, it.globals)
+		if st.Receiver == nil {
+			it.globals.define(fn.name, fn)
+			continue
+		}
+		recvType := strings.TrimPrefix(it.typeString(st.Receiver.Type), "*")
+		decl, ok := it.types[recvType]
+		switch {
+		case !ok || decl.iface != nil:
+			return fmt.Errorf("line %d: cannot define methods on %s", st.Token.Line, recvType)
+		case decl.strct != nil:
+			decl.strct.methods[fn.name] = fn
+		default:
+			decl.methods[fn.name] = fn
+		}
+	}
+	return nil
+}
+
+func supportedPackage(path string) bool {
+	if _, ok := stdlib[path]; ok {
+		return true
+	}
+	return path == "sync/atomic"
+}
+
+func (it *Interpreter) declareType(st *ast.TypeStatement) {
+	decl := &typeDecl{name: st.Name.Value, methods: make(map[string]*Function), it: it}
+	switch v := st.Value.(type) {
+	case *ast.StructLiteral:
+		decl.strct = &structType{it: it, name: decl.name, methods: make(map[string]*Function)}
+		for _, f := range v.Fields {
+			if f.Name == nil {
+				continue
+			}
+			decl.strct.names = append(decl.strct.names, f.Name.Value)
+			decl.strct.types = append(decl.strct.types, it.typeString(f.Type))
+		}
+	case *ast.InterfaceLiteral:
+		decl.iface = v
+	default:
+		decl.underlying = it.typeString(st.Value)
+		decl.alias = st.IsAlias
+		if alias, ok := it.types[decl.underlying]; ok {
+			decl.strct, decl.iface, decl.underlying = alias.strct, alias.iface, alias.underlying
+			if st.IsAlias {
+				decl.methods = alias.methods
+			}
+		}
+	}
+	it.types[decl.name] = decl
+}
+
+// keepsName reports whether values of a named non-struct type are kept as
+// Named: those with methods, so that the methods can be found, and those of
+// a basic type, so that %T names them. Others are represented by their
+// underlying value.
+func (d *typeDecl) keepsName() bool {
+	if d.strct != nil || d.iface != nil {
+		return false
+	}
+	u := d.underlying
+	basic := intTypes[u] || floatTypes[u] || u == "string" || u == "bool"
+	return len(d.methods) > 0 || basic && !d.alias
+}
+
+func (it *Interpreter) newFunction(st *ast.FunctionStatement, closure *env) *Function {
+	return &Function{
+		name:     it.ident(st.Name.Value),
+		params:   st.Parameters,
+		results:  it.typeString(st.ReturnType),
+		body:     st.Body,
+		closure:  closure,
+		receiver: st.Receiver,
+	}
+}
+
+// ident translates a name the way codegen does, so the interpreter and the
+// generated Go program agree on what every identifier refers to.
+func (it *Interpreter) ident(name string) string {
+	if it.dict != nil {
+		if translated, ok := it.dict.Lookup(name); ok {
+			return translated
+		}
+	}
+	return name
+}
+
+// keyword returns the Singlish spelling of a Go keyword, for error messages.
+func (it *Interpreter) keyword(goKeyword string) string {
+	if it.dict != nil {
+		if kw, ok := it.dict.ReverseLookup(goKeyword); ok {
+			return kw
+		}
+	}
+	return goKeyword
+}
+
+// toError converts a recovered panic into the error returned by Run.
+func (it *Interpreter) toError(r any) error {
+	switch v := r.(type) {
+	case exitSignal:
+		return &ExitError{Code: v.code}
+	case userPanic:
+		return &PanicError{Value: v.value}
+	case *RuntimeError:
+		return v
+	case error:
+		return &PanicError{Value: v}
+	}
+	return &PanicError{Value: r}
+}
+
+// callFunction calls a user-defined action with recv bound to its receiver, if any.
+func (it *Interpreter) callFunction(fn *Function, recv any, args []any) any {
+	return it.call(fn, recv, args, false, nil)
+}
+
+func (it *Interpreter) call(fn *Function, recv any, args []any, spread bool, deferredFrom *frame) any {
+	fr := &frame{deferredFrom: deferredFrom}
+	scope := newEnv(fn.closure)
+	scope.frame = fr
+	if fn.receiver != nil && fn.receiver.Name != nil {
+		scope.define(fn.receiver.Name.Value, recv)
+	}
+	it.bindParams(scope, fn, args, spread)
+
+	func() {
+		defer it.runDefers(fr)
+		it.execBlock(fn.body.Statements, scope)
+	}()
+
+	switch len(fr.results) {
+	case 0:
+		return nil
+	case 1:
+		return it.convertForAssign(fr.results[0], fn.results)
+	}
+	types := splitTypeList(fn.results)
+	out := make(tuple, len(fr.results))
+	for i, v := range fr.results {
+		if i < len(types) {
+			v = it.convertForAssign(v, types[i])
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func (it *Interpreter) bindParams(scope *env, fn *Function, args []any, spread bool) {
+	if len(args) == 1 && len(fn.params) > 1 {
+		if tup, ok := args[0].(tuple); ok {
+			args = tup
+		}
+	}
+	for i, p := range fn.params {
+		t := it.typeString(p.Type)
+		var v any
+		switch {
+		case strings.HasPrefix(t, "..."):
+			if spread && i < len(args) {
+				v = args[i]
+			} else if i < len(args) {
+				v = append([]any{}, args[i:]...)
+			} else {
+				v = []any(nil)
+			}
+		case i < len(args):
+			v = it.convertForAssign(copyValue(args[i]), t)
+		default:
+			panic(runtimeError("not enough arguments in call to %s", fn))
+		}
+		if p.Name != nil {
+			scope.define(p.Name.Value, v)
+		}
+	}
+}
+
+// runDefers runs the deferred calls of fr in reverse order. A panic that is still
+// unrecovered afterwards continues unwinding into the caller.
+func (it *Interpreter) runDefers(fr *frame) {
+	if r := recover(); r != nil {
+		if _, ok := r.(exitSignal); ok {
+			panic(r)
+		}
+		fr.panic = &panicState{value: r}
+	}
+	for i := len(fr.defers) - 1; i >= 0; i-- {
+		it.runDeferred(fr, fr.defers[i])
+	}
+	if fr.panic != nil && !fr.panic.recovered {
+		panic(fr.panic.value)
+	}
+}
+
+func (it *Interpreter) runDeferred(fr *frame, deferred func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(exitSignal); ok {
+				panic(r)
+			}
+			// A panic inside a deferred call replaces the one being unwound.
+			fr.panic = &panicState{value: r}
+		}
+	}()
+	deferred()
+}
+
+// recoverValue implements the recover builtin for the frame a deferred call runs in.
+func recoverValue(fr *frame) any {
+	if fr == nil || fr.deferredFrom == nil {
+		return nil
+	}
+	p := fr.deferredFrom.panic
+	if p == nil || p.recovered {
+		return nil
+	}
+	p.recovered = true
+	if up, ok := p.value.(userPanic); ok {
+		return up.value
+	}
+	return p.value
+}
+
+// goroutine runs f on a new goroutine. Like Go, an unrecovered panic there ends the program.
+func (it *Interpreter) goroutine(f func()) {
+	go func() {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			err := it.toError(r)
+			if exit, ok := err.(*ExitError); ok {
+				it.exit(exit.Code)
+				return
+			}
+			fmt.Fprintln(it.Stderr, err)
+			it.exit(2)
+		}()
+		f()
+	}()
+}
+
+// splitTypeList splits a result list such as "(int, error)" into its types.
+func splitTypeList(t string) []string {
+	t = strings.TrimSpace(t)
+	if !strings.HasPrefix(t, "(") || !strings.HasSuffix(t, ")") {
+		if t == "" {
+			return nil
+		}
+		return []string{t}
+	}
+	var out []string
+	depth, start := 0, 1
+	for i := 1; i < len(t)-1; i++ {
+		switch t[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(t[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(t[start:len(t)-1]))
+}
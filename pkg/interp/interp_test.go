@@ -0,0 +1,364 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/transpiler"
+
+	"os"
+)
+
+// runSource interprets a Singlish program and returns what it printed.
+func runSource(t *testing.T, source string) (string, error) {
+	t.Helper()
+	dict := dictionaries.NewDefaultDictionary()
+	program, err := transpiler.Parse(source, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var out bytes.Buffer
+	it := New(dict)
+	it.Stdout = &out
+	it.Stderr = &out
+	err = it.Run(program)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"hello",
+			"kampung main\n\naction boss() {\n\tgong(\"hello\", 1+2)\n}\n",
+			"hello 3\n",
+		},
+		{
+			"loops and slices",
+			`kampung main
+action boss() {
+	got nums = []nombor{3, 1, 2}
+	got total = 0
+	loop _, n = all nums {
+		total += n
+	}
+	loop i := 0; i < 2; i++ {
+		nums = append(nums, i)
+	}
+	gong(total, count(nums), nums)
+}
+`,
+			"6 5 [3 1 2 0 1]\n",
+		},
+		{
+			"maps default to zero",
+			`kampung main
+action boss() {
+	got tally = buat(menu[tar]nombor)
+	got words = []tar{"a", "b", "a"}
+	loop _, w = all words {
+		tally[w]++
+	}
+	got v, ok = tally["c"]
+	gong(tally["a"], tally["b"], v, ok)
+}
+`,
+			"2 1 0 false\n",
+		},
+		{
+			"structs have value semantics",
+			`kampung main
+dapao "fmt"
+pattern Point barang {
+	X nombor
+	Y nombor
+}
+action (p *Point) Move(dx nombor) {
+	p.X += dx
+}
+action (p Point) String() tar {
+	balek fmt.Sprintf("(%d,%d)", p.X, p.Y)
+}
+action boss() {
+	got a = Point{X: 1, Y: 2}
+	got b = a
+	b.Move(10)
+	gong(a, b)
+	fmt.Printf("%+v\n", []nombor{a.X})
+}
+`,
+			"(1,2) (11,2)\n[1]\n",
+		},
+		{
+			"closures share captured variables",
+			`kampung main
+action boss() {
+	got n = 0
+	got inc = action() nombor {
+		n++
+		balek n
+	}
+	inc()
+	inc()
+	gong(inc(), n)
+}
+`,
+			"3 3\n",
+		},
+		{
+			"defer and recover",
+			`kampung main
+action safe() {
+	nanti action() {
+		got r = recover()
+		gong("recovered:", r)
+	}()
+	panic("boom")
+}
+action boss() {
+	safe()
+	gong("still alive")
+}
+`,
+			"recovered: boom\nstill alive\n",
+		},
+		{
+			"goroutines and channels",
+			`kampung main
+dapao "sync"
+action boss() {
+	got ch = buat(lobang nombor, 3)
+	got wg sync.WaitGroup
+	loop i := 1; i <= 3; i++ {
+		wg.Add(1)
+		chiong action(n nombor) {
+			nanti wg.Done()
+			ch <- n * n
+		}(i)
+	}
+	wg.Wait()
+	close(ch)
+	got sum = 0
+	loop v = all ch {
+		sum += v
+	}
+	gong(sum)
+}
+`,
+			"14\n",
+		},
+		{
+			"type switch",
+			`kampung main
+action describe(v interface{}) tar {
+	see_how v.(type) {
+	say nombor:
+		balek "int"
+	say tar:
+		balek "string"
+	anyhow:
+		balek "other"
+	}
+}
+action boss() {
+	gong(describe(1), describe("x"), describe(1.5))
+}
+`,
+			"int string other\n",
+		},
+		{
+			"methods on named types",
+			`kampung main
+pattern Score nombor
+action (s Score) Passing() bolehtak {
+	balek s >= 50
+}
+action boss() {
+	got s Score = 75
+	gong(s, s.Passing(), (s - 30).Passing())
+}
+`,
+			"75 true false\n",
+		},
+		{
+			"sized integers wrap",
+			`kampung main
+action boss() {
+	got b byte = 255
+	b++
+	got i int8 = 127
+	i++
+	got u uint = 0
+	u--
+	got w int32 = 2147483647
+	w = w + 1
+	got big = 70000
+	gong(b, i, u, w, byte(big), uint16(big))
+}
+`,
+			"0 -128 18446744073709551615 -2147483648 112 4464\n",
+		},
+		{
+			"bytes and runes of strings",
+			`kampung main
+action boss() {
+	got s = "héllo"
+	got counts = map[rune]nombor{}
+	loop _, r = all s {
+		counts[r]++
+	}
+	got bs = []byte("hi")
+	bs[0] = bs[0] - 32
+	gong(s[0] == 104, counts[108], string(bs), string([]rune(s)[1]))
+}
+`,
+			"true 2 Hi é\n",
+		},
+		{
+			"Go type names",
+			`kampung main
+pattern Pt barang {
+	X nombor
+}
+pattern Level int8
+action add(a, b nombor) nombor {
+	balek a + b
+}
+action boss() {
+	got p = Pt{X: 1}
+	got l Level = 3
+	fmt.Printf("%T %T %T %T %T %v%%\n", p, &p, l, add, map[tar]Pt{}, 5)
+	gong(fmt.Sprintf("%-6T|%T", "s", "héllo"[0]))
+}
+`,
+			"main.Pt *main.Pt main.Level func(int, int) int map[string]main.Pt 5%\nstring|uint8\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runSource(t, tt.source)
+			if err != nil {
+				t.Fatalf("Run failed: %v\noutput:\n%s", err, got)
+			}
+			if got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := runSource(t, "kampung main\ndapao \"net/http\"\naction boss() {}\n")
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected unsupported package error, got %v", err)
+	}
+
+	_, err = runSource(t, "kampung main\naction boss() {\n\tpanic(\"sian\")\n}\n")
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Error() != "panic: sian" {
+		t.Errorf("expected PanicError, got %v", err)
+	}
+
+	_, err = runSource(t, "kampung main\ndapao \"os\"\naction boss() {\n\tnanti gong(\"skipped\")\n\tos.Exit(3)\n}\n")
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 {
+		t.Errorf("expected ExitError with code 3, got %v", err)
+	}
+
+	out, err := runSource(t, "kampung main\naction boss() {\n\tgot xs = []nombor{1}\n\tgong(xs[5])\n}\n")
+	if err == nil || !strings.Contains(err.Error(), "index out of range") {
+		t.Errorf("expected index out of range, got %v (output %q)", err, out)
+	}
+
+	_, err = runSource(t, "kampung main\naction boss() {\n\tfmt.Printf(\"%T\\n\", []nombor{1})\n}\n")
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || !strings.Contains(err.Error(), "%T") {
+		t.Errorf("expected RuntimeError for %%T of a slice, got %v", err)
+	}
+}
+
+func TestRunQualifiedAliases(t *testing.T) {
+	path := t.TempDir() + "/dict.txt"
+	if err := os.WriteFile(path, []byte("@extends default\nbesar: strings.ToUpper\nkira: strconv.Itoa\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := dictionaries.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	program, err := transpiler.Parse("kampung main\n\naction boss() {\n\tgong(besar(\"steady\") + kira(42))\n}\n", dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var out bytes.Buffer
+	it := New(dict)
+	it.Stdout = &out
+	if err := it.Run(program); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := out.String(); got != "STEADY42\n" {
+		t.Errorf("Run() printed %q, want %q", got, "STEADY42\n")
+	}
+}
+
+func TestRunWithoutImports(t *testing.T) {
+	out, err := runSource(t, "kampung main\n\naction boss() {\n\tgot strs = []string{\"b\", \"a\"}\n\tsort.Strings(strs)\n\tgong(strings.Join(strs, \",\"))\n}\n")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if out != "a,b\n" {
+		t.Errorf("Run() printed %q, want %q", out, "a,b\n")
+	}
+}
+
+func TestExecAndEval(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	var out bytes.Buffer
+	it := New(dict)
+	it.Stdout = &out
+
+	entries := []string{
+		"got calls = 0",
+		"action next() nombor {\n\tbalek 7\n}",
+		"calls++\ngong(\"once\")",
+		"got t = next()",
+	}
+	for _, entry := range entries {
+		program, err := transpiler.Parse(entry+"\n", dict)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", entry, err)
+		}
+		if err := it.Exec(program); err != nil {
+			t.Fatalf("Exec(%q) failed: %v", entry, err)
+		}
+	}
+
+	for range 2 {
+		var values []any
+		for _, name := range []string{"calls", "t"} {
+			program, err := transpiler.Parse(name+"\n", dict)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			v, err := it.Eval(program.Statements[0].(*ast.ExpressionStatement).Expression)
+			if err != nil {
+				t.Fatalf("Eval(%s) failed: %v", name, err)
+			}
+			values = append(values, v...)
+		}
+		if len(values) != 2 || values[0] != 1 || values[1] != 7 {
+			t.Errorf("Eval() = %v, want [1 7]", values)
+		}
+	}
+	if got := out.String(); got != "once\n" {
+		t.Errorf("printed %q, want each statement to run once", got)
+	}
+}
@@ -0,0 +1,325 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stdlib is the whitelist of standard library members the interpreter can call.
+// Functions are invoked through reflection; anything else is a constant or variable.
+// Packages missing from this table make the interpreter refuse to run the program.
+var stdlib = map[string]map[string]any{
+	"errors": {
+		"New":    errors.New,
+		"Is":     errors.Is,
+		"Unwrap": errors.Unwrap,
+	},
+	"fmt": {
+		"Sprint":   fmt.Sprint,
+		"Sprintln": fmt.Sprintln,
+		// Print functions are handled by the interpreter so output follows
+		// Interpreter.Stdout, and the ones with a format so that %T names
+		// the Go types of interpreter values.
+	},
+	"math": {
+		"Abs":                    math.Abs,
+		"Ceil":                   math.Ceil,
+		"Floor":                  math.Floor,
+		"Max":                    math.Max,
+		"Min":                    math.Min,
+		"Mod":                    math.Mod,
+		"Pow":                    math.Pow,
+		"Round":                  math.Round,
+		"Sqrt":                   math.Sqrt,
+		"Trunc":                  math.Trunc,
+		"Inf":                    math.Inf,
+		"IsNaN":                  math.IsNaN,
+		"Log":                    math.Log,
+		"Pi":                     math.Pi,
+		"E":                      math.E,
+		"MaxInt":                 math.MaxInt,
+		"MinInt":                 math.MinInt,
+		"MaxInt64":               math.MaxInt64,
+		"MinInt64":               math.MinInt64,
+		"MaxInt32":               math.MaxInt32,
+		"MaxFloat64":             math.MaxFloat64,
+		"SmallestNonzeroFloat64": math.SmallestNonzeroFloat64,
+	},
+	"bytes": {
+		"NewBuffer":       bytes.NewBuffer,
+		"NewBufferString": bytes.NewBufferString,
+		"NewReader":       bytes.NewReader,
+		"Contains":        bytes.Contains,
+		"Equal":           bytes.Equal,
+	},
+	"math/rand": {
+		"Seed":    rand.Seed,
+		"Int":     rand.Int,
+		"Intn":    rand.Intn,
+		"Float64": rand.Float64,
+		"Perm":    rand.Perm,
+	},
+	"os": {
+		"Getenv":    os.Getenv,
+		"Hostname":  os.Hostname,
+		"LookupEnv": os.LookupEnv,
+		"Setenv":    os.Setenv,
+		"Unsetenv":  os.Unsetenv,
+		"Environ":   os.Environ,
+		"Stdout":    os.Stdout,
+		"Stderr":    os.Stderr,
+	},
+	"sort": {
+		"SearchInts":       sort.SearchInts,
+		"SearchStrings":    sort.SearchStrings,
+		"IntsAreSorted":    sort.IntsAreSorted,
+		"StringsAreSorted": sort.StringsAreSorted,
+	},
+	"strconv": {
+		"Atoi":        strconv.Atoi,
+		"Itoa":        strconv.Itoa,
+		"FormatBool":  strconv.FormatBool,
+		"FormatFloat": strconv.FormatFloat,
+		"FormatInt":   strconv.FormatInt,
+		"ParseBool":   strconv.ParseBool,
+		"ParseFloat":  strconv.ParseFloat,
+		"ParseInt":    strconv.ParseInt,
+		"Quote":       strconv.Quote,
+	},
+	"strings": {
+		"Contains":     strings.Contains,
+		"ContainsRune": strings.ContainsRune,
+		"Count":        strings.Count,
+		"EqualFold":    strings.EqualFold,
+		"Fields":       strings.Fields,
+		"HasPrefix":    strings.HasPrefix,
+		"HasSuffix":    strings.HasSuffix,
+		"Index":        strings.Index,
+		"Join":         strings.Join,
+		"LastIndex":    strings.LastIndex,
+		"Repeat":       strings.Repeat,
+		"Replace":      strings.Replace,
+		"ReplaceAll":   strings.ReplaceAll,
+		"Split":        strings.Split,
+		"Title":        strings.Title,
+		"ToLower":      strings.ToLower,
+		"ToUpper":      strings.ToUpper,
+		"Trim":         strings.Trim,
+		"TrimLeft":     strings.TrimLeft,
+		"TrimPrefix":   strings.TrimPrefix,
+		"TrimRight":    strings.TrimRight,
+		"TrimSpace":    strings.TrimSpace,
+		"TrimSuffix":   strings.TrimSuffix,
+		"NewReader":    strings.NewReader,
+	},
+	"sync": {
+		"NewCond": sync.NewCond,
+	},
+	"time": {
+		"Date":          time.Date,
+		"Parse":         time.Parse,
+		"ParseDuration": time.ParseDuration,
+		"UTC":           time.UTC,
+		"Local":         time.Local,
+		"January":       time.January,
+		"February":      time.February,
+		"March":         time.March,
+		"April":         time.April,
+		"May":           time.May,
+		"June":          time.June,
+		"July":          time.July,
+		"August":        time.August,
+		"September":     time.September,
+		"October":       time.October,
+		"November":      time.November,
+		"December":      time.December,
+		"Sunday":        time.Sunday,
+		"Monday":        time.Monday,
+		"Tuesday":       time.Tuesday,
+		"Wednesday":     time.Wednesday,
+		"Thursday":      time.Thursday,
+		"Friday":        time.Friday,
+		"Saturday":      time.Saturday,
+		"DateOnly":      time.DateOnly,
+		"DateTime":      time.DateTime,
+		"TimeOnly":      time.TimeOnly,
+		"Now":           time.Now,
+		"Since":         time.Since,
+		"Sleep":         time.Sleep,
+		"After":         time.After,
+		"Tick":          time.Tick,
+		"NewTimer":      time.NewTimer,
+		"NewTicker":     time.NewTicker,
+		"Unix":          time.Unix,
+		"Nanosecond":    time.Nanosecond,
+		"Microsecond":   time.Microsecond,
+		"Millisecond":   time.Millisecond,
+		"Second":        time.Second,
+		"Minute":        time.Minute,
+		"Hour":          time.Hour,
+		"RFC3339":       time.RFC3339,
+		"Kitchen":       time.Kitchen,
+	},
+	"unicode": {
+		"IsDigit":  unicode.IsDigit,
+		"IsLetter": unicode.IsLetter,
+		"IsLower":  unicode.IsLower,
+		"IsSpace":  unicode.IsSpace,
+		"IsUpper":  unicode.IsUpper,
+		"ToLower":  unicode.ToLower,
+		"ToUpper":  unicode.ToUpper,
+	},
+	"unicode/utf8": {
+		"RuneCountInString": utf8.RuneCountInString,
+		"RuneLen":           utf8.RuneLen,
+	},
+}
+
+// stdlibTypes are standard library types that can be declared with got or used in conversions.
+var stdlibTypes = map[string]reflect.Type{
+	"bytes.Buffer":    reflect.TypeOf(bytes.Buffer{}),
+	"strings.Builder": reflect.TypeOf(strings.Builder{}),
+	"sync.Map":        reflect.TypeOf(sync.Map{}),
+	"sync.Mutex":      reflect.TypeOf(sync.Mutex{}),
+	"sync.Once":       reflect.TypeOf(sync.Once{}),
+	"sync.Pool":       reflect.TypeOf(sync.Pool{}),
+	"sync.RWMutex":    reflect.TypeOf(sync.RWMutex{}),
+	"sync.WaitGroup":  reflect.TypeOf(sync.WaitGroup{}),
+	"atomic.Int32":    reflect.TypeOf(atomic.Int32{}),
+	"atomic.Int64":    reflect.TypeOf(atomic.Int64{}),
+	"atomic.Value":    reflect.TypeOf(atomic.Value{}),
+	"time.Duration":   reflect.TypeOf(time.Duration(0)),
+	"time.Month":      reflect.TypeOf(time.Month(0)),
+	"time.Time":       reflect.TypeOf(time.Time{}),
+	"time.Weekday":    reflect.TypeOf(time.Weekday(0)),
+}
+
+// packageName returns the name a package is referred to by in source, e.g. "rand" for "math/rand".
+func packageName(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// toReflect converts an interpreter value into a reflect.Value assignable to t.
+func (it *Interpreter) toReflect(v any, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	if p, ok := v.(*Pointer); ok && p.Cell != nil && reflect.TypeOf(p.Cell.value) != nil && reflect.TypeOf(p.Cell.value).Kind() == reflect.Ptr {
+		// &wg where wg already holds a *sync.WaitGroup.
+		v = p.Cell.value
+	}
+	if t.Kind() == reflect.Interface {
+		if f, ok := v.(*Function); ok {
+			return reflect.ValueOf(f)
+		}
+		return reflect.ValueOf(v)
+	}
+
+	v = unwrap(v)
+	rv := reflect.ValueOf(v)
+	if rv.Type().AssignableTo(t) {
+		return rv
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if items, ok := v.([]any); ok {
+			out := reflect.MakeSlice(t, len(items), len(items))
+			for i, item := range items {
+				out.Index(i).Set(it.toReflect(item, t.Elem()))
+			}
+			return out
+		}
+	case reflect.Map:
+		if m, ok := v.(*Map); ok {
+			out := reflect.MakeMapWithSize(t, len(m.m))
+			for k, val := range m.m {
+				out.SetMapIndex(it.toReflect(k, t.Key()), it.toReflect(val, t.Elem()))
+			}
+			return out
+		}
+	case reflect.Func:
+		if fn, ok := v.(*Function); ok {
+			return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+				in := make([]any, len(args))
+				for i, a := range args {
+					in[i] = fromReflect(a)
+				}
+				res := it.callFunction(fn, nil, in)
+				return it.resultsToReflect(res, t)
+			})
+		}
+	}
+
+	if rv.Type().ConvertibleTo(t) {
+		return rv.Convert(t)
+	}
+	panic(runtimeError("cannot use %v (%s) as %s", v, typeName(v), t))
+}
+
+func (it *Interpreter) resultsToReflect(res any, t reflect.Type) []reflect.Value {
+	out := make([]reflect.Value, t.NumOut())
+	values := []any{res}
+	if tup, ok := res.(tuple); ok {
+		values = tup
+	}
+	for i := range out {
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+		out[i] = it.toReflect(v, t.Out(i))
+	}
+	return out
+}
+
+// fromReflect converts a standard library result into an interpreter value.
+func fromReflect(rv reflect.Value) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Type().PkgPath() == "" {
+			return int(rv.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Type().PkgPath() == "" {
+			return int(rv.Uint())
+		}
+	case reflect.Float32, reflect.Float64:
+		if rv.Type().PkgPath() == "" {
+			return rv.Float()
+		}
+	case reflect.Slice:
+		if rv.Type().PkgPath() == "" && !rv.IsNil() {
+			out := make([]any, rv.Len())
+			for i := range out {
+				out[i] = fromReflect(rv.Index(i))
+			}
+			return out
+		}
+	case reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return fromReflect(rv.Elem())
+	}
+	return rv.Interface()
+}
@@ -0,0 +1,745 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/ast"
+)
+
+var intTypes = map[string]bool{
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "byte": true, "rune": true,
+}
+
+// sizedInts are the integer types other than int. Their values are kept
+// as the Go type itself, so that arithmetic wraps at the width Go would
+// and %T names them; int values are plain ints.
+var sizedInts = map[string]reflect.Type{
+	"int8": reflect.TypeFor[int8](), "int16": reflect.TypeFor[int16](),
+	"int32": reflect.TypeFor[int32](), "int64": reflect.TypeFor[int64](),
+	"uint": reflect.TypeFor[uint](), "uint8": reflect.TypeFor[uint8](),
+	"uint16": reflect.TypeFor[uint16](), "uint32": reflect.TypeFor[uint32](),
+	"uint64": reflect.TypeFor[uint64](), "uintptr": reflect.TypeFor[uintptr](),
+	"byte": reflect.TypeFor[byte](), "rune": reflect.TypeFor[rune](),
+}
+
+var floatTypes = map[string]bool{"float64": true, "float32": true}
+
+// sizeInt converts n to the integer type t, wrapping it to the width of t.
+func sizeInt(n int, t string) any {
+	if rt, ok := sizedInts[t]; ok {
+		return reflect.ValueOf(n).Convert(rt).Interface()
+	}
+	return n
+}
+
+// typeString renders a type expression in Go syntax, translating Singlish type names.
+func (it *Interpreter) typeString(expr ast.Expression) string {
+	switch e := expr.(type) {
+	case nil:
+		return ""
+	case *ast.Identifier:
+		v := e.Value
+		prefix := ""
+		for _, p := range []string{"...", "*"} {
+			if strings.HasPrefix(v, p) {
+				prefix += p
+				v = v[len(p):]
+			}
+		}
+		return prefix + it.ident(v)
+	case *ast.PrefixExpression:
+		if e.Operator == "*" {
+			return "*" + it.typeString(e.Right)
+		}
+	case *ast.InfixExpression:
+		if e.Operator == "." {
+			return it.typeString(e.Left) + "." + it.selectorName(e.Right)
+		}
+	case *ast.StructLiteral:
+		return "struct{}"
+	case *ast.InterfaceLiteral:
+		return "interface{}"
+	}
+	return expr.String()
+}
+
+func (it *Interpreter) isType(t string) bool {
+	switch {
+	case intTypes[t], floatTypes[t]:
+		return true
+	case t == "string", t == "bool", t == "error", t == "any", t == "interface{}":
+		return true
+	case strings.HasPrefix(t, "["), strings.HasPrefix(t, "map["), strings.HasPrefix(t, "chan "),
+		strings.HasPrefix(t, "*"), strings.HasPrefix(t, "func("):
+		return true
+	}
+	if _, ok := it.types[t]; ok {
+		return true
+	}
+	_, ok := stdlibTypes[t]
+	return ok
+}
+
+// zero returns the zero value of type t.
+func (it *Interpreter) zero(t string) any {
+	switch {
+	case t == "":
+		return nil
+	case intTypes[t]:
+		return sizeInt(0, t)
+	case floatTypes[t]:
+		return 0.0
+	case t == "string":
+		return ""
+	case t == "bool":
+		return false
+	case strings.HasPrefix(t, "...") || strings.HasPrefix(t, "[]"):
+		return []any(nil)
+	case strings.HasPrefix(t, "["):
+		end := strings.Index(t, "]")
+		n, err := strconv.Atoi(t[1:end])
+		if err != nil {
+			panic(runtimeError("unsupported array type %s", t))
+		}
+		out := make([]any, n)
+		for i := range out {
+			out[i] = it.zero(t[end+1:])
+		}
+		return out
+	case strings.HasPrefix(t, "map["):
+		key, elem := mapTypes(t)
+		return &Map{it: it, key: key, elem: elem}
+	}
+
+	if decl, ok := it.types[t]; ok {
+		switch {
+		case decl.strct != nil:
+			s := &Struct{typ: decl.strct, fields: make([]any, len(decl.strct.types))}
+			for i, ft := range decl.strct.types {
+				s.fields[i] = it.zero(ft)
+			}
+			return s
+		case decl.keepsName():
+			return Named{typ: decl, value: it.zero(decl.underlying)}
+		case decl.underlying != "":
+			return it.zero(decl.underlying)
+		}
+		return nil
+	}
+	if rt, ok := stdlibTypes[t]; ok {
+		// Types with pointer-receiver methods (sync.Mutex, strings.Builder, ...) are
+		// kept behind a pointer so calling those methods mutates the variable.
+		if rt.Kind() == reflect.Struct && t != "time.Time" {
+			return reflect.New(rt).Interface()
+		}
+		return reflect.Zero(rt).Interface()
+	}
+	return nil
+}
+
+// mapTypes splits "map[K]V" into K and V.
+func mapTypes(t string) (key, elem string) {
+	depth := 0
+	for i := len("map"); i < len(t); i++ {
+		switch t[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return t[len("map["):i], t[i+1:]
+			}
+		}
+	}
+	return "", ""
+}
+
+// convert implements an explicit conversion T(v).
+func (it *Interpreter) convert(v any, t string) any {
+	v = unwrap(v)
+	switch {
+	case intTypes[t]:
+		switch x := v.(type) {
+		case float64:
+			return sizeInt(int(x), t)
+		case bool:
+			panic(runtimeError("cannot convert %v (bool) to %s", x, t))
+		}
+		return sizeInt(it.toInt(v), t)
+	case floatTypes[t]:
+		return it.toFloat(v)
+	case t == "string":
+		if isInt(v) {
+			return string(rune(it.toInt(v)))
+		}
+		if x, ok := v.([]any); ok {
+			return it.bytesOrRunesToString(x)
+		}
+		return it.toString(v)
+	case t == "[]byte":
+		s := it.toString(v)
+		out := make([]any, len(s))
+		for i := 0; i < len(s); i++ {
+			out[i] = s[i]
+		}
+		return out
+	case t == "[]rune":
+		var out []any
+		for _, r := range it.toString(v) {
+			out = append(out, r)
+		}
+		return out
+	}
+	if decl, ok := it.types[t]; ok {
+		switch {
+		case decl.keepsName():
+			return Named{typ: decl, value: it.convert(v, decl.underlying)}
+		case decl.underlying != "":
+			return it.convert(v, decl.underlying)
+		}
+		return copyValue(v)
+	}
+	if rt, ok := stdlibTypes[t]; ok {
+		return it.toReflect(v, rt).Interface()
+	}
+	return v
+}
+
+// bytesOrRunesToString converts a []byte or []rune back to a string. A
+// slice of ints written as a literal is taken as bytes when it can be.
+func (it *Interpreter) bytesOrRunesToString(xs []any) string {
+	if len(xs) > 0 {
+		if _, ok := xs[0].(rune); ok {
+			var sb strings.Builder
+			for _, x := range xs {
+				sb.WriteRune(rune(it.toInt(x)))
+			}
+			return sb.String()
+		}
+	}
+	b := make([]byte, 0, len(xs))
+	isBytes := true
+	for _, x := range xs {
+		n := it.toInt(x)
+		if n > 255 {
+			isBytes = false
+			break
+		}
+		b = append(b, byte(n))
+	}
+	if isBytes && (len(xs) == 0 || isSized(xs[0]) || utf8.Valid(b)) {
+		return string(b)
+	}
+	var sb strings.Builder
+	for _, x := range xs {
+		sb.WriteRune(rune(it.toInt(x)))
+	}
+	return sb.String()
+}
+
+// convertForAssign applies the implicit conversions Go performs for untyped
+// constants, e.g. got p point = 3 stores 3.0.
+func (it *Interpreter) convertForAssign(v any, t string) any {
+	if t == "" {
+		return v
+	}
+	if decl, ok := it.types[t]; ok && decl.underlying != "" {
+		if _, named := v.(Named); decl.keepsName() && !named {
+			return Named{typ: decl, value: it.convertForAssign(v, decl.underlying)}
+		}
+		t = decl.underlying
+	}
+	n, ok := v.(int)
+	if !ok {
+		return v
+	}
+	switch {
+	case floatTypes[t]:
+		return float64(n)
+	case t == "time.Duration":
+		return time.Duration(n)
+	}
+	return sizeInt(n, t)
+}
+
+// convertLike converts v to the type of the value it replaces in an assignment.
+func (it *Interpreter) convertLike(v, old any) any {
+	if n, ok := old.(Named); ok {
+		if _, named := v.(Named); !named {
+			return Named{typ: n.typ, value: it.convertLike(v, n.value)}
+		}
+		return v
+	}
+	n, ok := v.(int)
+	if !ok {
+		return v
+	}
+	switch old.(type) {
+	case float64:
+		return float64(n)
+	case time.Duration:
+		return time.Duration(n)
+	}
+	if isSized(old) {
+		return reflect.ValueOf(n).Convert(reflect.TypeOf(old)).Interface()
+	}
+	return v
+}
+
+// matchesType reports whether v has type t, for type switches and assertions.
+func (it *Interpreter) matchesType(v any, t string) bool {
+	if n, ok := v.(Named); ok {
+		switch t {
+		case n.typ.name, "any", "interface{}":
+			return true
+		}
+		if decl, ok := it.types[t]; ok && decl.iface != nil {
+			return it.implements(v, it.interfaceMethods(decl))
+		}
+		return t == "error" && it.implements(v, []string{"Error"})
+	}
+	switch {
+	case t == "any" || t == "interface{}":
+		return true
+	case t == "nil":
+		return v == nil
+	case t == "error":
+		return it.implements(v, []string{"Error"})
+	case t == "int":
+		_, ok := v.(int)
+		return ok
+	case intTypes[t]:
+		return v != nil && reflect.TypeOf(v) == sizedInts[t]
+	case floatTypes[t]:
+		_, ok := v.(float64)
+		return ok
+	case t == "string":
+		_, ok := v.(string)
+		return ok
+	case t == "bool":
+		_, ok := v.(bool)
+		return ok
+	case strings.HasPrefix(t, "[]"):
+		_, ok := v.([]any)
+		return ok
+	case strings.HasPrefix(t, "map["):
+		m, ok := v.(*Map)
+		return ok && "map["+m.key+"]"+m.elem == t
+	case strings.HasPrefix(t, "*"):
+		if p, ok := v.(*Pointer); ok && p.Struct != nil {
+			return p.Struct.typ.name == t[1:]
+		}
+	}
+	if decl, ok := it.types[t]; ok {
+		switch {
+		case decl.strct != nil:
+			s, ok := v.(*Struct)
+			return ok && s.typ == decl.strct
+		case decl.iface != nil:
+			return it.implements(v, it.interfaceMethods(decl))
+		}
+		return it.matchesType(v, decl.underlying)
+	}
+	if v == nil {
+		return false
+	}
+	rt := reflect.TypeOf(v)
+	return rt.String() == t || rt.String() == "*"+t
+}
+
+func (it *Interpreter) interfaceMethods(decl *typeDecl) []string {
+	names := make([]string, len(decl.iface.Methods))
+	for i, m := range decl.iface.Methods {
+		names[i] = it.ident(m.Name.Value)
+	}
+	return names
+}
+
+// implements reports whether v has all the named methods.
+func (it *Interpreter) implements(v any, methods []string) bool {
+	var st *structType
+	addressable := false
+	switch x := v.(type) {
+	case nil:
+		return false
+	case Named:
+		for _, name := range methods {
+			if m, ok := x.typ.methods[name]; !ok || m.pointerReceiver() {
+				return false
+			}
+		}
+		return true
+	case *Struct:
+		st = x.typ
+	case *Pointer:
+		if x.Struct == nil {
+			return false
+		}
+		st, addressable = x.Struct.typ, true
+	default:
+		rt := reflect.TypeOf(v)
+		for _, name := range methods {
+			if _, ok := rt.MethodByName(name); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	for _, name := range methods {
+		m, ok := st.methods[name]
+		if !ok || (!addressable && m.pointerReceiver()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (it *Interpreter) truthy(v any) bool {
+	b, ok := unwrap(v).(bool)
+	if !ok {
+		panic(runtimeError("non-bolehtak %v (%s) used as condition", v, typeName(v)))
+	}
+	return b
+}
+
+func (it *Interpreter) toInt(v any) int {
+	switch x := unwrap(v).(type) {
+	case int:
+		return x
+	case float64:
+		return int(x)
+	}
+	rv := reflect.ValueOf(unwrap(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint())
+	}
+	panic(runtimeError("%v (%s) is not a nombor", v, typeName(v)))
+}
+
+func (it *Interpreter) toFloat(v any) float64 {
+	if f, ok := unwrap(v).(float64); ok {
+		return f
+	}
+	if isUnsigned(reflect.TypeOf(unwrap(v))) {
+		return float64(reflect.ValueOf(unwrap(v)).Uint())
+	}
+	return float64(it.toInt(v))
+}
+
+func (it *Interpreter) toString(v any) string {
+	if s, ok := unwrap(v).(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// typedInt returns the type of v if it is an integer of a type other than
+// int: a sized integer such as byte, or a named integer type from the
+// standard library such as time.Duration.
+func typedInt(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	rt := reflect.TypeOf(v)
+	switch rt.Kind() {
+	case reflect.Int:
+		if rt.PkgPath() != "" {
+			return rt
+		}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rt
+	}
+	return nil
+}
+
+// isSized reports whether v is a sized integer such as a byte.
+func isSized(v any) bool {
+	return v != nil && sizedInts[reflect.TypeOf(v).String()] != nil
+}
+
+// isInt reports whether v is an integer of any type.
+func isInt(v any) bool {
+	_, ok := v.(int)
+	return ok || typedInt(v) != nil
+}
+
+func isUnsigned(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toUint returns the bits of an integer as a uint64.
+func (it *Interpreter) toUint(v any) uint64 {
+	if rv := reflect.ValueOf(unwrap(v)); isUnsigned(rv.Type()) {
+		return rv.Uint()
+	}
+	return uint64(it.toInt(v))
+}
+
+// binary evaluates a binary operator. Mixing nombor and point promotes to point,
+// which is what Go does for untyped constants.
+func (it *Interpreter) binary(op string, l, r any) any {
+	ln, lok := l.(Named)
+	rn, rok := r.(Named)
+	if lok || rok {
+		res := it.binary(op, unwrap(l), unwrap(r))
+		if _, isBool := res.(bool); isBool {
+			return res
+		}
+		if lok {
+			return Named{typ: ln.typ, value: res}
+		}
+		return Named{typ: rn.typ, value: res}
+	}
+	if lt, rt := typedInt(l), typedInt(r); lt != nil || rt != nil {
+		// The untyped operand takes the type of the other, except that the
+		// count of a shift leaves the type alone.
+		t := lt
+		if t == nil && op != "<<" && op != ">>" {
+			t = rt
+		}
+		if t == nil {
+			return intOp(op, it.toInt(l), it.toInt(r))
+		}
+		if _, float := l.(float64); float {
+			return floatOp(op, it.toFloat(l), it.toFloat(r))
+		}
+		if _, float := r.(float64); float {
+			return floatOp(op, it.toFloat(l), it.toFloat(r))
+		}
+		var res any
+		if isUnsigned(t) {
+			res = uintOp(op, it.toUint(l), it.toUint(r))
+		} else {
+			res = intOp(op, it.toInt(l), it.toInt(r))
+		}
+		if _, isBool := res.(bool); isBool {
+			return res
+		}
+		return reflect.ValueOf(res).Convert(t).Interface()
+	}
+
+	switch a := l.(type) {
+	case int:
+		switch b := r.(type) {
+		case int:
+			return intOp(op, a, b)
+		case float64:
+			return floatOp(op, float64(a), b)
+		}
+	case float64:
+		switch b := r.(type) {
+		case int:
+			return floatOp(op, a, float64(b))
+		case float64:
+			return floatOp(op, a, b)
+		}
+	case string:
+		if b, ok := r.(string); ok {
+			switch op {
+			case "+":
+				return a + b
+			case "==":
+				return a == b
+			case "!=":
+				return a != b
+			case "<":
+				return a < b
+			case "<=":
+				return a <= b
+			case ">":
+				return a > b
+			case ">=":
+				return a >= b
+			}
+		}
+	}
+	switch op {
+	case "==":
+		return it.equal(l, r)
+	case "!=":
+		return !it.equal(l, r)
+	}
+	panic(runtimeError("invalid operation: %v %s %v (mismatched types %s and %s)", l, op, r, typeName(l), typeName(r)))
+}
+
+func intOp(op string, a, b int) any {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	case "%":
+		return a % b
+	case "&":
+		return a & b
+	case "|":
+		return a | b
+	case "^":
+		return a ^ b
+	case "&^":
+		return a &^ b
+	case "<<":
+		return a << b
+	case ">>":
+		return a >> b
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	panic(runtimeError("operator %s not defined on nombor", op))
+}
+
+func uintOp(op string, a, b uint64) any {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	case "%":
+		return a % b
+	case "&":
+		return a & b
+	case "|":
+		return a | b
+	case "^":
+		return a ^ b
+	case "&^":
+		return a &^ b
+	case "<<":
+		return a << b
+	case ">>":
+		return a >> b
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	panic(runtimeError("operator %s not defined on nombor", op))
+}
+
+func floatOp(op string, a, b float64) any {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	panic(runtimeError("operator %s not defined on point", op))
+}
+
+func (it *Interpreter) equal(a, b any) (eq bool) {
+	a, b = unwrap(a), unwrap(b)
+	if isNil(a) || isNil(b) {
+		return isNil(a) && isNil(b)
+	}
+	switch x := a.(type) {
+	case *Struct:
+		y, ok := b.(*Struct)
+		if !ok || x.typ != y.typ {
+			return false
+		}
+		for i := range x.fields {
+			if !it.equal(x.fields[i], y.fields[i]) {
+				return false
+			}
+		}
+		return true
+	case *Pointer:
+		y, ok := b.(*Pointer)
+		if !ok {
+			return false
+		}
+		if x.Struct != nil {
+			return x.Struct == y.Struct
+		}
+		return x.Cell == y.Cell
+	case int, float64:
+		switch b.(type) {
+		case int, float64:
+			return it.binary("==", a, b).(bool)
+		}
+		return isInt(b) && it.binary("==", a, b).(bool)
+	}
+	if isInt(a) {
+		return (isInt(b) || isFloat(b)) && it.binary("==", a, b).(bool)
+	}
+	defer func() {
+		if recover() != nil {
+			panic(runtimeError("comparing uncomparable type %s", typeName(a)))
+		}
+	}()
+	return a == b
+}
+
+func isFloat(v any) bool {
+	_, ok := v.(float64)
+	return ok
+}
+
+func (it *Interpreter) less(a, b any) bool {
+	return it.truthy(it.binary("<", a, b))
+}
+
+func (it *Interpreter) sortSlice(list []any, less func(i, j int) bool) {
+	sort.SliceStable(list, less)
+}
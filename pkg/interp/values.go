@@ -0,0 +1,496 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rickchow/singlish/pkg/ast"
+)
+
+// Values are represented with plain Go values wherever possible so they can be handed
+// straight to whitelisted standard library functions:
+//
+//	nombor, banyak, zhi   -> int
+//	point                 -> float64
+//	tar                   -> string
+//	bolehtak              -> bool
+//	[]T, [N]T             -> []any
+//	menu[K]V              -> *Map
+//	lobang T              -> chan any
+//	kosong                -> nil
+//
+// User-defined structs, pointers and functions have their own types below. Values from
+// the standard library (time.Duration, *sync.WaitGroup, errors, ...) are kept as-is.
+
+// Struct is an instance of a user-defined barang type.
+type Struct struct {
+	typ    *structType
+	fields []any
+}
+
+type structType struct {
+	it      *Interpreter
+	name    string
+	names   []string
+	types   []string
+	methods map[string]*Function
+}
+
+// Map is a menu. It remembers its element type so missing keys read as the zero value.
+type Map struct {
+	it   *Interpreter
+	m    map[any]any
+	key  string
+	elem string
+}
+
+// Named is a value of a named non-struct type with methods, e.g. pattern Score nombor.
+// Named types without methods are represented by their underlying value.
+type Named struct {
+	typ   *typeDecl
+	value any
+}
+
+// Pointer is the result of taking the address of a struct or a variable.
+type Pointer struct {
+	Struct *Struct
+	Cell   *cell
+}
+
+// Function is a user-defined action or closure.
+type Function struct {
+	name     string
+	params   []*ast.FieldDefinition
+	results  string // result type(s) in Go syntax, e.g. "int" or "(int, error)"
+	body     *ast.BlockStatement
+	closure  *env
+	receiver *ast.FieldDefinition
+}
+
+// tuple carries the results of a call that returns more than one value.
+type tuple []any
+
+type cell struct {
+	value any
+}
+
+type env struct {
+	mu     sync.RWMutex
+	vars   map[string]*cell
+	parent *env
+	frame  *frame
+}
+
+func newEnv(parent *env) *env {
+	e := &env{vars: make(map[string]*cell), parent: parent}
+	if parent != nil {
+		e.frame = parent.frame
+	}
+	return e
+}
+
+func (e *env) define(name string, value any) {
+	if name == "_" {
+		return
+	}
+	e.mu.Lock()
+	e.vars[name] = &cell{value: value}
+	e.mu.Unlock()
+}
+
+func (e *env) lookup(name string) (*cell, bool) {
+	for cur := e; cur != nil; cur = cur.parent {
+		cur.mu.RLock()
+		c, ok := cur.vars[name]
+		cur.mu.RUnlock()
+		if ok {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+func (s *Struct) field(name string) (int, bool) {
+	for i, n := range s.typ.names {
+		if n == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (s *Struct) copy() *Struct {
+	fields := make([]any, len(s.fields))
+	for i, f := range s.fields {
+		fields[i] = copyValue(f)
+	}
+	return &Struct{typ: s.typ, fields: fields}
+}
+
+// String formats the struct the way fmt would, preferring a user-defined String method.
+func (s *Struct) String() string {
+	if out, ok := s.callFormatter(false, "String", "Error"); ok {
+		return out
+	}
+	return s.plain()
+}
+
+// Error lets structs with an Error method be used wherever Go expects an error.
+func (s *Struct) Error() string {
+	if out, ok := s.callFormatter(false, "Error", "String"); ok {
+		return out
+	}
+	return s.plain()
+}
+
+// callFormatter calls the first of the named methods that exists. Methods with pointer
+// receivers are only considered when addressable is set, matching Go's method sets.
+func (s *Struct) callFormatter(addressable bool, names ...string) (string, bool) {
+	for _, name := range names {
+		m, ok := s.typ.methods[name]
+		if !ok || len(m.params) != 0 || (!addressable && m.pointerReceiver()) {
+			continue
+		}
+		res := s.typ.it.callFunction(m, s.receiverFor(m), nil)
+		if str, ok := res.(string); ok {
+			return str, true
+		}
+	}
+	return "", false
+}
+
+func (s *Struct) plain() string {
+	parts := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		parts[i] = fmt.Sprint(f)
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
+
+// receiverFor returns the value bound to a method's receiver: the struct itself for
+// pointer receivers and a copy for value receivers.
+func (s *Struct) receiverFor(m *Function) any {
+	if m.pointerReceiver() {
+		return &Pointer{Struct: s}
+	}
+	return s.copy()
+}
+
+// Format makes fmt call String or Error for the verbs Go would, and print the
+// fields otherwise, including field names for %+v.
+func (s *Struct) Format(f fmt.State, verb rune) {
+	if isStringVerb(verb) {
+		if out, ok := s.callFormatter(false, "Error", "String"); ok {
+			fmt.Fprintf(f, fmt.FormatString(f, verb), out)
+			return
+		}
+	}
+	s.formatFields(f, verb)
+}
+
+func (s *Struct) formatFields(f fmt.State, verb rune) {
+	format := fmt.FormatString(f, verb)
+	parts := make([]string, len(s.fields))
+	for i, v := range s.fields {
+		parts[i] = fmt.Sprintf(format, v)
+		if verb == 'v' && f.Flag('+') {
+			parts[i] = s.typ.names[i] + ":" + parts[i]
+		}
+	}
+	fmt.Fprint(f, "{"+strings.Join(parts, " ")+"}")
+}
+
+func (p *Pointer) Format(f fmt.State, verb rune) {
+	if p.Struct == nil {
+		fmt.Fprintf(f, "%p", p.Cell)
+		return
+	}
+	if isStringVerb(verb) {
+		if out, ok := p.Struct.callFormatter(true, "Error", "String"); ok {
+			fmt.Fprintf(f, fmt.FormatString(f, verb), out)
+			return
+		}
+	}
+	fmt.Fprint(f, "&")
+	p.Struct.formatFields(f, verb)
+}
+
+func (n Named) Format(f fmt.State, verb rune) {
+	if isStringVerb(verb) {
+		if out, ok := n.callFormatter("Error", "String"); ok {
+			fmt.Fprintf(f, fmt.FormatString(f, verb), out)
+			return
+		}
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, verb), n.value)
+}
+
+func (n Named) String() string {
+	return fmt.Sprint(n)
+}
+
+func (n Named) Error() string {
+	return fmt.Sprint(n)
+}
+
+func (n Named) callFormatter(names ...string) (string, bool) {
+	for _, name := range names {
+		m, ok := n.typ.methods[name]
+		if !ok || len(m.params) != 0 || m.pointerReceiver() {
+			continue
+		}
+		if str, ok := n.typ.it.callFunction(m, n, nil).(string); ok {
+			return str, true
+		}
+	}
+	return "", false
+}
+
+// isStringVerb reports whether fmt would use a String or Error method for verb.
+func isStringVerb(verb rune) bool {
+	switch verb {
+	case 'v', 's', 'q', 'x', 'X':
+		return true
+	}
+	return false
+}
+
+func (m *Map) String() string {
+	return fmt.Sprint(m.m)
+}
+
+// get returns the value stored under key, or the zero value of the element type.
+func (m *Map) get(key any) (any, bool) {
+	v, ok := m.m[m.keyOf(key)]
+	if !ok {
+		return m.it.zero(m.elem), false
+	}
+	return v, true
+}
+
+// keyOf converts key to the key type of m, so that an untyped constant
+// finds what a typed value stored, as in m['a'] for a map[rune]int.
+func (m *Map) keyOf(key any) any {
+	return m.it.convertForAssign(key, m.key)
+}
+
+func (p *Pointer) String() string {
+	if p.Struct != nil {
+		if out, ok := p.Struct.callFormatter(true, "String", "Error"); ok {
+			return out
+		}
+		return "&" + p.Struct.plain()
+	}
+	return fmt.Sprintf("%p", p.Cell)
+}
+
+func (p *Pointer) Error() string {
+	if p.Struct != nil {
+		if out, ok := p.Struct.callFormatter(true, "Error", "String"); ok {
+			return out
+		}
+		return "&" + p.Struct.plain()
+	}
+	return p.String()
+}
+
+func (p *Pointer) load() any {
+	if p.Struct != nil {
+		return p.Struct
+	}
+	return p.Cell.value
+}
+
+func (f *Function) pointerReceiver() bool {
+	return f.receiver != nil && strings.HasPrefix(f.receiver.Type.String(), "*")
+}
+
+func (f *Function) String() string {
+	if f.name != "" {
+		return "action " + f.name
+	}
+	return "action literal"
+}
+
+// copyValue gives structs value semantics when they are assigned or passed around.
+func copyValue(v any) any {
+	if s, ok := v.(*Struct); ok {
+		return s.copy()
+	}
+	return v
+}
+
+// unwrap returns the underlying value of a Named value.
+func unwrap(v any) any {
+	if n, ok := v.(Named); ok {
+		return n.value
+	}
+	return v
+}
+
+// isNil reports whether v is nil, including typed nils from the standard library.
+func isNil(v any) bool {
+	v = unwrap(v)
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// typeName describes the dynamic type of v in Go syntax, for type switches and assertions.
+func typeName(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "nil"
+	case int:
+		return "int"
+	case float64:
+		return "float64"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []any:
+		return "[]any"
+	case *Map:
+		return "map[" + x.key + "]" + x.elem
+	case chan any:
+		return "chan"
+	case *Struct:
+		return x.typ.name
+	case *Pointer:
+		if x.Struct != nil {
+			return "*" + x.Struct.typ.name
+		}
+		return "*"
+	case *Function:
+		return "func"
+	case Named:
+		return x.typ.name
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// goTypeName returns the type of v as %T prints it in a compiled program.
+// A slice or channel does not keep its element type, so its type cannot be
+// named.
+func (it *Interpreter) goTypeName(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "<nil>"
+	case *Struct:
+		return "main." + x.typ.name
+	case *Pointer:
+		if x.Struct != nil {
+			return "*main." + x.Struct.typ.name
+		}
+		return "*" + it.goTypeName(x.Cell.value)
+	case Named:
+		return "main." + x.typ.name
+	case *Map:
+		return "map[" + it.goType(x.key) + "]" + it.goType(x.elem)
+	case *Function:
+		params := make([]string, len(x.params))
+		for i, p := range x.params {
+			params[i] = it.goType(it.typeString(p.Type))
+		}
+		out := "func(" + strings.Join(params, ", ") + ")"
+		if results := strings.TrimSuffix(strings.TrimPrefix(x.results, "("), ")"); results != "" {
+			types := strings.Split(results, ", ")
+			for i, t := range types {
+				types[i] = it.goType(t[strings.LastIndex(t, " ")+1:])
+			}
+			if len(types) == 1 {
+				return out + " " + types[0]
+			}
+			return out + " (" + strings.Join(types, ", ") + ")"
+		}
+		return out
+	case []any, chan any:
+		panic(runtimeError("%%T of a slice or channel is not supported by the interpreter; run without --interp"))
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// goType returns the type t, written in Go syntax, as %T prints it: the
+// types the program declares belong to package main.
+func (it *Interpreter) goType(t string) string {
+	switch {
+	case t == "any" || t == "interface{}":
+		return "interface {}"
+	case t == "byte":
+		return "uint8"
+	case t == "rune":
+		return "int32"
+	case strings.HasPrefix(t, "..."):
+		return "..." + it.goType(t[len("..."):])
+	case strings.HasPrefix(t, "*"):
+		return "*" + it.goType(t[1:])
+	case strings.HasPrefix(t, "map["):
+		key, elem := mapTypes(t)
+		return "map[" + it.goType(key) + "]" + it.goType(elem)
+	case strings.HasPrefix(t, "["):
+		end := strings.Index(t, "]")
+		return t[:end+1] + it.goType(t[end+1:])
+	case strings.HasPrefix(t, "chan "):
+		return "chan " + it.goType(t[len("chan "):])
+	}
+	if _, ok := it.types[t]; ok {
+		return "main." + t
+	}
+	return t
+}
+
+// format returns the format and arguments of a Printf-style call to pass
+// on to fmt. Each %T becomes a %s of the Go type name of its argument,
+// which fmt would otherwise take from the interpreter's representation.
+func (it *Interpreter) format(f any, args []any) (string, []any) {
+	format := it.toString(f)
+	if !strings.Contains(format, "T") || strings.Contains(format, "[") {
+		return format, args
+	}
+	out := slices.Clone(args)
+	var sb strings.Builder
+	next := 0 // argument of the next verb
+	for i := 0; i < len(format); i++ {
+		sb.WriteByte(format[i])
+		if format[i] != '%' {
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.*", format[j]) >= 0 {
+			if format[j] == '*' {
+				next++
+			}
+			j++
+		}
+		if j == len(format) {
+			sb.WriteString(format[i+1:])
+			break
+		}
+		sb.WriteString(format[i+1 : j])
+		verb := format[j]
+		switch verb {
+		case '%':
+		case 'T':
+			if next < len(out) {
+				out[next] = it.goTypeName(out[next])
+			}
+			verb = 's'
+			next++
+		default:
+			next++
+		}
+		sb.WriteByte(verb)
+		i = j
+	}
+	return sb.String(), out
+}
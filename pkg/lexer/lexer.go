@@ -1,8 +1,15 @@
 package lexer
 
-import "unicode"
-
-// Lex scans source input into tokens and diagnostics.
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Lex scans source input into tokens and diagnostics. A keyword may be a
+// phrase of several words separated by single spaces, such as "see how";
+// it matches those words separated by any spaces and tabs, and the longest
+// phrase wins. The token's Value is the phrase as given in keywords.
 func Lex(input string, keywords map[string]struct{}) ([]Token, []Diagnostic) {
 	l := newLexer(input, keywords)
 	l.lex()
@@ -15,6 +22,7 @@ type lexer struct {
 	line        int
 	col         int
 	keywords    map[string]struct{}
+	phrases     map[string][][]string // first word -> the rest of each phrase, longest first
 	tokens      []Token
 	diagnostics []Diagnostic
 }
@@ -23,11 +31,21 @@ func newLexer(input string, keywords map[string]struct{}) *lexer {
 	if keywords == nil {
 		keywords = map[string]struct{}{}
 	}
+	phrases := make(map[string][][]string)
+	for k := range keywords {
+		if words := strings.Split(k, " "); len(words) > 1 {
+			phrases[words[0]] = append(phrases[words[0]], words[1:])
+		}
+	}
+	for _, rest := range phrases {
+		sort.Slice(rest, func(i, j int) bool { return len(rest[i]) > len(rest[j]) })
+	}
 	return &lexer{
 		src:      []rune(input),
 		line:     1,
 		col:      1,
 		keywords: keywords,
+		phrases:  phrases,
 	}
 }
 
@@ -74,7 +92,7 @@ func (l *lexer) lex() {
 			continue
 		}
 
-		l.addDiagnostic("unexpected character", l.line, l.col)
+		l.addDiagnostic(CodeUnexpectedCharacter, "unexpected character", l.line, l.col)
 		l.advance()
 	}
 }
@@ -134,6 +152,13 @@ func (l *lexer) lexIdentifier() {
 		l.advance()
 	}
 	value := string(l.src[startPos:l.pos])
+	for _, rest := range l.phrases[value] {
+		if l.matchPhrase(rest) {
+			phrase := value + " " + strings.Join(rest, " ")
+			l.tokens = append(l.tokens, Token{Type: TokenKeyword, Value: phrase, Line: startLine, Col: startCol})
+			return
+		}
+	}
 	tokType := TokenIdentifier
 	if _, ok := l.keywords[value]; ok {
 		tokType = TokenKeyword
@@ -141,6 +166,30 @@ func (l *lexer) lexIdentifier() {
 	l.tokens = append(l.tokens, Token{Type: tokType, Value: value, Line: startLine, Col: startCol})
 }
 
+// matchPhrase consumes words, each preceded by spaces or tabs, if they come
+// next, and reports whether they did. Nothing is consumed if they do not.
+func (l *lexer) matchPhrase(words []string) bool {
+	pos, line, col := l.pos, l.line, l.col
+	for _, word := range words {
+		if ch := l.peek(); ch != ' ' && ch != '\t' {
+			l.pos, l.line, l.col = pos, line, col
+			return false
+		}
+		for ch := l.peek(); ch == ' ' || ch == '\t'; ch = l.peek() {
+			l.advance()
+		}
+		start := l.pos
+		for !l.eof() && isIdentifierPart(l.peek()) {
+			l.advance()
+		}
+		if string(l.src[start:l.pos]) != word {
+			l.pos, l.line, l.col = pos, line, col
+			return false
+		}
+	}
+	return true
+}
+
 func (l *lexer) lexNumber() {
 	startLine, startCol := l.line, l.col
 	startPos := l.pos
@@ -189,7 +238,8 @@ func (l *lexer) lexBlockComment() bool {
 		}
 		l.advance()
 	}
-	l.addDiagnostic("unterminated block comment", startLine, startCol)
+	l.addDiagnostic(CodeUnterminatedComment, "unterminated block comment", startLine, startCol)
+	l.extendDiagnostic()
 	return false
 }
 
@@ -226,14 +276,18 @@ func (l *lexer) lexString(quote rune) bool {
 		}
 
 		if ch == '\n' || ch == '\r' {
-			l.addDiagnostic("unterminated string literal", startLine, startCol)
+			l.addDiagnostic(CodeUnterminatedString, "unterminated string literal", startLine, startCol)
+			l.extendDiagnostic()
+			d := &l.diagnostics[len(l.diagnostics)-1]
+			d.Notes = append(d.Notes, "use a raw string in backquotes (`...`) for text that spans lines")
 			return false
 		}
 
 		l.advance()
 	}
 
-	l.addDiagnostic("unterminated string literal", startLine, startCol)
+	l.addDiagnostic(CodeUnterminatedString, "unterminated string literal", startLine, startCol)
+	l.extendDiagnostic()
 	return false
 }
 
@@ -280,8 +334,19 @@ func (l *lexer) matchOperator() string {
 	return ""
 }
 
-func (l *lexer) addDiagnostic(message string, line, col int) {
-	l.diagnostics = append(l.diagnostics, Diagnostic{Message: message, Line: line, Col: col})
+func (l *lexer) addDiagnostic(code, message string, line, col int) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{Message: message, Line: line, Col: col, Code: code})
+}
+
+// extendDiagnostic makes the last diagnostic cover the source from its
+// start up to the current position.
+func (l *lexer) extendDiagnostic() {
+	d := &l.diagnostics[len(l.diagnostics)-1]
+	if l.line == d.Line {
+		d.Length = l.col - d.Col
+	} else {
+		d.EndLine, d.EndCol = l.line, l.col
+	}
 }
 
 func isWhitespace(ch rune) bool {
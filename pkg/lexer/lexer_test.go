@@ -84,4 +84,36 @@ func TestLexUnterminatedStringDiagnostic(t *testing.T) {
 	if diag.Line != 1 || diag.Col != 6 {
 		t.Fatalf("unexpected diagnostic location: %#v", diag)
 	}
+	if diag.Code != CodeUnterminatedString || diag.Severity != SeverityError {
+		t.Fatalf("unexpected diagnostic code or severity: %#v", diag)
+	}
+}
+
+func TestLexPhraseKeywords(t *testing.T) {
+	keywords := map[string]struct{}{"see how": {}, "can or not": {}, "can": {}, "or": {}}
+	input := "see  how x {\ncan or\tnot can or\nnot\nsee why\n}"
+	tokens, diagnostics := Lex(input, keywords)
+	if len(diagnostics) != 0 {
+		t.Fatalf("expected no diagnostics, got %v", diagnostics)
+	}
+	want := []Token{
+		{TokenKeyword, "see how", 1, 1},
+		{TokenIdentifier, "x", 1, 10},
+		{TokenPunctuation, "{", 1, 12},
+		{TokenKeyword, "can or not", 2, 1},
+		{TokenKeyword, "can", 2, 12},
+		{TokenKeyword, "or", 2, 16},
+		{TokenIdentifier, "not", 3, 1},
+		{TokenIdentifier, "see", 4, 1},
+		{TokenIdentifier, "why", 4, 5},
+		{TokenPunctuation, "}", 5, 1},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens %v, want %d", len(tokens), tokens, len(want))
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %d = %#v, want %#v", i, tokens[i], want[i])
+		}
+	}
 }
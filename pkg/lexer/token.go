@@ -23,8 +23,86 @@ type Token struct {
 
 // Diagnostic captures lexer errors with source location.
 type Diagnostic struct {
-	Message string
+	Message  string
+	Line     int
+	Col      int
+	Length   int
+	Severity Severity
+	Code     string // stable identifier such as SG1001; see the Code constants
+
+	// EndLine and EndCol end a span that covers several lines, just past
+	// its last character. They are zero for spans on one line, which use
+	// Length instead.
+	EndLine int
+	EndCol  int
+
+	Labels []Label  // related locations, such as where a block was opened
+	Notes  []string // extra explanation shown below the source
+}
+
+// Label points at a secondary location of a Diagnostic.
+type Label struct {
 	Line    int
 	Col     int
 	Length  int
+	Message string
 }
+
+// Severity says how serious a diagnostic is. The zero value is an error.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNote:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// Diagnostic codes. They are part of the machine-readable output and must
+// not be renumbered once released.
+const (
+	CodeUnexpectedCharacter = "SG1001"
+	CodeUnterminatedComment = "SG1002"
+	CodeUnterminatedString  = "SG1003"
+	CodeUnexpectedToken     = "SG2001"
+	CodeNoExpression        = "SG2002"
+	CodeGoNeedsCall         = "SG2003"
+	CodeDeferNeedsCall      = "SG2004"
+	CodeInvalidNumber       = "SG2005"
+	CodeUnclosedBlock       = "SG2006"
+	CodeUnknownKeyword      = "SG2007" // identifier where a keyword was probably meant
+	CodeFuncName            = "SG2008" // function or method named func
+	CodeTestSignature       = "SG3001"
+	CodeTestFailure         = "SG3002" // a test failed or panicked
+	CodeGoCompiler          = "SG4001" // error from the Go toolchain, mapped to Singlish
+	CodeTypeCheck           = "SG4002" // type error found by singlish check
+	CodeDictSyntax          = "SG5001" // dictionary line is not "word: go"
+	CodeDictDuplicate       = "SG5002" // word defined twice in a dictionary
+	CodeDictInvalidWord     = "SG5003" // word the lexer cannot read as one token
+	CodeDictShadowsGo       = "SG5004" // word hides a Go keyword or predeclared identifier
+	CodeDictBadTarget       = "SG5005" // word stands for Go the parser cannot handle
+	CodeDictAmbiguous       = "SG5006" // word is also the Go of another word
+	CodeDictMetadata        = "SG5007" // bad category or canonical flag in a JSON dictionary
+	CodeDictLayer           = "SG5008" // bad @extends or @remove
+	CodeMigrateNoWord       = "SG5009" // keyword has no word in the new dictionary
+	CodeMigrateShadowed     = "SG5010" // name is a keyword in the new dictionary
+	CodeVetUnreachable      = "SG6001" // statement after balek or another jump
+	CodeVetLoopClosure      = "SG6002" // goroutine in a loop captures the loop variable
+	CodeVetDeferInLoop      = "SG6003" // defer inside a loop
+	CodeVetUnused           = "SG6004" // local variable never used
+	CodeVetIgnoredError     = "SG6005" // error result of a call thrown away
+	CodeVetShadow           = "SG6006" // name hides the Go a keyword stands for
+	CodeVetGoContinue       = "SG6007" // continue keyword where a goroutine was meant
+	CodeGeneral             = "SG9001" // error not tied to a source position
+	CodeStaleOutput         = "SG9002" // generated Go file missing or out of date
+)
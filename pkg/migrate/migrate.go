@@ -0,0 +1,132 @@
+// Package migrate moves Singlish source from one dictionary to another. It
+// rewrites each keyword to the word the new dictionary uses for the same Go,
+// and leaves everything else, including comments and whitespace, exactly
+// as it was.
+package migrate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// Change is one keyword rewritten by Source.
+type Change struct {
+	Line, Col int    // where the old word started
+	Old, New  string // the word before and after
+}
+
+// Source rewrites the keywords of src, read with the from dictionary, to
+// the canonical words of the to dictionary. A keyword whose Go has no word
+// in the new dictionary is written as the Go itself, with a note. Names that
+// are keywords in the new dictionary are reported, since the new
+// dictionary would read them as keywords; they are left for the author to
+// rename. If src cannot be lexed it is returned unchanged with the lexer's
+// diagnostics.
+func Source(src string, from, to *dictionaries.Dictionary) (string, []Change, []lexer.Diagnostic) {
+	keywords := make(map[string]struct{})
+	for _, k := range from.Keys() {
+		keywords[k] = struct{}{}
+	}
+	// The transpiler always reads ki as a keyword; do the same.
+	keywords["ki"] = struct{}{}
+
+	tokens, diags := lexer.Lex(src, keywords)
+	if len(diags) > 0 {
+		return src, nil, diags
+	}
+
+	var changes []Change
+	for _, tok := range tokens {
+		switch tok.Type {
+		case lexer.TokenKeyword:
+			target, ok := from.Lookup(tok.Value)
+			if !ok {
+				continue
+			}
+			word, ok := to.ReverseLookup(target)
+			if !ok {
+				word = target
+				diags = append(diags, lexer.Diagnostic{
+					Message:  fmt.Sprintf("the new dictionary has no word for %s; writing `%s` as Go", target, tok.Value),
+					Line:     tok.Line,
+					Col:      tok.Col,
+					Length:   utf8.RuneCountInString(tok.Value),
+					Severity: lexer.SeverityNote,
+					Code:     lexer.CodeMigrateNoWord,
+				})
+			}
+			if word != tok.Value {
+				changes = append(changes, Change{Line: tok.Line, Col: tok.Col, Old: tok.Value, New: word})
+			}
+		case lexer.TokenIdentifier:
+			if target, ok := to.Lookup(tok.Value); ok {
+				diags = append(diags, lexer.Diagnostic{
+					Message:  fmt.Sprintf("`%s` is a name here but the new dictionary reads it as %s", tok.Value, target),
+					Line:     tok.Line,
+					Col:      tok.Col,
+					Length:   utf8.RuneCountInString(tok.Value),
+					Severity: lexer.SeverityWarning,
+					Code:     lexer.CodeMigrateShadowed,
+					Notes:    []string{"rename it before or after migrating"},
+				})
+			}
+		}
+	}
+	return apply(src, changes), changes, diags
+}
+
+// apply makes changes, which are in source order, to src.
+func apply(src string, changes []Change) string {
+	if len(changes) == 0 {
+		return src
+	}
+	starts := lineStarts(src)
+	var out strings.Builder
+	last := 0
+	for _, c := range changes {
+		offset := starts[c.Line-1]
+		for i := 1; i < c.Col; i++ {
+			_, size := utf8.DecodeRuneInString(src[offset:])
+			offset += size
+		}
+		out.WriteString(src[last:offset])
+		out.WriteString(c.New)
+		last = wordEnd(src, offset, c.Old)
+	}
+	out.WriteString(src[last:])
+	return out.String()
+}
+
+// wordEnd returns where the word starting at offset in src ends. The words
+// of a phrase keyword may be separated by any spaces and tabs in src.
+func wordEnd(src string, offset int, word string) int {
+	for i, part := range strings.Split(word, " ") {
+		if i > 0 {
+			offset += len(src[offset:]) - len(strings.TrimLeft(src[offset:], " \t"))
+		}
+		offset += len(part)
+	}
+	return offset
+}
+
+// lineStarts returns the byte offset of the start of each line of src,
+// counting "\r\n" as one line break, as the lexer does.
+func lineStarts(src string) []int {
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		switch src[i] {
+		case '\r':
+			if i+1 < len(src) && src[i+1] == '\n' {
+				i++
+			}
+			starts = append(starts, i+1)
+		case '\n':
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
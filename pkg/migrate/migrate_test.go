@@ -0,0 +1,93 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+func load(t *testing.T, content string) *dictionaries.Dictionary {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dict.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, err := dictionaries.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return dict
+}
+
+func TestSource(t *testing.T) {
+	from := load(t, "kampung: package\naction: func\nbalek: return\ngong: fmt.Println\n")
+	to := load(t, "kampung: package\nbuat: func\nbalik: return\nkampong: package\n")
+
+	src := "kampung main\r\n\r\n// action stays in comments\r\naction  main() {\r\n\tgong(\"action 好\")  /* balek */\r\n\tbalek\r\n}\r\n"
+	want := "kampung main\r\n\r\n// action stays in comments\r\nbuat  main() {\r\n\tfmt.Println(\"action 好\")  /* balek */\r\n\tbalik\r\n}\r\n"
+	got, changes, diags := Source(src, from, to)
+	if got != want {
+		t.Errorf("Source() =\n%q\nwant\n%q", got, want)
+	}
+	wantChanges := []Change{
+		{Line: 4, Col: 1, Old: "action", New: "buat"},
+		{Line: 5, Col: 2, Old: "gong", New: "fmt.Println"},
+		{Line: 6, Col: 2, Old: "balek", New: "balik"},
+	}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("Source() changes = %+v, want %+v", changes, wantChanges)
+	}
+	for i := range wantChanges {
+		if changes[i] != wantChanges[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], wantChanges[i])
+		}
+	}
+	if len(diags) != 1 || diags[0].Code != lexer.CodeMigrateNoWord || diags[0].Line != 5 || diags[0].Severity != lexer.SeverityNote {
+		t.Errorf("Source() diagnostics = %+v, want a note that gong has no word", diags)
+	}
+}
+
+func TestSourcePhrases(t *testing.T) {
+	from := load(t, "kampung: package\nsee   how: switch\nsay: case\n")
+	to := load(t, "kampung: package\ntengok: switch\nkalau: case\nlepak lah: continue\n")
+
+	src := "kampung main\n\nfunc f(x int) {\n\tsee \t how x {\n\tsay 1:\n\t}\n}\n"
+	want := "kampung main\n\nfunc f(x int) {\n\ttengok x {\n\tkalau 1:\n\t}\n}\n"
+	if got, _, _ := Source(src, from, to); got != want {
+		t.Errorf("Source() = %q, want %q", got, want)
+	}
+
+	got, changes, _ := Source(want, to, from)
+	if want := "kampung main\n\nfunc f(x int) {\n\tsee how x {\n\tsay 1:\n\t}\n}\n"; got != want {
+		t.Errorf("Source() back = %q, want %q", got, want)
+	}
+	if len(changes) != 2 || changes[0].New != "see how" {
+		t.Errorf("Source() back changes = %+v", changes)
+	}
+}
+
+func TestSourceShadowed(t *testing.T) {
+	from := load(t, "kampung: package\n")
+	to := load(t, "kampung: package\nlepak: continue\n")
+
+	src := "kampung main\n\nfunc main() {\n\tlepak := 1\n\t_ = lepak\n}\n"
+	got, changes, diags := Source(src, from, to)
+	if got != src || len(changes) != 0 {
+		t.Errorf("Source() rewrote %+v", changes)
+	}
+	if len(diags) != 2 || diags[0].Code != lexer.CodeMigrateShadowed || diags[1].Line != 5 {
+		t.Errorf("Source() diagnostics = %+v, want two warnings about lepak", diags)
+	}
+}
+
+func TestSourceLexError(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := "kampung main\n\"unterminated\n"
+	got, changes, diags := Source(src, dict, dict)
+	if got != src || changes != nil || len(diags) == 0 || diags[0].Code != lexer.CodeUnterminatedString {
+		t.Errorf("Source() = %q, %+v, %+v; want the source back with the lexer's error", got, changes, diags)
+	}
+}
@@ -2,8 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"go/token"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/rickchow/singlish/pkg/ast"
 	"github.com/rickchow/singlish/pkg/dictionaries"
@@ -78,6 +80,7 @@ type Parser struct {
 	infixParseFns  map[lexer.TokenType]infixParseFn
 
 	noCompositeLiteral bool
+	reportedEOF        bool // an unclosed brace has already been reported
 }
 
 func New(tokens []lexer.Token, dict *dictionaries.Dictionary) *Parser {
@@ -117,7 +120,7 @@ func (p *Parser) nextToken() {
 			p.peekToken = p.tokens[p.pos]
 			p.pos++
 		} else {
-			p.peekToken = lexer.Token{Type: "EOF", Value: ""}
+			p.peekToken = p.eofToken()
 		}
 
 		if p.peekToken.Type != lexer.TokenComment {
@@ -126,6 +129,42 @@ func (p *Parser) nextToken() {
 	}
 }
 
+// eofToken returns the EOF token, positioned just after the last token.
+func (p *Parser) eofToken() lexer.Token {
+	eof := lexer.Token{Type: "EOF", Value: ""}
+	if n := len(p.tokens); n > 0 {
+		last := p.tokens[n-1]
+		eof.Line = last.Line + strings.Count(last.Value, "\n")
+		eof.Col = last.Col + utf8.RuneCountInString(last.Value)
+		if i := strings.LastIndexByte(last.Value, '\n'); i >= 0 {
+			eof.Col = 1 + utf8.RuneCountInString(last.Value[i+1:])
+		}
+	}
+	return eof
+}
+
+// unclosedError reports that the end of the file was reached before the
+// closing brace of the what that open started. Only the first unclosed
+// brace is reported, as the enclosing ones are unclosed for the same reason.
+func (p *Parser) unclosedError(open lexer.Token, what string) {
+	if p.reportedEOF {
+		return
+	}
+	p.reportedEOF = true
+	p.errors = append(p.errors, lexer.Diagnostic{
+		Message: fmt.Sprintf("expected } to close %s, reached end of file", what),
+		Line:    p.curToken.Line,
+		Col:     p.curToken.Col,
+		Code:    lexer.CodeUnclosedBlock,
+		Labels: []lexer.Label{{
+			Line:    open.Line,
+			Col:     open.Col,
+			Length:  utf8.RuneCountInString(open.Value),
+			Message: what + " opened here",
+		}},
+	})
+}
+
 func (p *Parser) curTokenIs(t lexer.TokenType) bool {
 	return p.curToken.Type == t
 }
@@ -170,7 +209,8 @@ func (p *Parser) peekError(t lexer.TokenType, expectedValue string) {
 		Message: msg,
 		Line:    p.peekToken.Line,
 		Col:     p.peekToken.Col,
-		Length:  len(p.peekToken.Value),
+		Length:  utf8.RuneCountInString(p.peekToken.Value),
+		Code:    lexer.CodeUnexpectedToken,
 	})
 }
 
@@ -187,6 +227,11 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.curToken.Type != "EOF" {
+		if group, ok := p.parseImportGroup(); ok {
+			program.Statements = append(program.Statements, group...)
+			p.nextToken()
+			continue
+		}
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
@@ -206,6 +251,14 @@ func (p *Parser) parseStatement() ast.Statement {
 		}
 	}
 
+	if p.keywordAsName(canonical) {
+		s := p.parseExpressionStatement()
+		if s == nil {
+			return nil
+		}
+		return s
+	}
+
 	switch canonical {
 	case "package":
 		s := p.parsePackageStatement()
@@ -335,7 +388,8 @@ func (p *Parser) parseGoStatement() *ast.GoStatement {
 		Message: msg,
 		Line:    stmt.Token.Line,
 		Col:     stmt.Token.Col,
-		Length:  len(stmt.Token.Value),
+		Length:  utf8.RuneCountInString(stmt.Token.Value),
+		Code:    lexer.CodeGoNeedsCall,
 	})
 	return nil
 }
@@ -355,11 +409,38 @@ func (p *Parser) parseDeferStatement() *ast.DeferStatement {
 		Message: msg,
 		Line:    stmt.Token.Line,
 		Col:     stmt.Token.Col,
-		Length:  len(stmt.Token.Value),
+		Length:  utf8.RuneCountInString(stmt.Token.Value),
+		Code:    lexer.CodeDeferNeedsCall,
 	})
 	return nil
 }
 
+// keywordAsName reports whether the current token, which means canonical,
+// is a Go keyword written as a name. Only dictionary words are keywords in
+// Singlish, but Go's own spelling is read as the keyword where the syntax
+// after it follows, as in interface{} or func(x int) {...}; elsewhere, as
+// in `type := 4` or gong(func), it is an ordinary name.
+func (p *Parser) keywordAsName(canonical string) bool {
+	if p.curToken.Type != lexer.TokenIdentifier || p.curToken.Value != canonical || !token.IsKeyword(canonical) {
+		return false
+	}
+	next := p.peekToken.Value
+	if p.peekTokenIs(lexer.TokenString) || p.peekTokenIs(lexer.TokenNumber) {
+		next = ""
+	}
+	switch canonical {
+	case "struct", "interface":
+		return next != "{"
+	case "func":
+		return next != "(" && !p.peekTokenIs(lexer.TokenIdentifier)
+	}
+	switch next {
+	case "=", ":=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<=", ">>=", "&^=", ",", ".", "[", "++", "--":
+		return true
+	}
+	return false
+}
+
 func (p *Parser) peekCanonical(kw string) bool {
 	val := p.peekToken.Value
 	if p.dict != nil {
@@ -521,6 +602,39 @@ func (p *Parser) parseImportStatement() *ast.ImportStatement {
 	return stmt
 }
 
+// parseImportGroup parses a grouped import such as dapao ( "fmt" "os" )
+// into one ImportStatement per path, as fmt writes them. It reports false,
+// consuming nothing, if the current token does not start one.
+func (p *Parser) parseImportGroup() ([]ast.Statement, bool) {
+	keyword := p.curToken.Value
+	if p.dict != nil {
+		if val, found := p.dict.Lookup(keyword); found {
+			keyword = val
+		}
+	}
+	if keyword != "import" || !p.peekTokenIs(lexer.TokenPunctuation) || p.peekToken.Value != "(" {
+		return nil, false
+	}
+	tok := p.curToken
+	p.nextToken()
+
+	var group []ast.Statement
+	for !(p.peekTokenIs(lexer.TokenPunctuation) && p.peekToken.Value == ")") {
+		if !p.expectPeekType(lexer.TokenString) {
+			return group, true
+		}
+		group = append(group, &ast.ImportStatement{
+			Token: tok,
+			Path:  &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Value},
+		})
+		if p.peekTokenIs(lexer.TokenPunctuation) && p.peekToken.Value == ";" {
+			p.nextToken()
+		}
+	}
+	p.nextToken()
+	return group, true
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
@@ -574,6 +688,13 @@ func (p *Parser) parseIdentifierListForLet() []*ast.Identifier {
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// A return with nothing after it on the same line returns no values,
+	// as in Go. Leave the closing brace for the enclosing block.
+	if p.peekTokenIs("EOF") || p.peekToken.Line != stmt.Token.Line ||
+		(p.peekTokenIs(lexer.TokenPunctuation) && p.peekToken.Value == "}") {
+		return stmt
+	}
+
 	p.nextToken()
 
 	// Handle explicit return without value (if followed by ; or EOF)
@@ -707,16 +828,15 @@ func (p *Parser) parseIdentifier() ast.Expression {
 			canonical = val
 		}
 	}
-
-	if canonical == "struct" {
+	if canonical == "struct" && !p.keywordAsName(canonical) {
 		return p.parseStructLiteral()
 	}
-	if canonical == "interface" {
+	if canonical == "interface" && !p.keywordAsName(canonical) {
 		return p.parseInterfaceLiteral()
 	}
 
 	// Handle 'func' (action) as closure
-	if canonical == "func" {
+	if canonical == "func" && !p.keywordAsName(canonical) {
 		return p.parseFunctionLiteral()
 	}
 
@@ -731,8 +851,8 @@ func (p *Parser) parseIdentifier() ast.Expression {
 		return expression
 	}
 
-	// Handle channel receive (catch -> <-)
-	if canonical == "<-" {
+	// Handle channel receive (catch -> <-) and negation (dun -> !)
+	if canonical == "<-" || canonical == "!" {
 		expression := &ast.PrefixExpression{
 			Token:    p.curToken,
 			Operator: canonical,
@@ -797,7 +917,8 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 			Message: msg,
 			Line:    p.curToken.Line,
 			Col:     p.curToken.Col,
-			Length:  len(p.curToken.Value),
+			Length:  utf8.RuneCountInString(p.curToken.Value),
+			Code:    lexer.CodeInvalidNumber,
 		})
 		return nil
 	}
@@ -814,7 +935,8 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 			Message: msg,
 			Line:    p.curToken.Line,
 			Col:     p.curToken.Col,
-			Length:  len(p.curToken.Value),
+			Length:  utf8.RuneCountInString(p.curToken.Value),
+			Code:    lexer.CodeInvalidNumber,
 		})
 		return nil
 	}
@@ -1221,7 +1343,8 @@ func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
 		Message: msg,
 		Line:    p.curToken.Line,
 		Col:     p.curToken.Col,
-		Length:  len(p.curToken.Value),
+		Length:  utf8.RuneCountInString(p.curToken.Value),
+		Code:    lexer.CodeNoExpression,
 	})
 }
 
@@ -1256,6 +1379,16 @@ func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
 	p.nextToken()
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Value}
+	if p.curToken.Type == lexer.TokenIdentifier && p.curToken.Value == "func" {
+		p.errors = append(p.errors, lexer.Diagnostic{
+			Message: "func cannot be the name of a function",
+			Line:    p.curToken.Line,
+			Col:     p.curToken.Col,
+			Length:  len("func"),
+			Code:    lexer.CodeFuncName,
+			Notes:   []string{"a call such as func(3) would start a function literal; choose another name"},
+		})
+	}
 
 	if !p.expectPeek(lexer.TokenPunctuation, "(") {
 		return nil
@@ -1526,6 +1659,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	for !p.curTokenIs(lexer.TokenPunctuation) || p.curToken.Value != "}" {
 		if p.curToken.Type == "EOF" {
+			p.unclosedError(block.Token, "block")
 			break
 		}
 		stmt := p.parseStatement()
@@ -1552,11 +1686,13 @@ func (p *Parser) parseStructLiteral() ast.Expression {
 
 func (p *Parser) parseStructFields() []*ast.FieldDefinition {
 	fields := []*ast.FieldDefinition{}
+	open := p.curToken
 
 	p.nextToken()
 
 	for !p.curTokenIs(lexer.TokenPunctuation) || p.curToken.Value != "}" {
 		if p.curToken.Type == "EOF" {
+			p.unclosedError(open, "struct")
 			break
 		}
 
@@ -1620,11 +1756,13 @@ func (p *Parser) parseInterfaceLiteral() ast.Expression {
 
 func (p *Parser) parseInterfaceMethods() []*ast.MethodDefinition {
 	methods := []*ast.MethodDefinition{}
+	open := p.curToken
 
 	p.nextToken()
 
 	for !p.curTokenIs(lexer.TokenPunctuation) || p.curToken.Value != "}" {
 		if p.curToken.Type == "EOF" {
+			p.unclosedError(open, "interface")
 			break
 		}
 
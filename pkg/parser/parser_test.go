@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
 	"github.com/rickchow/singlish/pkg/lexer"
 )
 
@@ -82,6 +83,44 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingPrefixKeywords(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	prefixTests := []struct {
+		input    string
+		operator string
+	}{
+		{"dun x", "!"},
+		{"catch x", "<-"},
+		{"ki x", "*"},
+	}
+
+	for _, tt := range prefixTests {
+		tokens, _ := lexer.Lex(tt.input, keywords)
+		p := New(tokens, dict)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: got %d statements, want 1", tt.input, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("%q: statement is %T, want *ast.ExpressionStatement", tt.input, program.Statements[0])
+		}
+		exp, ok := stmt.Expression.(*ast.PrefixExpression)
+		if !ok {
+			t.Fatalf("%q: expression is %T, want *ast.PrefixExpression", tt.input, stmt.Expression)
+		}
+		if exp.Operator != tt.operator {
+			t.Errorf("%q: operator = %q, want %q", tt.input, exp.Operator, tt.operator)
+		}
+	}
+}
+
 func TestParsingInfixExpressions(t *testing.T) {
 	infixTests := []struct {
 		input      string
@@ -232,6 +271,28 @@ func TestCallExpressionParsing(t *testing.T) {
 	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 }
 
+func TestUnclosedBlockError(t *testing.T) {
+	input := "func main() {\n\tif x {\n\t\tfoo()\n\t}\n\tbar()\n"
+	tokens, _ := lexer.Lex(input, nil)
+	p := New(tokens, nil)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errors), errors)
+	}
+	diag := errors[0]
+	if diag.Code != lexer.CodeUnclosedBlock {
+		t.Errorf("diag.Code = %q, want %q", diag.Code, lexer.CodeUnclosedBlock)
+	}
+	if diag.Line != 5 || diag.Col != 7 {
+		t.Errorf("error at %d:%d, want end of file at 5:7", diag.Line, diag.Col)
+	}
+	if len(diag.Labels) != 1 || diag.Labels[0].Line != 1 || diag.Labels[0].Col != 13 {
+		t.Errorf("diag.Labels = %+v, want the { on line 1", diag.Labels)
+	}
+}
+
 func checkParserErrors(t *testing.T, p *Parser) {
 	errors := p.Errors()
 	if len(errors) == 0 {
@@ -324,3 +385,103 @@ func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{
 	t.Errorf("type of exp not handled. got=%T", exp)
 	return false
 }
+
+func TestGroupedImports(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, _ := lexer.Lex("kampung main\n\ndapao (\n\t\"fmt\"\n\t\"math/rand\";\n)\ndapao \"os\"\n", keywords)
+
+	p := New(tokens, dict)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	want := []string{`"fmt"`, `"math/rand"`, `"os"`}
+	if len(program.Statements) != len(want)+1 {
+		t.Fatalf("program has %d statements, want %d", len(program.Statements), len(want)+1)
+	}
+	for i, path := range want {
+		stmt, ok := program.Statements[i+1].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.ImportStatement. got=%T", i+1, program.Statements[i+1])
+		}
+		if stmt.Path.Value != path {
+			t.Errorf("import %d = %s, want %s", i, stmt.Path.Value, path)
+		}
+	}
+}
+
+func TestGoKeywordsAsNames(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tests := []struct {
+		input string
+		want  string // type of the statement, or of the expression statement's expression
+	}{
+		{"type := 4", "*ast.InfixExpression"},
+		{"func = nil", "*ast.InfixExpression"},
+		{"select++", "*ast.IncDecStatement"},
+		{"gong(func, struct, interface)", "*ast.CallExpression"},
+		{"type Kedai struct {\n\tx int\n}", "*ast.TypeStatement"},
+		{"func open() {}", "*ast.FunctionStatement"},
+		{"interface{}", "*ast.InterfaceLiteral"},
+	}
+	for _, tt := range tests {
+		tokens, _ := lexer.Lex(tt.input, keywords)
+		p := New(tokens, dict)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: got %d statements, want 1", tt.input, len(program.Statements))
+		}
+		var node any = program.Statements[0]
+		if stmt, ok := node.(*ast.ExpressionStatement); ok {
+			node = stmt.Expression
+		}
+		if got := fmt.Sprintf("%T", node); got != tt.want {
+			t.Errorf("%q: parsed as %s, want %s", tt.input, got, tt.want)
+		}
+	}
+
+	for input, want := range map[string]string{
+		"gong(func, struct, interface)": "*ast.Identifier",
+		"run(func(x int) {})":           "*ast.FunctionLiteral",
+	} {
+		tokens, _ := lexer.Lex(input, keywords)
+		p := New(tokens, dict)
+		call := p.ParseProgram().Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+		for _, arg := range call.Arguments {
+			if got := fmt.Sprintf("%T", arg); got != want {
+				t.Errorf("%q: argument %s is %s, want %s", input, arg, got, want)
+			}
+		}
+	}
+}
+
+func TestFuncAsFunctionName(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	for _, input := range []string{
+		"action func(x nombor) nombor {\n\tbalek x\n}",
+		"action (t T) func() {}",
+	} {
+		tokens, _ := lexer.Lex(input, keywords)
+		p := New(tokens, dict)
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0].Code != lexer.CodeFuncName {
+			t.Fatalf("%q: errors = %+v, want one %s", input, errors, lexer.CodeFuncName)
+		}
+		if errors[0].Line != 1 || errors[0].Length != 4 {
+			t.Errorf("%q: error at line %d, length %d, want the func on line 1", input, errors[0].Line, errors[0].Length)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/interp"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/typecheck"
+)
+
+// Session accumulates Singlish declarations and statements across REPL entries.
+// Entries run in the interpreter, in a session that lasts as long as the REPL,
+// so variables keep their values and each statement takes effect once. Before
+// an entry runs, the whole session is type-checked as the Go program it
+// translates to, so entries that Go would reject are refused.
+type Session struct {
+	dict    *dictionaries.Dictionary
+	stdout  io.Writer
+	stderr  io.Writer
+	it      *interp.Interpreter
+	imports []string
+	decls   []declaration
+	stmts   []string // sources of earlier statements, for the Go program
+	lastGo  string
+}
+
+type declaration struct {
+	name   string
+	source string
+}
+
+// NewSession creates an empty session whose entries write to stdout and
+// stderr.
+func NewSession(dict *dictionaries.Dictionary, stdout, stderr io.Writer) *Session {
+	s := &Session{dict: dict, stdout: stdout, stderr: stderr}
+	s.Reset()
+	return s
+}
+
+// LastGo returns the Go program generated for the most recent entry.
+func (s *Session) LastGo() string {
+	return s.lastGo
+}
+
+// Reset forgets all declarations and statements.
+func (s *Session) Reset() {
+	s.it = interp.New(s.dict)
+	s.it.Stdout = s.stdout
+	s.it.Stderr = s.stderr
+	s.imports = nil
+	s.decls = nil
+	s.stmts = nil
+	s.lastGo = ""
+}
+
+// Eval evaluates a complete entry. Entries that Go would reject are not run
+// and not added to the session; an entry that fails while running stays,
+// with whatever it did before failing.
+func (s *Session) Eval(input string) error {
+	program, err := transpiler.Parse(input, s.dict)
+	if err != nil {
+		return err
+	}
+
+	sources := statementSources(input, program.Statements)
+	var imports []string
+	var decls []declaration
+	var stmts []string
+	for i, stmt := range program.Statements {
+		switch st := stmt.(type) {
+		case *ast.PackageStatement:
+			// Every session lives in kampung main.
+		case *ast.ImportStatement:
+			imports = append(imports, strings.Trim(st.Path.Value, "\"`"))
+		case *ast.FunctionStatement:
+			name := st.Name.Value
+			if st.Receiver != nil {
+				name = st.Receiver.Type.String() + "." + name
+			}
+			decls = append(decls, declaration{name: name, source: sources[i]})
+		case *ast.TypeStatement:
+			decls = append(decls, declaration{name: st.Name.Value, source: sources[i]})
+		default:
+			stmts = append(stmts, sources[i])
+			stmts = append(stmts, keepAlive(stmt)...)
+		}
+	}
+
+	// A lone expression is shown like a calculator would; if Go refuses to print it
+	// (e.g. a call without results) fall back to running it as a plain statement.
+	if len(program.Statements) == 1 && isPrintable(program.Statements[0]) {
+		printed := []string{"fmt.Println(" + strings.TrimSuffix(strings.TrimSpace(input), ";") + ")"}
+		if err := s.check(imports, decls, printed, true); err == nil {
+			values, err := s.it.Eval(program.Statements[0].(*ast.ExpressionStatement).Expression)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(s.stdout, values...)
+			return nil
+		}
+	}
+
+	if err := s.check(imports, decls, stmts, false); err != nil {
+		return err
+	}
+	s.imports = uniq(append(s.imports, imports...))
+	s.decls = mergeDecls(s.decls, decls)
+	s.stmts = append(s.stmts, stmts...)
+	return s.it.Exec(program)
+}
+
+// check type-checks the session with entry added as the Go program it
+// translates to, which LastGo then returns. Code using packages outside
+// the standard library is not checked.
+func (s *Session) check(imports []string, decls []declaration, entry []string, needsFmt bool) error {
+	allImports := append(append([]string{}, s.imports...), imports...)
+	if needsFmt {
+		allImports = append(allImports, "fmt")
+	}
+	source := s.assemble(allImports, mergeDecls(s.decls, decls), entry)
+	program, err := transpiler.ParseFile(source, s.dict)
+	if err != nil {
+		return err
+	}
+	goCode, err := codegen.Generate(program, s.dict)
+	if err != nil {
+		return fmt.Errorf("codegen error: %w", err)
+	}
+	s.lastGo = goCode
+
+	diags, err := typecheck.Check(source, program, s.dict)
+	if err != nil {
+		return err
+	}
+	var msgs []string
+	for _, d := range diags {
+		if d.Severity == lexer.SeverityError {
+			msgs = append(msgs, d.Message)
+		}
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
+
+// assemble builds a full Singlish program from the session state plus a new entry.
+func (s *Session) assemble(imports []string, decls []declaration, entry []string) string {
+	var body bytes.Buffer
+	for _, stmt := range s.stmts {
+		body.WriteString(stmt + "\n")
+	}
+	for _, stmt := range entry {
+		body.WriteString(stmt + "\n")
+	}
+
+	var declSrc bytes.Buffer
+	for _, d := range decls {
+		declSrc.WriteString(d.source + "\n\n")
+	}
+
+	var out bytes.Buffer
+	out.WriteString(s.keyword("package") + " main\n")
+	// Only import packages that are actually referenced; Go rejects unused imports.
+	used := declSrc.String() + body.String()
+	for _, imp := range uniq(append([]string{"fmt"}, imports...)) {
+		if refersTo(used, path.Base(imp)) {
+			out.WriteString(s.keyword("import") + " " + fmt.Sprintf("%q", imp) + "\n")
+		}
+	}
+	out.WriteString("\n")
+	out.Write(declSrc.Bytes())
+	out.WriteString(s.keyword("func") + " " + s.keyword("main") + "() {\n")
+	out.Write(body.Bytes())
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// keyword returns the Singlish spelling of a Go keyword in the session dictionary.
+func (s *Session) keyword(goKeyword string) string {
+	if s.dict != nil {
+		if kw, ok := s.dict.ReverseLookup(goKeyword); ok {
+			return kw
+		}
+	}
+	return goKeyword
+}
+
+// Depth reports how many brackets are still open at the end of input.
+// The REPL keeps reading lines while the depth is positive.
+func Depth(input string) int {
+	tokens, diagnostics := lexer.Lex(input, nil)
+	for _, d := range diagnostics {
+		// A raw string or block comment spanning lines is not finished yet.
+		if strings.HasPrefix(d.Message, "unterminated") {
+			return 1
+		}
+	}
+	depth := 0
+	for _, tok := range tokens {
+		if tok.Type != lexer.TokenPunctuation {
+			continue
+		}
+		switch tok.Value {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+	}
+	return depth
+}
+
+// isPrintable reports whether stmt is a bare expression whose value can be shown.
+func isPrintable(stmt ast.Statement) bool {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok || es.Expression == nil {
+		return false
+	}
+	switch e := es.Expression.(type) {
+	case *ast.IncDecStatement:
+		return false
+	case *ast.InfixExpression:
+		switch e.Operator {
+		case "=", ":=", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<-":
+			return false
+		}
+	case *ast.Identifier:
+		switch e.Value {
+		case "break", "continue", "fallthrough":
+			return false
+		}
+	case *ast.CallExpression:
+		return !isPrintCall(e)
+	}
+	return true
+}
+
+// isPrintCall reports whether call already writes its own output (gong, fmt.Printf, ...)
+// or is a builtin without a result, so wrapping it in fmt.Println would be wrong.
+func isPrintCall(call *ast.CallExpression) bool {
+	switch fn := call.Function.(type) {
+	case *ast.Identifier:
+		switch fn.Value {
+		case "print", "println", "panic", "close", "delete":
+			return true
+		}
+		return strings.HasPrefix(fn.Value, "fmt.Print") || strings.HasPrefix(fn.Value, "fmt.Fprint")
+	case *ast.InfixExpression:
+		left, lok := fn.Left.(*ast.Identifier)
+		right, rok := fn.Right.(*ast.Identifier)
+		if fn.Operator == "." && lok && rok && left.Value == "fmt" {
+			return strings.HasPrefix(right.Value, "Print") || strings.HasPrefix(right.Value, "Fprint")
+		}
+	}
+	return false
+}
+
+// keepAlive returns statements that mark newly declared variables as used,
+// so that Go does not reject a session that declares a variable and never reads it.
+func keepAlive(stmt ast.Statement) []string {
+	var names []string
+	switch st := stmt.(type) {
+	case *ast.LetStatement:
+		for _, n := range st.Names {
+			names = append(names, n.Value)
+		}
+	case *ast.ExpressionStatement:
+		if infix, ok := st.Expression.(*ast.InfixExpression); ok && infix.Operator == ":=" {
+			if ident, ok := infix.Left.(*ast.Identifier); ok {
+				names = append(names, ident.Value)
+			}
+		}
+	}
+	var out []string
+	for _, n := range names {
+		if n != "_" {
+			out = append(out, "_ = "+n)
+		}
+	}
+	return out
+}
+
+// statementSources slices input into the original text of each top-level statement.
+// A statement runs from its first token up to the first token of the next statement.
+func statementSources(input string, stmts []ast.Statement) []string {
+	runes := []rune(input)
+	lineStarts := []int{0}
+	for i, r := range runes {
+		if r == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(stmt ast.Statement) int {
+		line, col := ast.Position(stmt)
+		if line < 1 || line > len(lineStarts) {
+			return -1
+		}
+		off := lineStarts[line-1] + col - 1
+		if off < 0 || off > len(runes) {
+			return -1
+		}
+		return off
+	}
+
+	sources := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		start := offset(stmt)
+		end := len(runes)
+		for j := i + 1; j < len(stmts); j++ {
+			if next := offset(stmts[j]); next > start {
+				end = next
+				break
+			}
+		}
+		if start < 0 {
+			sources[i] = stmt.String()
+			continue
+		}
+		sources[i] = strings.TrimSpace(string(runes[start:end]))
+	}
+	return sources
+}
+
+func mergeDecls(existing, added []declaration) []declaration {
+	out := append([]declaration{}, existing...)
+	for _, d := range added {
+		replaced := false
+		for i := range out {
+			if out[i].name == d.name {
+				out[i] = d
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func refersTo(source, pkg string) bool {
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(pkg) + `\.`).MatchString(source)
+}
+
+func uniq(list []string) []string {
+	seen := make(map[string]struct{})
+	out := []string{}
+	for _, s := range list {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		out = append(out, s)
+	}
+	return out
+}
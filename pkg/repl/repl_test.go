@@ -0,0 +1,103 @@
+package repl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+)
+
+func TestDepth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"got x = 5\n", 0},
+		{"action foo() {\n", 1},
+		{"action foo() {\n  nasi can {\n", 2},
+		{"action foo() {\n  gong(\"}\")\n}\n", 0},
+		{"got s = `raw\n", 1},
+		{"gong(1,\n", 1},
+	}
+	for _, tt := range tests {
+		if got := Depth(tt.input); got != tt.want {
+			t.Errorf("Depth(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// newSession returns a session with the default dictionary and what it
+// prints.
+func newSession() (*Session, *bytes.Buffer) {
+	var out bytes.Buffer
+	return NewSession(dictionaries.NewDefaultDictionary(), &out, &out), &out
+}
+
+func TestSessionKeepsDeclarations(t *testing.T) {
+	s, out := newSession()
+
+	if err := s.Eval("got x = 5\n"); err != nil {
+		t.Fatalf("Eval(got) failed: %v", err)
+	}
+	if err := s.Eval("action double(n nombor) nombor {\n\tbalek n * 2\n}\n"); err != nil {
+		t.Fatalf("Eval(action) failed: %v", err)
+	}
+	if err := s.Eval("double(x)\n"); err != nil {
+		t.Fatalf("Eval(expr) failed: %v", err)
+	}
+
+	if out.String() != "10\n" {
+		t.Errorf("printed %q, want %q", out.String(), "10\n")
+	}
+	for _, want := range []string{"var x = 5", "func double(n int) int", "fmt.Println(double(x))"} {
+		if !strings.Contains(s.LastGo(), want) {
+			t.Errorf("expected generated program to contain %q, got:\n%s", want, s.LastGo())
+		}
+	}
+}
+
+func TestSessionKeepsValues(t *testing.T) {
+	s, out := newSession()
+
+	// Each entry runs once: the value is not computed again, and the
+	// output of earlier entries is not repeated.
+	for _, entry := range []string{"got t = time.Now().UnixNano()\n", "gong(\"hello\")\n", "t\n", "t\n"} {
+		if err := s.Eval(entry); err != nil {
+			t.Fatalf("Eval(%q) failed: %v", entry, err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || lines[0] != "hello" || lines[1] != lines[2] {
+		t.Errorf("printed %q, want hello and the same time twice", lines)
+	}
+}
+
+func TestSessionDiscardsFailedEntries(t *testing.T) {
+	s, _ := newSession()
+
+	err := s.Eval("got z nombor = \"a\"\n")
+	if err == nil || !strings.Contains(err.Error(), "cannot use") {
+		t.Fatalf("Eval() = %v, want a type error", err)
+	}
+	if err := s.Eval("got z = 1\n"); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if strings.Contains(s.LastGo(), `"a"`) {
+		t.Errorf("failed entry should not be kept, got:\n%s", s.LastGo())
+	}
+}
+
+func TestSessionDoesNotPrintPrintCalls(t *testing.T) {
+	s, out := newSession()
+
+	if err := s.Eval("gong(\"hello\")\n"); err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if strings.Contains(s.LastGo(), "fmt.Println(fmt.Println") {
+		t.Errorf("gong should not be wrapped in another print, got:\n%s", s.LastGo())
+	}
+	if out.String() != "hello\n" {
+		t.Errorf("printed %q, want %q", out.String(), "hello\n")
+	}
+}
@@ -0,0 +1,309 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// Diagnostic is a lexer.Diagnostic together with the file it was found in.
+type Diagnostic struct {
+	Filename string
+	lexer.Diagnostic
+}
+
+// String formats d as file:line:col: message, the form editors and CI
+// annotations understand.
+func (d Diagnostic) String() string {
+	pos := strconv.Itoa(d.Line)
+	if d.Col > 0 {
+		pos += ":" + strconv.Itoa(d.Col)
+	}
+	if d.Filename == "" {
+		return fmt.Sprintf("%s: %s", pos, d.Message)
+	}
+	return fmt.Sprintf("%s:%s: %s", d.Filename, pos, d.Message)
+}
+
+// code returns d's code, treating diagnostics without one as general errors.
+func (d Diagnostic) code() string {
+	if d.Code == "" {
+		return lexer.CodeGeneral
+	}
+	return d.Code
+}
+
+// InFile attaches filename to each of diags.
+func InFile(filename string, diags []lexer.Diagnostic) []Diagnostic {
+	out := make([]Diagnostic, len(diags))
+	for i, d := range diags {
+		out[i] = Diagnostic{Filename: filename, Diagnostic: d}
+	}
+	return out
+}
+
+// compilerLine matches the file:line[:col]: message lines printed by the Go
+// toolchain.
+var compilerLine = regexp.MustCompile(`^([^\s#][^:]*):(\d+)(?::(\d+))?: (.*)$`)
+
+// ParseCompilerOutput extracts diagnostics from the output of go build or
+// go vet. Indented lines continue the message above them; anything else,
+// such as "# package" headers, is ignored.
+func ParseCompilerOutput(output string) []Diagnostic {
+	var diags []Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		if m := compilerLine.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{Filename: m[1]}
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Col, _ = strconv.Atoi(m[3])
+			d.Message = m[4]
+			d.Code = lexer.CodeGoCompiler
+			diags = append(diags, d)
+		} else if len(diags) > 0 && strings.HasPrefix(line, "\t") {
+			last := &diags[len(diags)-1]
+			last.Message += "\n" + strings.TrimPrefix(line, "\t")
+		}
+	}
+	return diags
+}
+
+var (
+	// testFailure matches the line go test prints for a failed test.
+	testFailure = regexp.MustCompile(`^(\s*)--- FAIL: (\S+)`)
+	// testMessage matches a file:line: message line logged by a test.
+	testMessage = regexp.MustCompile(`^(\S[^:]*):(\d+): (.*)$`)
+	// stackFrame matches a stack trace line in a Singlish file.
+	stackFrame = regexp.MustCompile(`^\t(.+\.singlish):(\d+)`)
+)
+
+// ParseTestOutput extracts the failures from the output of go test: each
+// message a failed test logged with its file and line, and each panic at
+// the first Singlish line of its stack. A test that failed without either
+// is reported without a position. Compile errors are left to
+// ParseCompilerOutput.
+func ParseTestOutput(output string) []Diagnostic {
+	var diags []Diagnostic
+	var failed []string          // tests in the order they failed
+	located := map[string]bool{} // tests with a diagnostic of their own
+	test, indent := "", 0        // the failed test whose output is being read
+	var panicked *Diagnostic
+	for _, line := range strings.Split(output, "\n") {
+		if m := testFailure.FindStringSubmatch(line); m != nil {
+			test, indent = m[2], len(m[1])
+			failed = append(failed, test)
+			continue
+		}
+		if strings.HasPrefix(line, "panic: ") && panicked == nil {
+			msg := line
+			if test != "" {
+				msg = test + ": " + line
+				located[test] = true
+			}
+			diags = append(diags, Diagnostic{Diagnostic: lexer.Diagnostic{Message: msg, Code: lexer.CodeTestFailure}})
+			panicked = &diags[len(diags)-1]
+			continue
+		}
+		if m := stackFrame.FindStringSubmatch(line); m != nil && panicked != nil && panicked.Filename == "" {
+			panicked.Filename = m[1]
+			panicked.Line, _ = strconv.Atoi(m[2])
+			continue
+		}
+		if test == "" {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, " "))
+		text := strings.TrimSpace(line)
+		if m := testMessage.FindStringSubmatch(text); m != nil && depth == indent+4 {
+			d := Diagnostic{Filename: m[1]}
+			d.Line, _ = strconv.Atoi(m[2])
+			d.Message = test + ": " + m[3]
+			d.Code = lexer.CodeTestFailure
+			diags = append(diags, d)
+			located[test] = true
+		} else if depth > indent+4 && located[test] && len(diags) > 0 {
+			last := &diags[len(diags)-1]
+			last.Message += "\n" + text
+		} else if depth <= indent {
+			test = ""
+		}
+	}
+	for _, name := range failed {
+		// A test with failed subtests is reported through them.
+		parent := slices.ContainsFunc(failed, func(sub string) bool { return strings.HasPrefix(sub, name+"/") })
+		if !located[name] && !parent {
+			diags = append(diags, Diagnostic{Diagnostic: lexer.Diagnostic{Message: name + " failed", Code: lexer.CodeTestFailure}})
+		}
+	}
+	return diags
+}
+
+type jsonDiagnostic struct {
+	File     string      `json:"file,omitempty"`
+	Line     int         `json:"line,omitempty"`
+	Column   int         `json:"column,omitempty"`
+	Length   int         `json:"length,omitempty"`
+	Severity string      `json:"severity"`
+	Code     string      `json:"code"`
+	Message  string      `json:"message"`
+	EndLine  int         `json:"endLine,omitempty"`
+	EndCol   int         `json:"endColumn,omitempty"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Notes    []string    `json:"notes,omitempty"`
+}
+
+type jsonLabel struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Length  int    `json:"length"`
+	Message string `json:"message"`
+}
+
+// WriteJSON writes diags as a JSON array. The file, line, column and
+// length are left out when unknown, as the column of a Go compiler error
+// is.
+func WriteJSON(w io.Writer, diags []Diagnostic) error {
+	out := make([]jsonDiagnostic, 0, len(diags))
+	for _, d := range diags {
+		jd := jsonDiagnostic{
+			File:     d.Filename,
+			Line:     d.Line,
+			Column:   d.Col,
+			Length:   d.Length,
+			Severity: d.Severity.String(),
+			Code:     d.code(),
+			Message:  d.Message,
+			EndLine:  d.EndLine,
+			EndCol:   d.EndCol,
+			Notes:    d.Notes,
+		}
+		for _, l := range d.Labels {
+			jd.Labels = append(jd.Labels, jsonLabel{Line: l.Line, Column: l.Col, Length: l.Length, Message: l.Message})
+		}
+		out = append(out, jd)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// SARIF 2.1.0, the subset needed to report results.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name           string      `json:"name"`
+		Version        string      `json:"version,omitempty"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID string `json:"id"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+		Related   []sarifLocation `json:"relatedLocations,omitempty"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+		Message          *sarifMessage         `json:"message,omitempty"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine   int `json:"startLine"`
+		StartColumn int `json:"startColumn,omitempty"`
+		EndLine     int `json:"endLine,omitempty"`
+		EndColumn   int `json:"endColumn,omitempty"`
+	}
+)
+
+// WriteSARIF writes diags as a SARIF 2.1.0 log, the format code scanning
+// services use to annotate pull requests. toolVersion may be empty.
+func WriteSARIF(w io.Writer, diags []Diagnostic, toolVersion string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "singlish",
+			Version:        toolVersion,
+			InformationURI: "https://github.com/rickchow88/singlish",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	seen := make(map[string]bool)
+	for _, d := range diags {
+		code := d.code()
+		if !seen[code] {
+			seen[code] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: code})
+		}
+		message := d.Message
+		for _, note := range d.Notes {
+			message += "\nnote: " + note
+		}
+		result := sarifResult{
+			RuleID:  code,
+			Level:   d.Severity.String(),
+			Message: sarifMessage{Text: message},
+		}
+		if d.Filename != "" {
+			uri := sarifArtifactLocation{URI: filepath.ToSlash(d.Filename)}
+			loc := sarifPhysicalLocation{ArtifactLocation: uri}
+			if d.Line > 0 {
+				loc.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Col}
+				if d.EndLine > d.Line {
+					loc.Region.EndLine, loc.Region.EndColumn = d.EndLine, d.EndCol
+				} else if d.Col > 0 && d.Length > 0 {
+					loc.Region.EndColumn = d.Col + d.Length
+				}
+			}
+			result.Locations = []sarifLocation{{PhysicalLocation: loc}}
+			for _, l := range d.Labels {
+				region := &sarifRegion{StartLine: l.Line, StartColumn: l.Col}
+				if l.Col > 0 && l.Length > 0 {
+					region.EndColumn = l.Col + l.Length
+				}
+				result.Related = append(result.Related, sarifLocation{
+					PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: uri, Region: region},
+					Message:          &sarifMessage{Text: l.Message},
+				})
+			}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
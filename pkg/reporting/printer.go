@@ -3,57 +3,287 @@ package reporting
 import (
 	"fmt"
 	"io"
+	"os"
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/rickchow/singlish/pkg/lexer"
 )
 
-// PrintErrorWithContext prints a diagnostic with the source line and a caret pointing to the error.
-func PrintErrorWithContext(out io.Writer, source string, diag lexer.Diagnostic) {
-	lines := strings.Split(source, "\n")
-	lineIndex := diag.Line - 1 // Line is 1-based
-
-	fmt.Fprintf(out, "Error on line %d: %s\n", diag.Line, diag.Message)
-
-	if lineIndex >= 0 && lineIndex < len(lines) {
-		line := lines[lineIndex]
-
-		// Print the line code
-		fmt.Fprintf(out, "%s\n", line)
-
-		// Create pointer string
-		// diag.Col is 1-based.
-		pad := ""
-		if diag.Col > 1 {
-			// We need to replicate the indentation (tabs/spaces) to ensure caret aligns correctly.
-			// Taking the substring up to the error column allows us to mirror whitespace.
-			if diag.Col-1 <= len(line) {
-				prefix := line[:diag.Col-1]
-				for _, r := range prefix {
-					if r == '\t' {
-						pad += "\t"
-					} else {
-						pad += " "
-					}
-				}
-			} else {
-				// Fallback if column is out of bounds (shouldn't happen ideally)
-				pad = strings.Repeat(" ", diag.Col-1)
+// ANSI escape sequences used when colour is on.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiCyan   = "\x1b[1;36m"
+	ansiBlue   = "\x1b[1;34m"
+)
+
+// Printer renders diagnostics for people: a header with the severity and
+// code, the source lines around the problem with line numbers in a gutter,
+// underlines for the problem and any labels, and notes.
+//
+//	error[SG2006]: expected } to close block, reached end of file
+//	 --> kopi.singlish:7:1
+//	  |
+//	3 | action boss() {
+//	  |               - block opened here
+//	...
+//	6 |     gong(x)
+//	7 |
+//	  | ^
+type Printer struct {
+	Filename string // shown in the location line; may be empty
+	Color    bool   // use ANSI colours
+	Context  int    // lines shown before and after each underlined line
+	TabWidth int    // columns per tab stop; 4 if zero
+}
+
+// underline marks part of a source line.
+type underline struct {
+	start, end int // display columns, 0-based, end exclusive
+	primary    bool
+	message    string
+}
+
+// Print renders diag, whose positions refer to source.
+func (p *Printer) Print(out io.Writer, source string, diag lexer.Diagnostic) {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+
+	colour := p.severityColour(diag.Severity)
+	header := diag.Severity.String()
+	if diag.Code != "" {
+		header += "[" + diag.Code + "]"
+	}
+	fmt.Fprintf(out, "%s%s\n", p.paint(colour, header), p.paint(ansiBold, ": "+diag.Message))
+
+	marks := make(map[int][]underline)
+	mark := func(line, col, endCol int, primary bool, message string) {
+		if line < 1 {
+			return
+		}
+		text := lineAt(lines, line)
+		start, end := p.displayCol(text, col), p.displayCol(text, endCol)
+		if end <= start {
+			end = start + 1
+		}
+		marks[line] = append(marks[line], underline{start, end, primary, message})
+	}
+	if diag.EndLine > diag.Line {
+		first := lineAt(lines, diag.Line)
+		mark(diag.Line, diag.Col, len([]rune(first))+1, true, "")
+		last := []rune(lineAt(lines, diag.EndLine))
+		indent := 1
+		for indent <= len(last) && unicode.IsSpace(last[indent-1]) {
+			indent++
+		}
+		mark(diag.EndLine, indent, diag.EndCol, true, "")
+	} else {
+		mark(diag.Line, diag.Col, diag.Col+max(diag.Length, 1), true, "")
+	}
+	for _, l := range diag.Labels {
+		mark(l.Line, l.Col, l.Col+max(l.Length, 1), false, l.Message)
+	}
+
+	// Choose the lines to show: every marked line and its context.
+	shown := make(map[int]bool)
+	for line := range marks {
+		for l := line - p.Context; l <= line+p.Context; l++ {
+			if l >= 1 && (l <= len(lines) || l == line) {
+				shown[l] = true
 			}
 		}
+	}
+	order := make([]int, 0, len(shown))
+	for l := range shown {
+		order = append(order, l)
+	}
+	sort.Ints(order)
 
-		marker := "^"
-		if diag.Length > 1 {
-			marker = strings.Repeat("^", diag.Length)
+	width := 1
+	if len(order) > 0 {
+		width = len(fmt.Sprint(order[len(order)-1]))
+	}
+	pad := strings.Repeat(" ", width)
+	bar := p.paint(ansiBlue, "|")
+
+	if diag.Line > 0 {
+		location := fmt.Sprintf("%d:%d", diag.Line, diag.Col)
+		if p.Filename != "" {
+			location = p.Filename + ":" + location
 		}
+		fmt.Fprintf(out, "%s%s %s\n", pad, p.paint(ansiBlue, "-->"), location)
+		fmt.Fprintf(out, "%s %s\n", pad, bar)
+	}
 
-		fmt.Fprintf(out, "%s%s\n", pad, marker)
+	for i, l := range order {
+		if i > 0 && l > order[i-1]+1 {
+			fmt.Fprintln(out, p.paint(ansiBlue, "..."))
+		}
+		text := p.expandTabs(lineAt(lines, l))
+		fmt.Fprintln(out, strings.TrimRight(fmt.Sprintf("%s %s %s", p.paint(ansiBlue, fmt.Sprintf("%*d", width, l)), bar, text), " "))
+
+		ul := marks[l]
+		sort.SliceStable(ul, func(a, b int) bool {
+			return ul[a].primary && !ul[b].primary
+		})
+		for _, u := range ul {
+			ch, c := "-", ansiBlue
+			if u.primary {
+				ch, c = "^", colour
+			}
+			row := strings.Repeat(" ", u.start) + strings.Repeat(ch, u.end-u.start)
+			if u.message != "" {
+				row += " " + u.message
+			}
+			fmt.Fprintf(out, "%s %s %s\n", pad, bar, p.paint(c, row))
+		}
+	}
+
+	for _, note := range diag.Notes {
+		fmt.Fprintf(out, "%s %s %s\n", pad, p.paint(ansiBlue, "="), p.paint(ansiBold, "note: ")+note)
+	}
+}
+
+// paint wraps s in an ANSI colour when colour is on.
+func (p *Printer) paint(code, s string) string {
+	if !p.Color || s == "" {
+		return s
 	}
+	return code + s + ansiReset
 }
 
-// PrintDiagnostics prints multiple diagnostics.
+func (p *Printer) severityColour(s lexer.Severity) string {
+	switch s {
+	case lexer.SeverityWarning:
+		return ansiYellow
+	case lexer.SeverityNote:
+		return ansiCyan
+	default:
+		return ansiRed
+	}
+}
+
+func (p *Printer) tabWidth() int {
+	if p.TabWidth > 0 {
+		return p.TabWidth
+	}
+	return 4
+}
+
+// displayCol converts a 1-based rune column in line into the 0-based
+// terminal column it is drawn at, allowing for tabs and wide characters.
+// Columns past the end of the line continue one cell per column.
+func (p *Printer) displayCol(line string, col int) int {
+	cells := 0
+	runes := []rune(line)
+	for i := 0; i < col-1; i++ {
+		if i >= len(runes) {
+			cells++
+			continue
+		}
+		if runes[i] == '\t' {
+			cells += p.tabWidth() - cells%p.tabWidth()
+		} else {
+			cells += RuneWidth(runes[i])
+		}
+	}
+	return cells
+}
+
+// expandTabs replaces tabs with spaces up to the next tab stop, so that
+// underlines line up whatever the terminal's tab settings.
+func (p *Printer) expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	cells := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := p.tabWidth() - cells%p.tabWidth()
+			b.WriteString(strings.Repeat(" ", n))
+			cells += n
+			continue
+		}
+		b.WriteRune(r)
+		cells += RuneWidth(r)
+	}
+	return b.String()
+}
+
+// lineAt returns the 1-based line n of lines, or "" past the end.
+func lineAt(lines []string, n int) string {
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return lines[n-1]
+}
+
+// wideRanges lists the East Asian wide and fullwidth characters and emoji
+// that terminals draw two cells wide.
+var wideRanges = [][2]rune{
+	{0x1100, 0x115F},   // Hangul Jamo
+	{0x2E80, 0x303E},   // CJK radicals, punctuation
+	{0x3041, 0x33FF},   // Hiragana, Katakana, CJK symbols
+	{0x3400, 0x4DBF},   // CJK extension A
+	{0x4E00, 0x9FFF},   // CJK unified ideographs
+	{0xA000, 0xA4CF},   // Yi
+	{0xAC00, 0xD7A3},   // Hangul syllables
+	{0xF900, 0xFAFF},   // CJK compatibility ideographs
+	{0xFE30, 0xFE4F},   // CJK compatibility forms
+	{0xFF00, 0xFF60},   // fullwidth forms
+	{0xFFE0, 0xFFE6},   // fullwidth signs
+	{0x1F300, 0x1F64F}, // symbols, pictographs, emoticons
+	{0x1F900, 0x1F9FF}, // supplemental symbols and pictographs
+	{0x1FA70, 0x1FAFF}, // symbols and pictographs extended-A
+	{0x20000, 0x3FFFD}, // CJK extensions B onwards
+}
+
+// RuneWidth returns the number of terminal cells r occupies: 0 for
+// combining marks and other invisible characters, 2 for wide characters
+// and 1 otherwise.
+func RuneWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	for _, wr := range wideRanges {
+		if r >= wr[0] && r <= wr[1] {
+			return 2
+		}
+	}
+	return 1
+}
+
+// UseColor reports whether output to f should be coloured: f must be a
+// terminal, and NO_COLOR and TERM=dumb turn colour off.
+func UseColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// PrintErrorWithContext prints a diagnostic with the surrounding source lines
+// and underlines pointing at the error.
+func PrintErrorWithContext(out io.Writer, source string, diag lexer.Diagnostic) {
+	(&Printer{Context: 1}).Print(out, source, diag)
+}
+
+// PrintDiagnostics prints multiple diagnostics, separated by blank lines.
 func PrintDiagnostics(out io.Writer, source string, diags []lexer.Diagnostic) {
-	for _, d := range diags {
-		PrintErrorWithContext(out, source, d)
+	(&Printer{Context: 1}).PrintAll(out, source, diags)
+}
+
+// PrintAll prints diags, separated by blank lines.
+func (p *Printer) PrintAll(out io.Writer, source string, diags []lexer.Diagnostic) {
+	for i, d := range diags {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		p.Print(out, source, d)
 	}
 }
@@ -0,0 +1,236 @@
+package reporting
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+func TestParseCompilerOutput(t *testing.T) {
+	output := "# command-line-arguments\n" +
+		"kopi.singlish:6: undefined: y\n" +
+		"kopi.singlish:9:3: cannot use x (variable of type int) as string value in argument to f\n" +
+		"\thave (int)\n" +
+		"\twant (string)\n"
+
+	diags := ParseCompilerOutput(output)
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %v", len(diags), diags)
+	}
+	if d := diags[0]; d.Filename != "kopi.singlish" || d.Line != 6 || d.Col != 0 || d.Message != "undefined: y" {
+		t.Errorf("diags[0] = %+v", d)
+	}
+	if d := diags[1]; d.Line != 9 || d.Col != 3 || d.Code != lexer.CodeGoCompiler {
+		t.Errorf("diags[1] = %+v", d)
+	}
+	if want := "cannot use x (variable of type int) as string value in argument to f\nhave (int)\nwant (string)"; diags[1].Message != want {
+		t.Errorf("diags[1].Message = %q, want %q", diags[1].Message, want)
+	}
+}
+
+func TestParseTestOutput(t *testing.T) {
+	output := "--- FAIL: TestAdd (0.00s)\n" +
+		"    math_test.singlish:7: Add(1, 2) = -1,\n" +
+		"        want 3\n" +
+		"--- FAIL: TestSub (0.00s)\n" +
+		"    --- FAIL: TestSub/zero (0.00s)\n" +
+		"--- FAIL: TestPanic (0.00s)\n" +
+		"panic: runtime error: index out of range [3] with length 0 [recovered]\n" +
+		"\n" +
+		"goroutine 10 [running]:\n" +
+		"testing.tRunner.func1.2({0x6c8e30, 0x15e89d4a120})\n" +
+		"\t/usr/local/go/src/testing/testing.go:2123 +0x232\n" +
+		"singlishpkg.TestPanic(0x15e89dc8908?)\n" +
+		"\tmath_test.singlish:19 +0x9\n" +
+		"FAIL\t.\t0.005s\n" +
+		"FAIL\n"
+
+	want := []Diagnostic{
+		{Filename: "math_test.singlish", Diagnostic: lexer.Diagnostic{Line: 7, Message: "TestAdd: Add(1, 2) = -1,\nwant 3", Code: lexer.CodeTestFailure}},
+		{Filename: "math_test.singlish", Diagnostic: lexer.Diagnostic{Line: 19, Message: "TestPanic: panic: runtime error: index out of range [3] with length 0 [recovered]", Code: lexer.CodeTestFailure}},
+		{Diagnostic: lexer.Diagnostic{Message: "TestSub/zero failed", Code: lexer.CodeTestFailure}},
+	}
+	diags := ParseTestOutput(output)
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %+v", len(diags), len(want), diags)
+	}
+	for i := range want {
+		if diags[i].Filename != want[i].Filename || diags[i].Line != want[i].Line || diags[i].Message != want[i].Message || diags[i].Code != want[i].Code {
+			t.Errorf("diags[%d] = %+v, want %+v", i, diags[i], want[i])
+		}
+	}
+	if diags := ParseTestOutput("ok  \t.\t0.002s\n"); len(diags) != 0 {
+		t.Errorf("passing output gave %+v", diags)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	diags := []Diagnostic{
+		{Filename: "kopi.singlish", Diagnostic: lexer.Diagnostic{Message: "boom", Line: 2, Col: 5, Length: 3, Code: lexer.CodeUnexpectedToken}},
+		{Diagnostic: lexer.Diagnostic{Message: "no file", Severity: lexer.SeverityWarning}},
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, diags); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+	want := map[string]any{"file": "kopi.singlish", "line": 2.0, "column": 5.0, "length": 3.0, "severity": "error", "code": "SG2001", "message": "boom"}
+	for k, v := range want {
+		if got[0][k] != v {
+			t.Errorf("got[0][%q] = %v, want %v", k, got[0][k], v)
+		}
+	}
+	if got[1]["severity"] != "warning" || got[1]["code"] != lexer.CodeGeneral {
+		t.Errorf("got[1] = %v", got[1])
+	}
+	for _, k := range []string{"file", "line", "column", "length"} {
+		if v, ok := got[1][k]; ok {
+			t.Errorf("got[1][%q] = %v, want it left out", k, v)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil || buf.String() != "[]\n" {
+		t.Errorf("WriteJSON(nil) = %q, %v; want an empty array", buf.String(), err)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	diags := []Diagnostic{
+		{Filename: "src/kopi.singlish", Diagnostic: lexer.Diagnostic{Message: "boom", Line: 2, Col: 5, Length: 3, Code: lexer.CodeUnexpectedToken}},
+		{Filename: "teh.singlish", Diagnostic: lexer.Diagnostic{Message: "again", Line: 1, Code: lexer.CodeUnexpectedToken}},
+		{Diagnostic: lexer.Diagnostic{Message: "no file"}},
+	}
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, diags, "v1.2.3"); err != nil {
+		t.Fatalf("WriteSARIF failed: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log: %+v", log)
+	}
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != 2 {
+		t.Errorf("got %d rules, want one per code", len(run.Tool.Driver.Rules))
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(run.Results))
+	}
+	loc := run.Results[0].Locations[0].PhysicalLocation
+	if loc.Region == nil || loc.Region.StartLine != 2 || loc.Region.StartColumn != 5 || loc.Region.EndColumn != 8 {
+		t.Errorf("unexpected region: %+v", loc.Region)
+	}
+	if run.Results[0].Level != "error" || run.Results[0].RuleID != "SG2001" {
+		t.Errorf("unexpected result: %+v", run.Results[0])
+	}
+	if len(run.Results[2].Locations) != 0 {
+		t.Errorf("result without a file has locations: %+v", run.Results[2].Locations)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	source := "kampung main\n\naction boss() {\n\tgot s = \"漢字\" @\n\tgong(s)\n\n"
+	tests := []struct {
+		name    string
+		printer Printer
+		diag    lexer.Diagnostic
+		want    string
+	}{
+		{
+			name:    "tabs and wide characters",
+			printer: Printer{Filename: "kopi.singlish", Context: 1},
+			diag:    lexer.Diagnostic{Message: "unexpected character", Line: 4, Col: 15, Length: 1, Code: lexer.CodeUnexpectedCharacter},
+			want: `error[SG1001]: unexpected character
+ --> kopi.singlish:4:15
+  |
+3 | action boss() {
+4 |     got s = "漢字" @
+  |                    ^
+5 |     gong(s)
+`,
+		},
+		{
+			name:    "labels and notes",
+			printer: Printer{},
+			diag: lexer.Diagnostic{
+				Message:  "expected } to close block, reached end of file",
+				Line:     6,
+				Col:      1,
+				Severity: lexer.SeverityWarning,
+				Labels:   []lexer.Label{{Line: 3, Col: 15, Length: 1, Message: "block opened here"}},
+				Notes:    []string{"every { needs a }"},
+			},
+			want: `warning: expected } to close block, reached end of file
+ --> 6:1
+  |
+3 | action boss() {
+  |               - block opened here
+...
+6 |
+  | ^
+  = note: every { needs a }
+`,
+		},
+		{
+			name:    "multi-line span",
+			printer: Printer{},
+			diag:    lexer.Diagnostic{Message: "span", Line: 3, Col: 8, EndLine: 5, EndCol: 9},
+			want: `error: span
+ --> 3:8
+  |
+3 | action boss() {
+  |        ^^^^^^^^
+...
+5 |     gong(s)
+  |     ^^^^^^^
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.printer.Print(&buf, source, tt.diag)
+			if buf.String() != tt.want {
+				t.Errorf("Print() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestPrinterColor(t *testing.T) {
+	var buf bytes.Buffer
+	p := Printer{Color: true}
+	p.Print(&buf, "gong(@)\n", lexer.Diagnostic{Message: "boom", Line: 1, Col: 6, Length: 1})
+	if !strings.Contains(buf.String(), ansiRed+"error"+ansiReset) {
+		t.Errorf("error header is not red:\n%q", buf.String())
+	}
+
+	buf.Reset()
+	p.Color = false
+	p.Print(&buf, "gong(@)\n", lexer.Diagnostic{Message: "boom", Line: 1, Col: 6, Length: 1})
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("colour off but output has escape sequences:\n%q", buf.String())
+	}
+}
+
+func TestRuneWidth(t *testing.T) {
+	for r, want := range map[rune]int{'a': 1, '漢': 2, '한': 2, '😀': 2, '\u0301': 0, 'é': 1} {
+		if got := RuneWidth(r); got != want {
+			t.Errorf("RuneWidth(%q) = %d, want %d", r, got, want)
+		}
+	}
+}
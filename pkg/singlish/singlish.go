@@ -0,0 +1,170 @@
+// Package singlish is the embeddable compiler API. It turns Singlish source
+// into Go source for tools that want to compile Singlish without running the
+// singlish command, such as editors, CI bots and the web playground.
+//
+//	res, err := singlish.Compile(ctx, src, singlish.Options{Filename: "main.singlish", Format: true})
+//	if err != nil {
+//		for _, d := range res.Diagnostics {
+//			fmt.Println(d)
+//		}
+//	}
+package singlish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/reporting"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+// DefaultFilename names the source in line directives when Options.Filename
+// is empty.
+const DefaultFilename = "main.singlish"
+
+// Options controls a compilation. The zero value compiles with the default
+// dictionary and leaves the Go code as the code generator wrote it.
+type Options struct {
+	// Filename is the path of the source. It is copied into diagnostics
+	// and line directives.
+	Filename string
+
+	// Dictionary maps Singlish keywords to Go. Nil means the built-in
+	// dictionary.
+	Dictionary *dictionaries.Dictionary
+
+	// EmitLineDirectives adds //line comments to the Go code so that the
+	// Go compiler, go vet and panics report Singlish file names and lines.
+	EmitLineDirectives bool
+
+	// Format runs the Go code through gofmt.
+	Format bool
+}
+
+// Diagnostic is a problem found in the source. Line and Col are 1-based.
+type Diagnostic = reporting.Diagnostic
+
+// Result is the output of Compile.
+type Result struct {
+	// Code is the generated Go source. It is empty if compilation failed.
+	Code string
+
+	// Diagnostics lists the problems found in the source.
+	Diagnostics []Diagnostic
+
+	// SourceMap maps lines of Code back to lines of the source. It is
+	// nil if compilation failed.
+	SourceMap *codegen.SourceMap
+}
+
+// Error is returned by Compile when the source has errors. The same
+// diagnostics are in Result.Diagnostics.
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return "compilation failed"
+	}
+	if len(e.Diagnostics) == 1 {
+		return e.Diagnostics[0].String()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e.Diagnostics[0], len(e.Diagnostics)-1)
+}
+
+// Compile translates Singlish source into Go. It always returns a non-nil
+// Result; when the source has errors the error is an *Error and the
+// diagnostics are also in the Result. Compile returns ctx.Err() if ctx is
+// done before it finishes.
+func Compile(ctx context.Context, source []byte, opts Options) (*Result, error) {
+	results, err := CompilePackage(ctx, []File{{Filename: opts.Filename, Source: source}}, opts)
+	return results[0], err
+}
+
+// File is one source file given to CompilePackage.
+type File struct {
+	// Filename is the path of the source, used as Options.Filename is.
+	Filename string
+	Source   []byte
+}
+
+// CompilePackage translates the files of one Singlish package into Go, one
+// Result per file in the order given. Each file sees what the others
+// declare at package level, as the files of a Go package do. The Filename
+// of each File takes the place of Options.Filename.
+//
+// When any file has errors the error is an *Error with the diagnostics of
+// all of them, each Result holds those of its own file, and no Go code is
+// generated.
+func CompilePackage(ctx context.Context, files []File, opts Options) ([]*Result, error) {
+	results := make([]*Result, len(files))
+	for i := range results {
+		results[i] = &Result{}
+	}
+	dict := opts.Dictionary
+	if dict == nil {
+		dict = dictionaries.NewDefaultDictionary()
+	}
+
+	programs := make([]*ast.Program, len(files))
+	var diags []Diagnostic
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		program, err := transpiler.ParseFile(string(f.Source), dict)
+		if err != nil {
+			var tErr *transpiler.TranspilationError
+			if !errors.As(err, &tErr) {
+				return results, err
+			}
+			results[i].Diagnostics = reporting.InFile(f.Filename, tErr.Diagnostics)
+			diags = append(diags, results[i].Diagnostics...)
+			continue
+		}
+		programs[i] = program
+	}
+	if len(diags) > 0 {
+		return results, &Error{Diagnostics: diags}
+	}
+
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		code, sourceMap, err := codegen.GenerateInPackage(programs[i], programs, dict)
+		if err != nil {
+			return results, fmt.Errorf("codegen error: %w", err)
+		}
+		results[i].Code, results[i].SourceMap = finish(code, sourceMap, f.Filename, opts)
+	}
+	return results, nil
+}
+
+// finish formats code and adds or strips the line directives of its source
+// map as opts asks.
+func finish(code string, sourceMap *codegen.SourceMap, filename string, opts Options) (string, *codegen.SourceMap) {
+	if !opts.Format && !opts.EmitLineDirectives {
+		return code, sourceMap
+	}
+	if filename == "" {
+		filename = DefaultFilename
+	}
+	// Formatting moves lines around, so carry the source map through
+	// gofmt as line directives and read it back afterwards.
+	code = sourceMap.LineDirectives(code, filename)
+	if opts.Format {
+		// Leave code gofmt cannot parse as it is so that go build
+		// reports the problem.
+		if formatted, err := format.Source([]byte(code)); err == nil {
+			code = string(formatted)
+		}
+	}
+	return codegen.ParseLineDirectives(code, !opts.EmitLineDirectives)
+}
@@ -0,0 +1,116 @@
+package singlish
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const kopi = "kampung main\n\naction boss() {\n\tgot x = 1\n\n\n\tgong(x)\n}\n"
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		directives bool
+	}{
+		{"plain", Options{}, false},
+		{"format", Options{Format: true}, false},
+		{"line directives", Options{Filename: "kopi.singlish", EmitLineDirectives: true}, true},
+		{"format and line directives", Options{Filename: "kopi.singlish", EmitLineDirectives: true, Format: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Compile(context.Background(), []byte(kopi), tt.opts)
+			if err != nil {
+				t.Fatalf("Compile error: %v", err)
+			}
+			if len(res.Diagnostics) > 0 {
+				t.Fatalf("unexpected diagnostics: %v", res.Diagnostics)
+			}
+			if got := strings.Contains(res.Code, "//line kopi.singlish:"); got != tt.directives {
+				t.Errorf("line directives present = %v, want %v:\n%s", got, tt.directives, res.Code)
+			}
+			if tt.opts.Format && !strings.Contains(res.Code, "import (\n\t\"fmt\"\n)\n") {
+				t.Errorf("code is not formatted:\n%s", res.Code)
+			}
+
+			found := false
+			for i, line := range strings.Split(res.Code, "\n") {
+				if line != "\tfmt.Println(x)" {
+					continue
+				}
+				found = true
+				if got, _ := res.SourceMap.Lookup(i + 1); got != 7 {
+					t.Errorf("Lookup(%d) = %d, want 7", i+1, got)
+				}
+			}
+			if !found {
+				t.Fatalf("generated code has no fmt.Println(x) line:\n%s", res.Code)
+			}
+		})
+	}
+}
+
+func TestCompileDiagnostics(t *testing.T) {
+	res, err := Compile(context.Background(), []byte("kampung main\n\naction boss() {\n\tgot = \n}\n"), Options{Filename: "bad.singlish"})
+	var cErr *Error
+	if !errors.As(err, &cErr) {
+		t.Fatalf("Compile error = %v, want *Error", err)
+	}
+	if len(res.Diagnostics) == 0 || len(res.Diagnostics) != len(cErr.Diagnostics) {
+		t.Fatalf("Result has %d diagnostics, error has %d", len(res.Diagnostics), len(cErr.Diagnostics))
+	}
+	d := res.Diagnostics[0]
+	if d.Filename != "bad.singlish" || d.Line != 4 {
+		t.Errorf("diagnostic = %+v, want bad.singlish line 4", d)
+	}
+	if !strings.HasPrefix(d.String(), "bad.singlish:4:") {
+		t.Errorf("String() = %q, want a bad.singlish:4: prefix", d.String())
+	}
+	if res.Code != "" || res.SourceMap != nil {
+		t.Errorf("failed compilation returned code")
+	}
+}
+
+func TestCompileCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Compile(ctx, []byte(kopi), Options{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Compile error = %v, want context.Canceled", err)
+	}
+}
+
+func TestCompilePackage(t *testing.T) {
+	files := []File{
+		{Filename: "a.singlish", Source: []byte("kampung main\n\naction len(s tar) nombor {\n\tbalek 42\n}\n")},
+		{Filename: "b.singlish", Source: []byte("kampung main\n\naction boss() {\n\tgong(len(\"abc\"), count(\"abc\"))\n}\n")},
+	}
+	results, err := CompilePackage(context.Background(), files, Options{Format: true})
+	if err != nil {
+		t.Fatalf("CompilePackage error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("CompilePackage returned %d results, want 2", len(results))
+	}
+	if want := "func len_(s string) int {"; !strings.Contains(results[0].Code, want) {
+		t.Errorf("a.singlish code does not contain %q:\n%s", want, results[0].Code)
+	}
+	if want := `fmt.Println(len_("abc"), len("abc"))`; !strings.Contains(results[1].Code, want) {
+		t.Errorf("b.singlish code does not contain %q:\n%s", want, results[1].Code)
+	}
+
+	files[0].Source = []byte("kampung main\n\naction len(s tar) nombor {\n\tgot = \n}\n")
+	results, err = CompilePackage(context.Background(), files, Options{})
+	var cErr *Error
+	if !errors.As(err, &cErr) {
+		t.Fatalf("CompilePackage error = %v, want *Error", err)
+	}
+	if len(results[0].Diagnostics) == 0 || results[0].Diagnostics[0].Filename != "a.singlish" {
+		t.Errorf("a.singlish diagnostics = %v", results[0].Diagnostics)
+	}
+	if len(results[1].Diagnostics) != 0 || results[1].Code != "" {
+		t.Errorf("b.singlish result = %+v, want no diagnostics and no code", results[1])
+	}
+}
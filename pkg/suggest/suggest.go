@@ -0,0 +1,295 @@
+// Package suggest finds misspelled Singlish keywords. The lexer treats an
+// unknown word such as "balik" as an identifier, which would otherwise only
+// surface later as a confusing Go compile error; Check spots identifiers
+// where a keyword is expected and offers the closest keywords instead.
+package suggest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// maxSuggestions caps how many equally close keywords are offered.
+const maxSuggestions = 3
+
+// Distance returns the edit distance between a and b: the number of
+// single-rune insertions, deletions, substitutions and swaps of adjacent
+// runes needed to turn one into the other.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between ra[:i] and rb[:j].
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// maxDistance is how different a word of n runes may be from a candidate
+// and still be taken for a misspelling of it.
+func maxDistance(n int) int {
+	switch {
+	case n <= 2:
+		return 0
+	case n <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Suggest returns the candidates closest to word, nearest first, or nil if
+// none is close enough. word itself is never suggested.
+func Suggest(word string, candidates []string) []string {
+	limit := maxDistance(utf8.RuneCountInString(word))
+	best := limit + 1
+	var found []string
+	for _, c := range candidates {
+		if c == word {
+			continue
+		}
+		dist := Distance(strings.ToLower(word), strings.ToLower(c))
+		if dist > limit {
+			continue
+		}
+		switch {
+		case dist < best:
+			best, found = dist, []string{c}
+		case dist == best:
+			found = append(found, c)
+		}
+	}
+	sort.Strings(found)
+	if len(found) > maxSuggestions {
+		found = found[:maxSuggestions]
+	}
+	return found
+}
+
+// Hint formats suggestions as "did you mean `a`?" or "did you mean `a` or
+// `b`?".
+func Hint(suggestions []string) string {
+	quoted := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		quoted[i] = "`" + s + "`"
+	}
+	switch len(quoted) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("did you mean %s?", quoted[0])
+	default:
+		return fmt.Sprintf("did you mean %s or %s?", strings.Join(quoted[:len(quoted)-1], ", "), quoted[len(quoted)-1])
+	}
+}
+
+// Check looks for bare words on their own as a statement, where a keyword
+// was probably meant. Each one that is close to a dictionary keyword is
+// reported with the keywords it may have meant.
+//
+// Type names are not looked at: a type may be declared in another file of
+// the package. A misspelled one, such as nombar, surfaces as Go's
+// "undefined: nombar", which AnnotateCompilerOutput and HintFor give the
+// same hint.
+func Check(program *ast.Program, dict *dictionaries.Dictionary) []lexer.Diagnostic {
+	if program == nil || dict == nil {
+		return nil
+	}
+	c := &checker{keywords: keywords(dict)}
+	c.statements(program.Statements)
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		if c.diags[i].Line != c.diags[j].Line {
+			return c.diags[i].Line < c.diags[j].Line
+		}
+		return c.diags[i].Col < c.diags[j].Col
+	})
+	return c.diags
+}
+
+// keywords returns the dictionary words that can be misspelled on their
+// own, leaving out qualified aliases such as "str.Upper".
+func keywords(dict *dictionaries.Dictionary) []string {
+	var out []string
+	for _, k := range dict.Keys() {
+		if !strings.Contains(k, ".") {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+type checker struct {
+	keywords []string
+	diags    []lexer.Diagnostic
+}
+
+// report adds a diagnostic for ident if it is close to a keyword.
+func (c *checker) report(ident *ast.Identifier) {
+	suggestions := Suggest(ident.Value, c.keywords)
+	if len(suggestions) == 0 {
+		return
+	}
+	c.diags = append(c.diags, lexer.Diagnostic{
+		Message: fmt.Sprintf("unknown keyword `%s`", ident.Value),
+		Line:    ident.Token.Line,
+		Col:     ident.Token.Col,
+		Length:  utf8.RuneCountInString(ident.Value),
+		Code:    lexer.CodeUnknownKeyword,
+		Notes:   []string{Hint(suggestions)},
+	})
+}
+
+func (c *checker) statements(stmts []ast.Statement) {
+	prevLine := 0
+	for _, s := range stmts {
+		line, _ := ast.Position(s)
+		// In "balik x" the x is parsed as a statement of its own; only the
+		// word that starts the line can be a misspelled keyword.
+		if es, ok := s.(*ast.ExpressionStatement); !ok || line != prevLine {
+			c.statement(s)
+		} else {
+			c.expression(es.Expression)
+		}
+		prevLine = line
+	}
+}
+
+func (c *checker) block(b *ast.BlockStatement) {
+	if b != nil {
+		c.statements(b.Statements)
+	}
+}
+
+func (c *checker) statement(s ast.Statement) {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		if ident, ok := s.Expression.(*ast.Identifier); ok {
+			c.report(ident)
+			return
+		}
+		c.expression(s.Expression)
+	case *ast.LetStatement:
+		c.expression(s.Value)
+	case *ast.FunctionStatement:
+		c.block(s.Body)
+	case *ast.ReturnStatement:
+		for _, v := range s.ReturnValues {
+			c.expression(v)
+		}
+	case *ast.BlockStatement:
+		c.block(s)
+	case *ast.IfStatement:
+		c.expression(s.Condition)
+		c.block(s.Consequence)
+		c.block(s.Alternative)
+		if s.AlternativeStmt != nil {
+			c.statement(s.AlternativeStmt)
+		}
+	case *ast.ForStatement:
+		if s.Init != nil {
+			c.statement(s.Init)
+		}
+		c.expression(s.Condition)
+		c.expression(s.Iterable)
+		c.block(s.Body)
+	case *ast.SwitchStatement:
+		c.expression(s.Expression)
+		for _, cs := range s.Cases {
+			c.block(cs.Body)
+		}
+	case *ast.SelectStatement:
+		for _, sc := range s.Cases {
+			c.block(sc.Body)
+		}
+	case *ast.GoStatement:
+		c.expression(s.Call)
+	case *ast.DeferStatement:
+		c.expression(s.Call)
+	}
+}
+
+// expression looks inside e for function literals, whose bodies are
+// checked like any other.
+func (c *checker) expression(e ast.Expression) {
+	switch e := e.(type) {
+	case *ast.FunctionLiteral:
+		if e == nil {
+			return
+		}
+		c.block(e.Body)
+	case *ast.CallExpression:
+		if e == nil {
+			return
+		}
+		c.expression(e.Function)
+		for _, a := range e.Arguments {
+			c.expression(a)
+		}
+	case *ast.InfixExpression:
+		c.expression(e.Left)
+		c.expression(e.Right)
+	case *ast.PrefixExpression:
+		c.expression(e.Right)
+	}
+}
+
+// undefinedName matches the Go compiler's "undefined: name" errors.
+var undefinedName = regexp.MustCompile(`: undefined: ([\pL_][\pL\pN_]*)$`)
+
+// AnnotateCompilerOutput adds an indented "did you mean" line below every
+// "undefined: name" error in Go compiler output whose name is close to a
+// dictionary keyword, such as a call to gongg instead of gong.
+func AnnotateCompilerOutput(output string, dict *dictionaries.Dictionary) string {
+	if dict == nil || !strings.Contains(output, "undefined: ") {
+		return output
+	}
+	words := keywords(dict)
+	lines := strings.SplitAfter(output, "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		m := undefinedName.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if m == nil {
+			continue
+		}
+		if hint := Hint(Suggest(m[1], words)); hint != "" {
+			if !strings.HasSuffix(line, "\n") {
+				b.WriteString("\n")
+			}
+			b.WriteString("\t" + hint + "\n")
+		}
+	}
+	return b.String()
+}
+
+// HintFor returns the "did you mean" hint for a name Go reports as
+// undefined, or "" if the name is not close to a dictionary keyword.
+func HintFor(name string, dict *dictionaries.Dictionary) string {
+	if dict == nil {
+		return ""
+	}
+	return Hint(Suggest(name, keywords(dict)))
+}
@@ -0,0 +1,166 @@
+package suggest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/parser"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"balek", "balek", 0},
+		{"balik", "balek", 1},
+		{"kampong", "kampung", 1},
+		{"blaek", "balek", 1}, // adjacent swap
+		{"gong", "gongg", 1},
+		{"", "abc", 3},
+		{"nombor", "tar", 5},
+		{"漢字", "漢", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"balek", "kampung", "nombor", "gong", "can"}
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"balik", []string{"balek"}},
+		{"kampong", []string{"kampung"}},
+		{"Nombor", []string{"nombor"}},
+		{"main", nil},  // two edits from can is too far for a short word
+		{"go", nil},    // words this short are never corrected
+		{"gong", nil},  // already a keyword
+		{"xyzzy", nil}, // nothing close
+	}
+	for _, tt := range tests {
+		if got := Suggest(tt.word, candidates); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", tt.word, got, tt.want)
+		}
+	}
+
+	if got := Suggest("tat", []string{"tar", "tap", "cat"}); !reflect.DeepEqual(got, []string{"cat", "tap", "tar"}) {
+		t.Errorf("ties = %v, want all three sorted", got)
+	}
+}
+
+func TestHint(t *testing.T) {
+	for suggestions, want := range map[string]string{
+		"":        "",
+		"a":       "did you mean `a`?",
+		"a,b":     "did you mean `a` or `b`?",
+		"a,b,c":   "did you mean `a`, `b` or `c`?",
+		"balek,x": "did you mean `balek` or `x`?",
+	} {
+		var list []string
+		if suggestions != "" {
+			list = strings.Split(suggestions, ",")
+		}
+		if got := Hint(list); got != want {
+			t.Errorf("Hint(%v) = %q, want %q", list, got, want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := `kampong main
+
+pattern Order barang {
+	Price nombor
+}
+
+action add(a nombar, o ki Order) nombor {
+	balik a + o.Price
+}
+
+action boss() {
+	got x nombor = 1
+	x
+	got f = action() {
+		balek
+		nasi x > 0 {
+			retun
+		}
+	}
+	f()
+}
+`
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lex failed: %v", diags)
+	}
+	p := parser.New(tokens, dict)
+	program := p.ParseProgram()
+
+	var got []string
+	for _, d := range Check(program, dict) {
+		got = append(got, d.Message+" "+d.Notes[0])
+		if d.Code != lexer.CodeUnknownKeyword {
+			t.Errorf("%s: code %q, want %q", d.Message, d.Code, lexer.CodeUnknownKeyword)
+		}
+	}
+	want := []string{
+		"unknown keyword `kampong` did you mean `kampung`?",
+		"unknown keyword `balik` did you mean `balek`?",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	// Car is close to tar, but may be declared in a nested block or in
+	// another file of the package; Go reports it if it is not.
+	input := "kampung main\n\naction boss() {\n\tpattern Car barang {}\n\tgot c Car\n\tgot b Bar\n\tgong(c, b)\n}\n"
+	keywords := make(map[string]struct{})
+	for _, k := range dict.Keys() {
+		keywords[k] = struct{}{}
+	}
+	tokens, diags := lexer.Lex(input, keywords)
+	if len(diags) > 0 {
+		t.Fatalf("Lex failed: %v", diags)
+	}
+	program := parser.New(tokens, dict).ParseProgram()
+	if diags := Check(program, dict); len(diags) > 0 {
+		t.Errorf("Check() = %v, want no diagnostics", diags)
+	}
+}
+
+func TestHintFor(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	for name, want := range map[string]string{
+		"nombar": "did you mean `nombor`?",
+		"zzz":    "",
+	} {
+		if got := HintFor(name, dict); got != want {
+			t.Errorf("HintFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestAnnotateCompilerOutput(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	output := "# command-line-arguments\nkopi.singlish:4: undefined: gongg\nkopi.singlish:5: undefined: zzz\n"
+	want := "# command-line-arguments\nkopi.singlish:4: undefined: gongg\n\tdid you mean `gong`?\nkopi.singlish:5: undefined: zzz\n"
+	if got := AnnotateCompilerOutput(output, dict); got != want {
+		t.Errorf("AnnotateCompilerOutput() =\n%s\nwant\n%s", got, want)
+	}
+}
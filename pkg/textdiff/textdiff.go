@@ -0,0 +1,171 @@
+// Package textdiff produces line-based unified diffs between two texts.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context is the number of unchanged lines shown around each change.
+const context = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type op struct {
+	kind opKind
+	line string
+	a, b int // 0-based line indexes in the old and new text
+}
+
+// Unified returns a unified diff turning oldText into newText, labelled with
+// oldName and newName. It returns "" if the texts are equal.
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	ops := diff(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops) {
+		writeHunk(&out, ops[h[0]:h[1]])
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines, keeping a marker when the final line
+// has no trailing newline so that such a change is still visible.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	last := lines[len(lines)-1]
+	if !strings.HasSuffix(last, "\n") {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+// diff computes an edit script from the longest common subsequence of a and b.
+func diff(a, b []string) []op {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, op{opEqual, a[i], i, j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, op{opInsert, b[j], i, j})
+			j++
+		default:
+			ops = append(ops, op{opDelete, a[i], i, j})
+			i++
+		}
+	}
+	return ops
+}
+
+// hunks groups changed operations together with their surrounding context,
+// returning [start, end) index pairs into ops.
+func hunks(ops []op) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+		start := max(i-context, 0)
+		end := i + 1
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			// Look ahead: merge with the next change if the gap is small.
+			k := end
+			for k < len(ops) && ops[k].kind == opEqual {
+				k++
+			}
+			if k < len(ops) && k-end <= 2*context {
+				end = k
+				continue
+			}
+			end = min(end+context, len(ops))
+			break
+		}
+		if n := len(result); n > 0 && start <= result[n-1][1] {
+			result[n-1][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+		i = end - 1
+	}
+	return result
+}
+
+func writeHunk(out *strings.Builder, ops []op) {
+	var oldCount, newCount int
+	for _, o := range ops {
+		if o.kind != opInsert {
+			oldCount++
+		}
+		if o.kind != opDelete {
+			newCount++
+		}
+	}
+	oldStart, newStart := ops[0].a+1, ops[0].b+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", span(oldStart, oldCount), span(newStart, newCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			out.WriteString(" " + o.line)
+		case opDelete:
+			out.WriteString("-" + o.line)
+		case opInsert:
+			out.WriteString("+" + o.line)
+		}
+	}
+}
+
+func span(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -2,13 +2,21 @@ package transpiler
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/rickchow/singlish/pkg/ast"
 	"github.com/rickchow/singlish/pkg/codegen"
 	"github.com/rickchow/singlish/pkg/dictionaries"
 	"github.com/rickchow/singlish/pkg/lexer"
 	"github.com/rickchow/singlish/pkg/parser"
+	"github.com/rickchow/singlish/pkg/suggest"
 )
 
+// GeneratedHeader is the first line of Go files written by singlish. It
+// follows the Go convention for marking generated code, which tools such as
+// gofmt, linters and code review sites recognise.
+const GeneratedHeader = "// Code generated by singlish; DO NOT EDIT.\n"
+
 // TranspilationError wraps a list of diagnostics from lexer or parser.
 type TranspilationError struct {
 	Diagnostics []lexer.Diagnostic
@@ -22,10 +30,35 @@ func (e *TranspilationError) Error() string {
 	return fmt.Sprintf("%s (and %d more errors)", e.Diagnostics[0].Message, len(e.Diagnostics)-1)
 }
 
-// Transpile converts Singlish source code to Go source code.
-// It uses the AST-based pipeline: Lexer -> Parser -> Codegen.
-func Transpile(source string, dict *dictionaries.Dictionary) (string, error) {
-	// 1. Lex
+// Parse lexes and parses Singlish source code into an AST.
+func Parse(source string, dict *dictionaries.Dictionary) (*ast.Program, error) {
+	program, diagnostics := parse(source, dict)
+	if len(diagnostics) > 0 {
+		return nil, &TranspilationError{Diagnostics: diagnostics}
+	}
+	return program, nil
+}
+
+// ParseFile is like Parse for a complete source file. It also reports
+// misspelled keywords, which Parse cannot do for fragments such as REPL
+// entries where a bare identifier is valid.
+func ParseFile(source string, dict *dictionaries.Dictionary) (*ast.Program, error) {
+	program, diagnostics := parse(source, dict)
+	if program != nil {
+		diagnostics = append(diagnostics, suggest.Check(program, dict)...)
+		sort.SliceStable(diagnostics, func(i, j int) bool {
+			return diagnostics[i].Line < diagnostics[j].Line
+		})
+	}
+	if len(diagnostics) > 0 {
+		return nil, &TranspilationError{Diagnostics: diagnostics}
+	}
+	return program, nil
+}
+
+// parse returns the program with any lexer or parser diagnostics. The
+// program is nil if lexing failed, and may be incomplete if parsing did.
+func parse(source string, dict *dictionaries.Dictionary) (*ast.Program, []lexer.Diagnostic) {
 	keywords := make(map[string]struct{})
 	if dict != nil {
 		for _, k := range dict.Keys() {
@@ -37,14 +70,21 @@ func Transpile(source string, dict *dictionaries.Dictionary) (string, error) {
 
 	tokens, diagnostics := lexer.Lex(source, keywords)
 	if len(diagnostics) > 0 {
-		return "", &TranspilationError{Diagnostics: diagnostics}
+		return nil, diagnostics
 	}
 
-	// 2. Parse
 	p := parser.New(tokens, dict)
 	program := p.ParseProgram()
-	if len(p.Errors()) > 0 {
-		return "", &TranspilationError{Diagnostics: p.Errors()}
+	return program, p.Errors()
+}
+
+// Transpile converts Singlish source code to Go source code.
+// It uses the AST-based pipeline: Lexer -> Parser -> Codegen.
+func Transpile(source string, dict *dictionaries.Dictionary) (string, error) {
+	// 1. Lex and 2. Parse
+	program, err := ParseFile(source, dict)
+	if err != nil {
+		return "", err
 	}
 
 	// 3. Codegen
@@ -55,3 +95,19 @@ func Transpile(source string, dict *dictionaries.Dictionary) (string, error) {
 
 	return code, nil
 }
+
+// TranspileWithSourceMap is like Transpile but also returns a source map
+// from generated Go lines back to Singlish lines.
+func TranspileWithSourceMap(source string, dict *dictionaries.Dictionary) (string, *codegen.SourceMap, error) {
+	program, err := ParseFile(source, dict)
+	if err != nil {
+		return "", nil, err
+	}
+
+	code, sourceMap, err := codegen.GenerateWithSourceMap(program, dict)
+	if err != nil {
+		return "", nil, fmt.Errorf("codegen error: %w", err)
+	}
+
+	return code, sourceMap, nil
+}
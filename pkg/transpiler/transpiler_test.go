@@ -144,3 +144,25 @@ func TestTranspilePointer(t *testing.T) {
 		t.Errorf("Expected 'var x *int', got:\n%s", got)
 	}
 }
+
+func TestParseFileSuggestsKeywords(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	input := "kampung main\n\naction boss() {\n\tbalik\n}\n"
+
+	_, err := ParseFile(input, dict)
+	tErr, ok := err.(*TranspilationError)
+	if !ok {
+		t.Fatalf("ParseFile error = %v, want *TranspilationError", err)
+	}
+	if len(tErr.Diagnostics) != 1 || len(tErr.Diagnostics[0].Notes) != 1 {
+		t.Fatalf("unexpected diagnostics: %+v", tErr.Diagnostics)
+	}
+	if note := tErr.Diagnostics[0].Notes[0]; note != "did you mean `balek`?" {
+		t.Errorf("note = %q", note)
+	}
+
+	// A bare identifier is a valid REPL entry, so Parse leaves it alone.
+	if _, err := Parse("balik", dict); err != nil {
+		t.Errorf("Parse(balik) failed: %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+// Package typecheck finds type errors in Singlish programs without building
+// them. It type-checks the Go a program translates to with go/types, reading
+// imported packages from the local GOROOT, and reports each error at the
+// Singlish code that produced it, with Go's type names written the way the
+// dictionary spells them.
+package typecheck
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	sgast "github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/suggest"
+)
+
+// File is one parsed source file of a package.
+type File struct {
+	Path    string
+	Source  string
+	Program *sgast.Program
+}
+
+// Check type-checks program, parsed from src, on its own and returns its
+// type errors. Packages from outside the standard library cannot be read
+// offline; each one gets a note saying the code using it was not checked.
+func Check(src string, program *sgast.Program, dict *dictionaries.Dictionary) ([]lexer.Diagnostic, error) {
+	diags, err := CheckPackage([]File{{Source: src, Program: program}}, dict)
+	return diags[""], err
+}
+
+// CheckPackage type-checks files, which make up one package, together, so
+// that each may use what the others declare. It returns the type errors of
+// each file by its Path.
+func CheckPackage(files []File, dict *dictionaries.Dictionary) (map[string][]lexer.Diagnostic, error) {
+	checkers, err := checkPackage(files, dict, nil)
+	if err != nil {
+		return nil, err
+	}
+	diags := make(map[string][]lexer.Diagnostic)
+	for i, c := range checkers {
+		if len(c.diags) > 0 {
+			diags[files[i].Path] = append(diags[files[i].Path], c.diags...)
+		}
+	}
+	return diags, nil
+}
+
+// Call is a call made as a statement of its own, so that its results are
+// thrown away.
+type Call struct {
+	Line int    // the Singlish line of the call
+	Name string // the Go name of the function or method called, as Remove
+	Func string // its full name, as os.Remove or (*os.File).Close; empty for a function value
+}
+
+// IgnoredErrors type-checks files, which make up one package, together and
+// returns the calls in each, by its Path, that throw away an error result.
+// Calls that cannot be type-checked, such as those into packages outside
+// the standard library, are left out.
+func IgnoredErrors(files []File, dict *dictionaries.Dictionary) (map[string][]Call, error) {
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	checkers, err := checkPackage(files, dict, info)
+	if err != nil {
+		return nil, err
+	}
+	calls := make(map[string][]Call)
+	for i, c := range checkers {
+		if c.file == nil {
+			continue
+		}
+		ast.Inspect(c.file, func(n ast.Node) bool {
+			stmt, ok := n.(*ast.ExprStmt)
+			if !ok {
+				return true
+			}
+			call, ok := stmt.X.(*ast.CallExpr)
+			if !ok || !returnsError(info.Types[call].Type) {
+				return true
+			}
+			var id *ast.Ident
+			switch fun := ast.Unparen(call.Fun).(type) {
+			case *ast.Ident:
+				id = fun
+			case *ast.SelectorExpr:
+				id = fun.Sel
+			default:
+				return true
+			}
+			line, ok := c.sourceMap.Lookup(fset.Position(call.Pos()).Line)
+			if !ok {
+				return true
+			}
+			ic := Call{Line: line, Name: id.Name}
+			if fn, ok := info.Uses[id].(*types.Func); ok {
+				ic.Func = fn.FullName()
+			}
+			calls[files[i].Path] = append(calls[files[i].Path], ic)
+			return true
+		})
+	}
+	return calls, nil
+}
+
+// returnsError reports whether t, the type of a call, is or holds an error.
+func returnsError(t types.Type) bool {
+	errorType := types.Universe.Lookup("error").Type()
+	if tuple, ok := t.(*types.Tuple); ok {
+		for v := range tuple.Variables() {
+			if types.Identical(v.Type(), errorType) {
+				return true
+			}
+		}
+		return false
+	}
+	return t != nil && types.Identical(t, errorType)
+}
+
+// checkPackage type-checks files together, recording what go/types finds in
+// info if it is not nil. It returns a checker for each file, in order, with
+// its errors and its parsed Go.
+func checkPackage(files []File, dict *dictionaries.Dictionary, info *types.Info) ([]*checker, error) {
+	pkg := make([]*sgast.Program, len(files))
+	for i, f := range files {
+		pkg[i] = f.Program
+	}
+	checkers := make(map[string]*checker) // by the name of the generated Go file
+	var ordered []*checker
+	for i, f := range files {
+		code, sourceMap, err := codegen.GenerateInPackage(f.Program, pkg, dict)
+		if err != nil {
+			return nil, fmt.Errorf("%s: codegen error: %w", f.Path, err)
+		}
+		c := &checker{
+			src:       strings.Split(f.Source, "\n"),
+			code:      code,
+			sourceMap: sourceMap,
+			dict:      dict,
+			program:   f.Program,
+		}
+		checkers[fmt.Sprintf("file%d.go", i)] = c
+		ordered = append(ordered, c)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var parsed []*ast.File
+	for i, c := range ordered {
+		name := fmt.Sprintf("file%d.go", i)
+		file, err := parser.ParseFile(fset, name, c.code, 0)
+		if err != nil {
+			var list scanner.ErrorList
+			if !errors.As(err, &list) {
+				return nil, err
+			}
+			for _, e := range list {
+				c.report(e.Pos, "the generated Go does not parse: "+e.Msg)
+			}
+			continue
+		}
+		parsed = append(parsed, file)
+	}
+
+	// Without every file, names the missing ones declare would be reported
+	// as undefined.
+	if len(parsed) == len(files) {
+		conf := types.Config{
+			Importer: std,
+			Error: func(err error) {
+				e, ok := err.(types.Error)
+				if !ok {
+					return
+				}
+				if c, ok := checkers[e.Fset.Position(e.Pos).Filename]; ok {
+					c.typeError(e)
+				}
+			},
+		}
+		conf.Check(parsed[0].Name.Name, fset, parsed, info)
+		for i, c := range ordered {
+			c.file = parsed[i]
+		}
+	}
+	return ordered, nil
+}
+
+// The standard library is read from source once and kept for later checks;
+// the importer is not safe for concurrent use, so checks take turns.
+var (
+	mu   sync.Mutex
+	fset = token.NewFileSet()
+	std  = importer.ForCompiler(fset, "source", nil)
+)
+
+type checker struct {
+	src       []string // lines of the Singlish source
+	code      string   // the generated Go
+	sourceMap *codegen.SourceMap
+	dict      *dictionaries.Dictionary
+	program   *sgast.Program
+	file      *ast.File // the parsed Go, if the package was type-checked
+	diags     []lexer.Diagnostic
+}
+
+// couldNotImport matches go/types' error for a package it cannot read.
+var couldNotImport = regexp.MustCompile(`^could not import (\S+)`)
+
+func (c *checker) typeError(e types.Error) {
+	if m := couldNotImport.FindStringSubmatch(e.Msg); m != nil {
+		path, err := strconv.Unquote(m[1])
+		if err != nil {
+			path = m[1]
+		}
+		c.diags = append(c.diags, lexer.Diagnostic{
+			Message:  fmt.Sprintf("package %q is not in the standard library, so code using it is not checked", path),
+			Line:     c.importLine(path),
+			Col:      1,
+			Severity: lexer.SeverityNote,
+			Code:     lexer.CodeTypeCheck,
+		})
+		return
+	}
+	c.report(e.Fset.Position(e.Pos), c.singlish(e.Msg))
+	// A misspelled keyword, such as nombar for nombor, is an undefined
+	// name to Go.
+	if name, ok := strings.CutPrefix(e.Msg, "undefined: "); ok {
+		if hint := suggest.HintFor(name, c.dict); hint != "" {
+			d := &c.diags[len(c.diags)-1]
+			d.Notes = append(d.Notes, hint)
+		}
+	}
+}
+
+// report adds an error at the Singlish code that produced the Go at pos.
+// Errors in Go that no Singlish line produced are put on line 1.
+func (c *checker) report(pos token.Position, msg string) {
+	d := lexer.Diagnostic{Message: msg, Line: 1, Col: 1, Severity: lexer.SeverityError, Code: lexer.CodeTypeCheck}
+	if line, ok := c.sourceMap.Lookup(pos.Line); ok {
+		d.Line = line
+		d.Col, d.Length = c.locate(line, c.goWordAt(pos))
+	}
+	c.diags = append(c.diags, d)
+}
+
+// importLine returns the line of the dapao for path, or 1.
+func (c *checker) importLine(path string) int {
+	for _, s := range c.program.Statements {
+		if is, ok := s.(*sgast.ImportStatement); ok && strings.Trim(is.Path.Value, "\"`") == path {
+			return is.Token.Line
+		}
+	}
+	return 1
+}
+
+// goWordAt returns the Go at pos: an identifier or selector such as
+// fmt.Println, a literal, or else the single character there.
+func (c *checker) goWordAt(pos token.Position) string {
+	lines := strings.SplitAfter(c.code, "\n")
+	if pos.Line < 1 || pos.Line > len(lines) || pos.Column < 1 || pos.Column > len(lines[pos.Line-1]) {
+		return ""
+	}
+	rest := lines[pos.Line-1][pos.Column-1:]
+	if strings.HasPrefix(rest, `"`) || strings.HasPrefix(rest, "`") || strings.HasPrefix(rest, "'") {
+		if lit, err := strconv.QuotedPrefix(rest); err == nil {
+			return lit
+		}
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		_, end = utf8.DecodeRuneInString(rest)
+	}
+	return strings.TrimRight(rest[:end], ".")
+}
+
+// locate finds the Singlish for the Go word on the given source line and
+// returns its column and length in runes. The word may be written as a
+// dictionary word, such as gong for fmt.Println, or as a renamed name.
+// Without a match the whole line is meant, from its first character.
+func (c *checker) locate(line int, word string) (col, length int) {
+	if line > len(c.src) {
+		return 1, 0
+	}
+	text := strings.TrimRight(c.src[line-1], "\r")
+	var spellings []string
+	for _, r := range c.sourceMap.Renames {
+		if r.Go == word {
+			spellings = append(spellings, r.Singlish)
+		}
+	}
+	if w, ok := c.dict.ReverseLookup(word); ok {
+		spellings = append(spellings, w)
+	}
+	if head, _, ok := strings.Cut(word, "."); ok {
+		spellings = append(spellings, word, head)
+		if w, ok := c.dict.ReverseLookup(head); ok {
+			spellings = append(spellings, w)
+		}
+	} else if word != "" {
+		spellings = append(spellings, word)
+	}
+	for _, s := range spellings {
+		if i := wordIndex(text, s); i >= 0 {
+			return utf8.RuneCountInString(text[:i]) + 1, utf8.RuneCountInString(s)
+		}
+	}
+	indent := len(text) - len(strings.TrimLeft(text, " \t"))
+	return utf8.RuneCountInString(text[:indent]) + 1, 0
+}
+
+// wordIndex returns the byte offset of the first whole-word occurrence of
+// word in text, or -1.
+func wordIndex(text, word string) int {
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return -1
+		}
+		start, end := i+j, i+j+len(word)
+		first, _ := utf8.DecodeRuneInString(word)
+		last, _ := utf8.DecodeLastRuneInString(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before) || !isWordRune(first)) && (end == len(text) || !isWordRune(after) || !isWordRune(last)) {
+			return start
+		}
+		i = end
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// goTypeWords are the Go words in type errors that the dictionary may have
+// Singlish for: the predeclared types and the keywords that build types.
+var goTypeWords = regexp.MustCompile(`\b(bool|byte|complex64|complex128|error|float32|float64|int|int8|int16|int32|int64|rune|string|uint|uint8|uint16|uint32|uint64|uintptr|any|chan|func|interface|map|struct)\b`)
+
+// singlish rewrites a go/types message in Singlish: type names as the
+// dictionary spells them, and renamed names as the author wrote them.
+func (c *checker) singlish(msg string) string {
+	msg = goTypeWords.ReplaceAllStringFunc(msg, func(word string) string {
+		if w, ok := c.dict.ReverseLookup(word); ok {
+			return w
+		}
+		return word
+	})
+	for _, r := range c.sourceMap.Renames {
+		msg = regexp.MustCompile(`\b`+regexp.QuoteMeta(r.Go)+`\b`).ReplaceAllString(msg, r.Singlish)
+	}
+	return msg
+}
@@ -0,0 +1,141 @@
+package typecheck
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+func check(t *testing.T, src string) []lexer.Diagnostic {
+	t.Helper()
+	dict := dictionaries.NewDefaultDictionary()
+	program, err := transpiler.ParseFile(src, dict)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	diags, err := Check(src, program, dict)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	return diags
+}
+
+func TestCheck(t *testing.T) {
+	src := "kampung main\n\naction main() {\n\tgot x nombor = \"a\"\n\tgot ok bolehtak = x\n\tgong(x, ok)\n}\n"
+	diags := check(t, src)
+	if len(diags) != 2 {
+		t.Fatalf("Check() = %+v, want two errors", diags)
+	}
+	tests := []struct {
+		line, col, length int
+		words             []string
+	}{
+		{4, 17, 3, []string{"untyped tar constant", "nombor value"}},
+		{5, 20, 1, []string{"variable of type nombor", "bolehtak value"}},
+	}
+	for i, tt := range tests {
+		d := diags[i]
+		if d.Line != tt.line || d.Col != tt.col || d.Length != tt.length || d.Code != lexer.CodeTypeCheck || d.Severity != lexer.SeverityError {
+			t.Errorf("diagnostic %d = %+v, want line %d col %d length %d", i, d, tt.line, tt.col, tt.length)
+		}
+		for _, w := range tt.words {
+			if !strings.Contains(d.Message, w) {
+				t.Errorf("diagnostic %d message %q does not mention %q", i, d.Message, w)
+			}
+		}
+	}
+}
+
+func TestCheckClean(t *testing.T) {
+	src := "kampung main\n\ndapao \"strings\"\n\naction main() {\n\tgong(strings.ToUpper(\"ok\"), count(\"lah\"))\n}\n"
+	if diags := check(t, src); len(diags) != 0 {
+		t.Errorf("Check() = %+v, want no errors", diags)
+	}
+}
+
+func TestCheckMisspelledType(t *testing.T) {
+	src := "kampung main\n\naction main() {\n\tgot x nombar\n\tgong(x)\n}\n"
+	diags := check(t, src)
+	if len(diags) != 1 || diags[0].Line != 4 || !strings.Contains(diags[0].Message, "undefined: nombar") {
+		t.Fatalf("Check() = %+v, want undefined: nombar on line 4", diags)
+	}
+	if len(diags[0].Notes) != 1 || diags[0].Notes[0] != "did you mean `nombor`?" {
+		t.Errorf("notes = %q, want a hint for nombor", diags[0].Notes)
+	}
+}
+
+func TestCheckRenamed(t *testing.T) {
+	src := "kampung main\n\naction len() nombor {\n\tbalek \"three\"\n}\n\naction main() {\n\tgong(len())\n}\n"
+	diags := check(t, src)
+	if len(diags) != 1 || diags[0].Line != 4 {
+		t.Fatalf("Check() = %+v, want one error on line 4", diags)
+	}
+	if strings.Contains(diags[0].Message, "len_") {
+		t.Errorf("message %q uses the Go name len_", diags[0].Message)
+	}
+}
+
+func TestCheckOutsideStd(t *testing.T) {
+	src := "kampung main\n\ndapao \"example.com/kopi\"\n\naction main() {\n\tkopi.Brew()\n}\n"
+	diags := check(t, src)
+	if len(diags) == 0 || diags[0].Severity != lexer.SeverityNote || diags[0].Line != 3 || !strings.Contains(diags[0].Message, "example.com/kopi") {
+		t.Fatalf("Check() = %+v, want a note on the dapao line", diags)
+	}
+	for _, d := range diags[1:] {
+		if d.Severity == lexer.SeverityError {
+			t.Errorf("unexpected error %+v", d)
+		}
+	}
+}
+
+func TestCheckPackage(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	sources := map[string]string{
+		"widget.singlish": "kampung kedai\n\npattern Widget barang {\n\tgot Name tar\n}\n\naction Make(name tar) Widget {\n\tbalek Widget{Name: name}\n}\n",
+		"shop.singlish":   "kampung kedai\n\naction Open() nombor {\n\tgot w Widget = Make(\"kopi\")\n\tbalek w.Name\n}\n",
+	}
+	var files []File
+	for _, path := range []string{"widget.singlish", "shop.singlish"} {
+		program, err := transpiler.ParseFile(sources[path], dict)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) failed: %v", path, err)
+		}
+		files = append(files, File{Path: path, Source: sources[path], Program: program})
+	}
+	diags, err := CheckPackage(files, dict)
+	if err != nil {
+		t.Fatalf("CheckPackage failed: %v", err)
+	}
+	// Widget and Make come from the other file; only the return is wrong.
+	if len(diags) != 1 || len(diags["shop.singlish"]) != 1 {
+		t.Fatalf("CheckPackage() = %+v, want one error in shop.singlish", diags)
+	}
+	if d := diags["shop.singlish"][0]; d.Line != 5 || !strings.Contains(d.Message, "tar") {
+		t.Errorf("error = %+v, want the tar returned on line 5", d)
+	}
+}
+
+func TestIgnoredErrors(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := "kampung main\n\ndapao \"os\"\n\naction main() {\n\tos.Remove(\"kopi.txt\")\n\tos.Getenv(\"HOME\")\n\tgong(1)\n\tgot f, _ = os.Open(\"kopi.txt\")\n\tf.Close()\n}\n"
+	program, err := transpiler.ParseFile(src, dict)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	calls, err := IgnoredErrors([]File{{Path: "main.singlish", Source: src, Program: program}}, dict)
+	if err != nil {
+		t.Fatalf("IgnoredErrors failed: %v", err)
+	}
+	want := []Call{
+		{Line: 6, Name: "Remove", Func: "os.Remove"},
+		{Line: 8, Name: "Println", Func: "fmt.Println"},
+		{Line: 10, Name: "Close", Func: "(*os.File).Close"},
+	}
+	if !slices.Equal(calls["main.singlish"], want) {
+		t.Errorf("IgnoredErrors() = %+v, want %+v", calls["main.singlish"], want)
+	}
+}
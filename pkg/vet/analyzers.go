@@ -0,0 +1,432 @@
+package vet
+
+import (
+	"fmt"
+	"go/types"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/typecheck"
+)
+
+var unreachable = &Analyzer{
+	Code: lexer.CodeVetUnreachable,
+	Name: "unreachable",
+	Doc:  "code after balek, cabut, go or gabra that can never run",
+	run: func(p *pass) {
+		p.blocks(func(stmts []ast.Statement) {
+			for i, s := range stmts {
+				word, ok := jump(s)
+				if !ok {
+					continue
+				}
+				line, _ := ast.Position(s)
+				for _, next := range stmts[i+1:] {
+					// Words on the jump's own line are its operand, as the
+					// label in "cabut outer" is.
+					if nextLine, col := ast.Position(next); nextLine > line {
+						p.report(nextLine, col, 0, fmt.Sprintf("unreachable code after `%s`", word))
+						return
+					}
+				}
+				return
+			}
+		})
+	},
+}
+
+// jump returns the word that starts s if s never lets control reach the
+// statement after it: a return, break, continue, goto or panic.
+func jump(s ast.Statement) (string, bool) {
+	switch s := s.(type) {
+	case *ast.ReturnStatement:
+		return s.Token.Value, true
+	case *ast.ExpressionStatement:
+		switch e := s.Expression.(type) {
+		case *ast.Identifier:
+			if e != nil && (e.Value == "break" || e.Value == "continue" || e.Value == "goto") {
+				return e.Token.Value, true
+			}
+		case *ast.CallExpression:
+			if id, ok := e.Function.(*ast.Identifier); ok && id != nil && id.Value == "panic" {
+				return id.Token.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+var loopClosure = &Analyzer{
+	Code: lexer.CodeVetLoopClosure,
+	Name: "loopclosure",
+	Doc:  "goroutine started in a loop that uses the loop variable",
+	run: func(p *pass) {
+		inspect(p.program, func(n ast.Node) bool {
+			loop, ok := n.(*ast.ForStatement)
+			if !ok {
+				return true
+			}
+			vars := loopVars(loop)
+			if len(vars) == 0 {
+				return true
+			}
+			inspect(loop.Body, func(n ast.Node) bool {
+				g, ok := n.(*ast.GoStatement)
+				if !ok || g.Call == nil {
+					return true
+				}
+				lit, ok := g.Call.Function.(*ast.FunctionLiteral)
+				if !ok || lit == nil {
+					return true
+				}
+				own := make(map[string]bool)
+				for _, param := range lit.Parameters {
+					if param != nil && param.Name != nil {
+						own[param.Name.Value] = true
+					}
+				}
+				reported := make(map[string]bool)
+				references(lit.Body, func(id *ast.Identifier) {
+					if !vars[id.Value] || own[id.Value] || reported[id.Value] {
+						return
+					}
+					reported[id.Value] = true
+					p.report(id.Token.Line, id.Token.Col, utf8.RuneCountInString(id.Token.Value),
+						fmt.Sprintf("the func started with `%s` uses the loop variable `%s`", g.Token.Value, id.Token.Value),
+						fmt.Sprintf("before Go 1.22 every pass of the `%s` shares one `%s`, so the goroutines may all see its last value; pass it to the func as an argument", loop.Token.Value, id.Token.Value))
+				})
+				return true
+			})
+			return true
+		})
+	},
+}
+
+// loopVars returns the names a loop declares for each pass: the key and
+// value of a range, or the names set with := before the first semicolon.
+func loopVars(loop *ast.ForStatement) map[string]bool {
+	vars := make(map[string]bool)
+	add := func(id *ast.Identifier) {
+		if id != nil && id.Value != "_" {
+			vars[id.Value] = true
+		}
+	}
+	if loop.IsRange {
+		add(loop.Key)
+		add(loop.Value)
+	} else if es, ok := loop.Init.(*ast.ExpressionStatement); ok && es != nil {
+		for _, id := range defined(es.Expression) {
+			add(id)
+		}
+	}
+	return vars
+}
+
+// defined returns the names on the left of e if e is a := assignment.
+func defined(e ast.Expression) []*ast.Identifier {
+	infix, ok := e.(*ast.InfixExpression)
+	if !ok || infix == nil || infix.Operator != ":=" {
+		return nil
+	}
+	var names []*ast.Identifier
+	var collect func(e ast.Expression)
+	collect = func(e ast.Expression) {
+		switch e := e.(type) {
+		case *ast.Identifier:
+			if e != nil {
+				names = append(names, e)
+			}
+		case *ast.InfixExpression:
+			if e != nil && e.Operator == "," {
+				collect(e.Left)
+				collect(e.Right)
+			}
+		}
+	}
+	collect(infix.Left)
+	return names
+}
+
+// references calls f with every identifier under n that can refer to a
+// variable; the names after a dot, as in p.name, cannot.
+func references(n ast.Node, f func(*ast.Identifier)) {
+	inspect(n, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			if !strings.HasSuffix(n.Value, "...") {
+				f(n)
+				break
+			}
+			// The parser keeps a spread argument such as nums... as
+			// text; the names in it are those not after a dot.
+			for _, m := range spreadNames.FindAllStringSubmatch(n.Value, -1) {
+				if m[1] != "." {
+					f(&ast.Identifier{Token: n.Token, Value: m[2]})
+				}
+			}
+		case *ast.InfixExpression:
+			if n.Operator == "." {
+				references(n.Left, f)
+				return false
+			}
+		}
+		return true
+	})
+}
+
+var spreadNames = regexp.MustCompile(`(^|.)([\pL_][\pL\pN_]*)`)
+
+var deferInLoop = &Analyzer{
+	Code: lexer.CodeVetDeferInLoop,
+	Name: "deferloop",
+	Doc:  "nanti inside a loop, which waits for the function to return",
+	run: func(p *pass) {
+		inspect(p.program, func(n ast.Node) bool {
+			loop, ok := n.(*ast.ForStatement)
+			if !ok {
+				return true
+			}
+			inspect(loop.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FunctionLiteral, *ast.ForStatement:
+					// A func has its own returns, and an inner loop is
+					// looked at on its own.
+					return false
+				case *ast.DeferStatement:
+					p.report(n.Token.Line, n.Token.Col, utf8.RuneCountInString(n.Token.Value),
+						fmt.Sprintf("`%s` in a `%s` runs when the function returns, not at the end of each pass", n.Token.Value, loop.Token.Value),
+						"move the body of the loop into a function of its own")
+				}
+				return true
+			})
+			return true
+		})
+	},
+}
+
+var unused = &Analyzer{
+	Code: lexer.CodeVetUnused,
+	Name: "unused",
+	Doc:  "variable declared with got in a function and never used",
+	run: func(p *pass) {
+		for _, s := range p.program.Statements {
+			fn, ok := s.(*ast.FunctionStatement)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			declared := make(map[*ast.Identifier]*ast.LetStatement)
+			var order []*ast.Identifier
+			inspect(fn.Body, func(n ast.Node) bool {
+				if ls, ok := n.(*ast.LetStatement); ok && p.declares(ls, "var") {
+					for _, id := range ls.Names {
+						if id != nil && id.Value != "_" {
+							declared[id] = ls
+							order = append(order, id)
+						}
+					}
+				}
+				return true
+			})
+			if len(order) == 0 {
+				continue
+			}
+			uses := make(map[string]int)
+			references(fn.Body, func(id *ast.Identifier) {
+				if _, ok := declared[id]; !ok {
+					uses[id.Value]++
+				}
+			})
+			for _, id := range order {
+				if uses[id.Value] == 0 {
+					p.report(id.Token.Line, id.Token.Col, utf8.RuneCountInString(id.Token.Value),
+						fmt.Sprintf("`%s` is declared with `%s` but never used", id.Token.Value, declared[id].Token.Value),
+						"Go will not build a function with an unused variable; remove it")
+				}
+			}
+		}
+	},
+}
+
+// declares reports whether ls is written with the word for the Go keyword.
+func (p *pass) declares(ls *ast.LetStatement, keyword string) bool {
+	if ls.Token.Value == keyword {
+		return true
+	}
+	if p.dict == nil {
+		return false
+	}
+	target, ok := p.dict.Lookup(ls.Token.Value)
+	return ok && target == keyword
+}
+
+var ignoredError = &Analyzer{
+	Code: lexer.CodeVetIgnoredError,
+	Name: "errcheck",
+	Doc:  "call whose salah result is thrown away",
+	run: func(p *pass) {
+		byLine := make(map[int][]typecheck.Call)
+		for _, c := range p.ignoredErrors {
+			if !unchecked[c.Func] {
+				byLine[c.Line] = append(byLine[c.Line], c)
+			}
+		}
+		if len(byLine) == 0 {
+			return
+		}
+		p.blocks(func(stmts []ast.Statement) {
+			for _, s := range stmts {
+				es, ok := s.(*ast.ExpressionStatement)
+				if !ok {
+					continue
+				}
+				call, ok := es.Expression.(*ast.CallExpression)
+				if !ok || call == nil {
+					continue
+				}
+				var name *ast.Identifier
+				switch f := call.Function.(type) {
+				case *ast.Identifier:
+					name = f
+				case *ast.InfixExpression:
+					if id, ok := f.Right.(*ast.Identifier); ok && f.Operator == "." {
+						name = id
+					}
+				}
+				if name == nil {
+					continue
+				}
+				goName := p.goName(name)
+				if !slices.ContainsFunc(byLine[name.Token.Line], func(c typecheck.Call) bool { return c.Name == goName || c.Name == goName+"_" }) {
+					continue
+				}
+				p.report(name.Token.Line, name.Token.Col, utf8.RuneCountInString(name.Token.Value),
+					fmt.Sprintf("the `%s` returned by `%s` is ignored", p.word("error"), name.Token.Value),
+					"handle it, or assign it to _ if ignoring it is on purpose")
+			}
+		})
+	},
+}
+
+// unchecked are the functions whose error results errcheck leaves alone, as
+// Go's errcheck does: printing to standard output, and writing to buffers,
+// which cannot fail.
+var unchecked = map[string]bool{
+	"fmt.Print":                      true,
+	"fmt.Printf":                     true,
+	"fmt.Println":                    true,
+	"(*bytes.Buffer).Write":          true,
+	"(*bytes.Buffer).WriteByte":      true,
+	"(*bytes.Buffer).WriteRune":      true,
+	"(*bytes.Buffer).WriteString":    true,
+	"(*strings.Builder).Write":       true,
+	"(*strings.Builder).WriteByte":   true,
+	"(*strings.Builder).WriteRune":   true,
+	"(*strings.Builder).WriteString": true,
+}
+
+// goName returns the Go name id is called by: the last part of the Go a
+// dictionary word stands for, as Println for gong, or else id itself.
+func (p *pass) goName(id *ast.Identifier) string {
+	if p.dict != nil {
+		if target, ok := p.dict.Lookup(id.Token.Value); ok {
+			return target[strings.LastIndex(target, ".")+1:]
+		}
+	}
+	return id.Value
+}
+
+var shadow = &Analyzer{
+	Code: lexer.CodeVetShadow,
+	Name: "shadow",
+	Doc:  "local name that hides the Go a keyword stands for, such as len for count",
+	run: func(p *pass) {
+		check := func(id *ast.Identifier) {
+			if id == nil || types.Universe.Lookup(id.Value) == nil {
+				return
+			}
+			word := p.word(id.Value)
+			if word == id.Value {
+				return
+			}
+			p.report(id.Token.Line, id.Token.Col, utf8.RuneCountInString(id.Token.Value),
+				fmt.Sprintf("`%s` hides the Go `%s` that `%s` stands for", id.Token.Value, id.Value, word),
+				fmt.Sprintf("`%s` means this `%s` wherever it is in scope; rename it", word, id.Token.Value))
+		}
+		params := func(fields []*ast.FieldDefinition) {
+			for _, f := range fields {
+				if f != nil {
+					check(f.Name)
+				}
+			}
+		}
+		// Top-level names are renamed when the Go is generated, so only
+		// the names declared inside functions can hide anything.
+		for _, s := range p.program.Statements {
+			fn, ok := s.(*ast.FunctionStatement)
+			if !ok {
+				continue
+			}
+			if fn.Receiver != nil {
+				params([]*ast.FieldDefinition{fn.Receiver})
+			}
+			params(fn.Parameters)
+			inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.LetStatement:
+					for _, id := range n.Names {
+						check(id)
+					}
+				case *ast.ExpressionStatement:
+					for _, id := range defined(n.Expression) {
+						check(id)
+					}
+				case *ast.ForStatement:
+					if n.IsRange {
+						check(n.Key)
+						check(n.Value)
+					}
+				case *ast.FunctionLiteral:
+					params(n.Parameters)
+				}
+				return true
+			})
+		}
+	},
+}
+
+var goContinue = &Analyzer{
+	Code: lexer.CodeVetGoContinue,
+	Name: "gocontinue",
+	Doc:  "the word for continue followed by a call, where chiong was meant",
+	run: func(p *pass) {
+		p.blocks(func(stmts []ast.Statement) {
+			for i := 0; i+1 < len(stmts); i++ {
+				es, ok := stmts[i].(*ast.ExpressionStatement)
+				if !ok {
+					continue
+				}
+				id, ok := es.Expression.(*ast.Identifier)
+				if !ok || id == nil || id.Value != "continue" {
+					continue
+				}
+				next, ok := stmts[i+1].(*ast.ExpressionStatement)
+				if !ok {
+					continue
+				}
+				if _, ok := next.Expression.(*ast.CallExpression); !ok {
+					continue
+				}
+				if line, _ := ast.Position(next); line != id.Token.Line {
+					continue
+				}
+				p.report(id.Token.Line, id.Token.Col, utf8.RuneCountInString(id.Token.Value),
+					fmt.Sprintf("`%s` means continue, so the call after it is never made", id.Token.Value),
+					fmt.Sprintf("write `%s` to run the call in a goroutine", p.word("go")))
+			}
+		})
+	},
+}
@@ -0,0 +1,176 @@
+package vet
+
+import (
+	"strings"
+	"testing"
+)
+
+// finding is a diagnostic as the tests compare it.
+type finding struct {
+	line, col int
+	code      string
+}
+
+func TestAnalyzers(t *testing.T) {
+	tests := []struct {
+		name string
+		body string // the statements of main, from line 4 on
+		want []finding
+		text string // in the first message
+	}{
+		{
+			name: "unreachable after balek",
+			body: "\tgong(1)\n\tbalek\n\tgong(2)\n\tgong(3)\n",
+			want: []finding{{6, 2, "SG6001"}},
+			text: "after `balek`",
+		},
+		{
+			name: "unreachable after gabra and cabut",
+			body: "\tloop {\n\t\tcabut\n\t\tgong(1)\n\t}\n\tgabra(\"no\")\n\tgong(2)\n",
+			want: []finding{{6, 3, "SG6001"}, {9, 2, "SG6001"}},
+			text: "after `cabut`",
+		},
+		{
+			name: "reachable",
+			body: "\tnasi can {\n\t\tbalek\n\t}\n\tgong(1)\n",
+		},
+		{
+			name: "loop variable in goroutine",
+			body: "\tloop i := 0; i < 3; i++ {\n\t\tchiong func() {\n\t\t\tgong(i, i)\n\t\t}()\n\t}\n",
+			want: []finding{{6, 9, "SG6002"}},
+			text: "loop variable `i`",
+		},
+		{
+			name: "range variable in goroutine",
+			body: "\tnums := []nombor{1}\n\tloop _, v = all nums {\n\t\tchiong func() {\n\t\t\tgong(v)\n\t\t}()\n\t}\n",
+			want: []finding{{7, 9, "SG6002"}},
+		},
+		{
+			name: "loop variable passed in",
+			body: "\tloop i := 0; i < 3; i++ {\n\t\tchiong func(i nombor) {\n\t\t\tgong(i)\n\t\t}(i)\n\t}\n",
+		},
+		{
+			name: "defer in loop",
+			body: "\tloop i := 0; i < 3; i++ {\n\t\tnanti gong(i)\n\t\tloop {\n\t\t\tnanti gong(i)\n\t\t}\n\t}\n",
+			want: []finding{{5, 3, "SG6003"}, {7, 4, "SG6003"}},
+			text: "`nanti` in a `loop`",
+		},
+		{
+			name: "defer in func in loop",
+			body: "\tloop i := 0; i < 3; i++ {\n\t\tf := func() {\n\t\t\tnanti gong(i)\n\t\t}\n\t\tf()\n\t}\n",
+		},
+		{
+			name: "unused",
+			body: "\tgot a nombor\n\tgot b, c nombor\n\tgot d = []nombor{1}\n\tgong(c, count(d...))\n",
+			want: []finding{{4, 6, "SG6004"}, {5, 6, "SG6004"}},
+			text: "`a` is declared with `got`",
+		},
+		{
+			name: "used in a closure",
+			body: "\tgot a nombor\n\tf := func() {\n\t\tgong(a)\n\t}\n\tf()\n",
+		},
+		{
+			name: "ignored error",
+			body: "\tsave()\n\terr := save()\n\tgong(err)\n\tk := Kedai{}\n\tk.Tutup()\n\tk.Buka()\n",
+			want: []finding{{4, 2, "SG6005"}, {8, 4, "SG6005"}},
+			text: "the `salah` returned by `save`",
+		},
+		{
+			name: "shadowed builtin",
+			body: "\tlen := 3\n\tgot tulis nombor\n\tloop _, string = all []tar{} {\n\t\tgong(string)\n\t}\n\tgong(len, tulis)\n",
+			want: []finding{{4, 2, "SG6006"}, {6, 10, "SG6006"}},
+			text: "`len` hides the Go `len` that `count` stands for",
+		},
+		{
+			name: "go where chiong was meant",
+			body: "\tloop {\n\t\tgo gong(1)\n\t}\n\tloop {\n\t\tgo\n\t\tgong(2)\n\t}\n",
+			want: []finding{{5, 3, "SG6007"}, {9, 3, "SG6001"}},
+			text: "`go` means continue",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "kampung main\n\naction main() {\n" + tt.body + "}\n" + helpers
+			diags := run(t, src, Analyzers)
+			var got []finding
+			for _, d := range diags {
+				got = append(got, finding{d.Line, d.Col, d.Code})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Run() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Run() = %v, want %v", got, tt.want)
+				}
+			}
+			if tt.text != "" && !strings.Contains(diags[0].Message, tt.text) {
+				t.Errorf("message %q does not contain %q", diags[0].Message, tt.text)
+			}
+		})
+	}
+}
+
+// helpers are declarations the test programs call.
+const helpers = `
+action save() salah {
+	balek kosong
+}
+
+pattern Kedai barang {
+	got nama tar
+}
+
+action (k Kedai) Tutup() (nombor, salah) {
+	balek 0, kosong
+}
+
+action (k Kedai) Buka() nombor {
+	balek 0
+}
+`
+
+func TestIgnoredErrorTypes(t *testing.T) {
+	src := `kampung main
+
+dapao (
+	"os"
+	"strings"
+)
+
+pattern Pintu barang {
+	got nama tar
+}
+
+action (p Pintu) Close() {
+}
+
+pattern Kedai barang {
+	got nama tar
+}
+
+action (k ki Kedai) Close() salah {
+	balek kosong
+}
+
+action main() {
+	os.Remove("kopi.txt")
+	os.Getenv("HOME")
+	p := Pintu{}
+	p.Close()
+	k := &Kedai{}
+	k.Close()
+	got sb strings.Builder
+	sb.WriteString("kopi")
+	gong(sb.String())
+}
+`
+	var got []finding
+	for _, d := range run(t, src, []*Analyzer{ignoredError}) {
+		got = append(got, finding{d.Line, d.Col, d.Code})
+	}
+	want := []finding{{24, 5, "SG6005"}, {29, 4, "SG6005"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Run(errcheck) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,421 @@
+// Package vet finds suspicious Singlish code: code that compiles, or fails
+// to compile in a confusing way, but probably does not do what its author
+// meant. Each check is an Analyzer with a diagnostic code and a short name,
+// either of which selects it. A line can be exempted with a comment:
+//
+//	got spare nombor //singlish:ignore unused
+//
+//	//singlish:ignore SG6003
+//	nanti f.Close()
+//
+// The comment covers its own line, or the next line when it stands alone.
+// Without rule names it silences every rule.
+package vet
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/typecheck"
+)
+
+// Analyzer is one check.
+type Analyzer struct {
+	Code string // diagnostic code of its reports, such as SG6001
+	Name string // short name, such as unreachable
+	Doc  string // one line saying what it finds
+	run  func(*pass)
+}
+
+// Analyzers are all the checks, in the order of their codes.
+var Analyzers = []*Analyzer{
+	unreachable,
+	loopClosure,
+	deferInLoop,
+	unused,
+	ignoredError,
+	shadow,
+	goContinue,
+}
+
+// Lookup returns the analyzer with the code or name.
+func Lookup(name string) (*Analyzer, bool) {
+	for _, a := range Analyzers {
+		if strings.EqualFold(name, a.Code) || name == a.Name {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// Select returns the analyzers to run: those named in enable, or all of them
+// if enable is empty, less those named in disable.
+func Select(enable, disable []string) ([]*Analyzer, error) {
+	selected := Analyzers
+	if len(enable) > 0 {
+		selected = nil
+		for _, name := range enable {
+			a, ok := Lookup(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown vet rule %q", name)
+			}
+			selected = append(selected, a)
+		}
+	}
+	off := make(map[*Analyzer]bool)
+	for _, name := range disable {
+		a, ok := Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown vet rule %q", name)
+		}
+		off[a] = true
+	}
+	var result []*Analyzer
+	for _, a := range Analyzers {
+		if !off[a] && containsAnalyzer(selected, a) {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func containsAnalyzer(list []*Analyzer, a *Analyzer) bool {
+	for _, b := range list {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs analyzers over program, parsed from src, and returns what they
+// find that no //singlish:ignore comment silences, in source order.
+func Run(src string, program *ast.Program, dict *dictionaries.Dictionary, analyzers []*Analyzer) []lexer.Diagnostic {
+	if program == nil {
+		return nil
+	}
+	diags, err := RunPackage([]typecheck.File{{Source: src, Program: program}}, dict, analyzers)
+	if err != nil {
+		return nil
+	}
+	return diags[""]
+}
+
+// RunPackage runs analyzers over files, which make up one package, and
+// returns what they find in each, by its Path, as Run does. Files are
+// type-checked together for the rules that need types.
+func RunPackage(files []typecheck.File, dict *dictionaries.Dictionary, analyzers []*Analyzer) (map[string][]lexer.Diagnostic, error) {
+	var calls map[string][]typecheck.Call
+	if containsAnalyzer(analyzers, ignoredError) {
+		var err error
+		if calls, err = typecheck.IgnoredErrors(files, dict); err != nil {
+			return nil, err
+		}
+	}
+	result := make(map[string][]lexer.Diagnostic)
+	for _, f := range files {
+		if f.Program == nil {
+			continue
+		}
+		p := &pass{program: f.Program, dict: dict, ignoredErrors: calls[f.Path]}
+		for _, a := range analyzers {
+			p.analyzer = a
+			a.run(p)
+		}
+		if diags := filter(f.Source, p.diags); len(diags) > 0 {
+			result[f.Path] = diags
+		}
+	}
+	return result, nil
+}
+
+// filter drops the diagnostics that //singlish:ignore comments in src
+// silence and those found twice, and sorts the rest into source order.
+func filter(src string, found []lexer.Diagnostic) []lexer.Diagnostic {
+	ignored := ignores(src)
+	type key struct {
+		code      string
+		line, col int
+	}
+	seen := make(map[key]bool)
+	var diags []lexer.Diagnostic
+	for _, d := range found {
+		if names, ok := ignored[d.Line]; ok && (len(names) == 0 || names[d.Code]) {
+			continue
+		}
+		// Nested loops can find the same thing twice.
+		if k := (key{d.Code, d.Line, d.Col}); !seen[k] {
+			seen[k] = true
+			diags = append(diags, d)
+		}
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Col < diags[j].Col
+	})
+	return diags
+}
+
+// ignoreDirective starts a comment that silences rules.
+const ignoreDirective = "//singlish:ignore"
+
+// ignores returns the lines exempted by //singlish:ignore comments in src
+// and the codes of the rules exempted on each; an empty set means all.
+func ignores(src string) map[int]map[string]bool {
+	tokens, _ := lexer.Lex(src, nil)
+	lines := make(map[int]map[string]bool)
+	lastCode := 0 // line of the last token that is not a comment
+	for _, tok := range tokens {
+		if tok.Type != lexer.TokenComment {
+			lastCode = tok.Line
+			continue
+		}
+		rest, ok := strings.CutPrefix(tok.Value, ignoreDirective)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		line := tok.Line
+		if lastCode != tok.Line {
+			line++ // on a line of its own: covers the next line
+		}
+		codes := make(map[string]bool)
+		for _, name := range strings.FieldsFunc(rest, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+			if a, ok := Lookup(name); ok {
+				codes[a.Code] = true
+			}
+		}
+		if len(codes) == 0 {
+			lines[line] = codes
+		} else if prev, ok := lines[line]; !ok || len(prev) > 0 {
+			for c := range prev {
+				codes[c] = true
+			}
+			lines[line] = codes
+		}
+	}
+	return lines
+}
+
+// pass is the state of one run.
+type pass struct {
+	program       *ast.Program
+	dict          *dictionaries.Dictionary
+	ignoredErrors []typecheck.Call // calls in the program that throw away an error
+	analyzer      *Analyzer        // the analyzer running
+	diags         []lexer.Diagnostic
+}
+
+// report adds a warning from the running analyzer at line and col.
+func (p *pass) report(line, col, length int, msg string, notes ...string) {
+	p.diags = append(p.diags, lexer.Diagnostic{
+		Message:  msg,
+		Line:     line,
+		Col:      col,
+		Length:   length,
+		Severity: lexer.SeverityWarning,
+		Code:     p.analyzer.Code,
+		Notes:    notes,
+	})
+}
+
+// word returns the dictionary's word for the Go, or the Go itself.
+func (p *pass) word(goWord string) string {
+	if p.dict != nil {
+		if w, ok := p.dict.ReverseLookup(goWord); ok {
+			return w
+		}
+	}
+	return goWord
+}
+
+// functions calls f with the body of every function and function literal
+// in the program, outermost first.
+func (p *pass) functions(f func(params []*ast.FieldDefinition, body *ast.BlockStatement)) {
+	inspect(p.program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionStatement:
+			if n.Body != nil {
+				params := n.Parameters
+				if n.Receiver != nil {
+					params = append([]*ast.FieldDefinition{n.Receiver}, params...)
+				}
+				f(params, n.Body)
+			}
+		case *ast.FunctionLiteral:
+			if n.Body != nil {
+				f(n.Parameters, n.Body)
+			}
+		}
+		return true
+	})
+}
+
+// blocks calls f with the statements of every block in the program, and
+// of the cases of switches and selects, which have no braces of their own.
+func (p *pass) blocks(f func(stmts []ast.Statement)) {
+	inspect(p.program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.BlockStatement:
+			f(n.Statements)
+		case *ast.SwitchStatement:
+			for _, c := range n.Cases {
+				if c != nil && c.Body != nil {
+					f(c.Body.Statements)
+				}
+			}
+		case *ast.SelectCase:
+			if n.Body != nil {
+				f(n.Body.Statements)
+			}
+		}
+		return true
+	})
+}
+
+// inspect walks the tree under n in source order, calling f for each node;
+// f returns false to skip the children of a node. Case bodies are reached
+// through their case, not as blocks of their own.
+func inspect(n ast.Node, f func(ast.Node) bool) {
+	if isNil(n) || !f(n) {
+		return
+	}
+	walk := func(children ...ast.Node) {
+		for _, c := range children {
+			inspect(c, f)
+		}
+	}
+	switch n := n.(type) {
+	case *ast.Program:
+		for _, s := range n.Statements {
+			walk(s)
+		}
+	case *ast.BlockStatement:
+		for _, s := range n.Statements {
+			walk(s)
+		}
+	case *ast.ExpressionStatement:
+		walk(n.Expression)
+	case *ast.LetStatement:
+		for _, name := range n.Names {
+			walk(name)
+		}
+		walk(n.Type, n.Value)
+	case *ast.ReturnStatement:
+		for _, v := range n.ReturnValues {
+			walk(v)
+		}
+	case *ast.TypeStatement:
+		walk(n.Name, n.Value)
+	case *ast.FunctionStatement:
+		if n.Receiver != nil {
+			walk(fieldNodes(n.Receiver)...)
+		}
+		walk(n.Name)
+		for _, p := range n.Parameters {
+			walk(fieldNodes(p)...)
+		}
+		walk(n.ReturnType, n.Body)
+	case *ast.FunctionLiteral:
+		for _, p := range n.Parameters {
+			walk(fieldNodes(p)...)
+		}
+		walk(n.ReturnType, n.Body)
+	case *ast.IfStatement:
+		walk(n.Condition, n.Consequence, n.AlternativeStmt)
+	case *ast.ForStatement:
+		walk(n.Init, n.Condition, n.Post, n.Key, n.Value, n.Iterable, n.Body)
+	case *ast.SwitchStatement:
+		walk(n.Expression)
+		for _, c := range n.Cases {
+			if c == nil {
+				continue
+			}
+			for _, e := range c.Expressions {
+				walk(e)
+			}
+			if c.Body != nil {
+				for _, s := range c.Body.Statements {
+					walk(s)
+				}
+			}
+		}
+	case *ast.SelectStatement:
+		for _, c := range n.Cases {
+			walk(c)
+		}
+	case *ast.SelectCase:
+		walk(n.Comm)
+		if n.Body != nil {
+			for _, s := range n.Body.Statements {
+				walk(s)
+			}
+		}
+	case *ast.GoStatement:
+		walk(n.Call)
+	case *ast.DeferStatement:
+		walk(n.Call)
+	case *ast.IncDecStatement:
+		walk(n.Left)
+	case *ast.PrefixExpression:
+		walk(n.Right)
+	case *ast.InfixExpression:
+		walk(n.Left, n.Right)
+	case *ast.IndexExpression:
+		walk(n.Left, n.Index)
+	case *ast.SliceExpression:
+		walk(n.Left, n.Low, n.High)
+	case *ast.CallExpression:
+		walk(n.Function)
+		for _, a := range n.Arguments {
+			walk(a)
+		}
+	case *ast.KeyValueExpression:
+		walk(n.Key, n.Value)
+	case *ast.CompositeLiteral:
+		walk(n.Type)
+		for _, e := range n.Elements {
+			walk(e)
+		}
+	case *ast.TypeAssertionExpression:
+		walk(n.Left, n.Type)
+	case *ast.StructLiteral:
+		for _, fd := range n.Fields {
+			walk(fieldNodes(fd)...)
+		}
+	case *ast.InterfaceLiteral:
+		for _, m := range n.Methods {
+			if m == nil {
+				continue
+			}
+			walk(m.Name)
+			for _, p := range m.Parameters {
+				walk(fieldNodes(p)...)
+			}
+			walk(m.ReturnType)
+		}
+	}
+}
+
+func fieldNodes(fd *ast.FieldDefinition) []ast.Node {
+	if fd == nil {
+		return nil
+	}
+	return []ast.Node{fd.Name, fd.Type}
+}
+
+// isNil reports whether n is nil or a nil pointer, as the parser leaves
+// behind for parts it could not read.
+func isNil(n ast.Node) bool {
+	if n == nil {
+		return true
+	}
+	v := reflect.ValueOf(n)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,130 @@
+package vet
+
+import (
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+	"github.com/rickchow/singlish/pkg/transpiler"
+	"github.com/rickchow/singlish/pkg/typecheck"
+)
+
+// run vets src with the default dictionary and analyzers.
+func run(t *testing.T, src string, analyzers []*Analyzer) []lexer.Diagnostic {
+	t.Helper()
+	dict := dictionaries.NewDefaultDictionary()
+	program, err := transpiler.ParseFile(src, dict)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+	return Run(src, program, dict, analyzers)
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		enable, disable []string
+		want            []string
+	}{
+		{nil, nil, []string{"SG6001", "SG6002", "SG6003", "SG6004", "SG6005", "SG6006", "SG6007"}},
+		{nil, []string{"unused", "sg6001", "shadow"}, []string{"SG6002", "SG6003", "SG6005", "SG6007"}},
+		{[]string{"gocontinue", "SG6003"}, nil, []string{"SG6003", "SG6007"}},
+		{[]string{"unused", "errcheck"}, []string{"SG6004"}, []string{"SG6005"}},
+	}
+	for _, tt := range tests {
+		got, err := Select(tt.enable, tt.disable)
+		if err != nil {
+			t.Errorf("Select(%q, %q) failed: %v", tt.enable, tt.disable, err)
+			continue
+		}
+		var codes []string
+		for _, a := range got {
+			codes = append(codes, a.Code)
+		}
+		if len(codes) != len(tt.want) {
+			t.Errorf("Select(%q, %q) = %q, want %q", tt.enable, tt.disable, codes, tt.want)
+			continue
+		}
+		for i := range codes {
+			if codes[i] != tt.want[i] {
+				t.Errorf("Select(%q, %q) = %q, want %q", tt.enable, tt.disable, codes, tt.want)
+				break
+			}
+		}
+	}
+	if _, err := Select([]string{"kopi"}, nil); err == nil {
+		t.Error("Select(unknown rule) succeeded, want an error")
+	}
+	if _, err := Select(nil, []string{"SG9999"}); err == nil {
+		t.Error("Select(unknown code) succeeded, want an error")
+	}
+}
+
+func TestIgnore(t *testing.T) {
+	src := `kampung main
+
+action main() {
+	got a nombor
+	got b nombor //singlish:ignore unused
+	got c nombor //singlish:ignore SG6001, SG6006
+	//singlish:ignore
+	got d nombor
+	// singlish:ignore is not a directive with a space
+	got e nombor
+	got f nombor //singlish:ignored
+}
+`
+	var lines []int
+	for _, d := range run(t, src, Analyzers) {
+		lines = append(lines, d.Line)
+	}
+	want := []int{4, 6, 10, 11}
+	if len(lines) != len(want) {
+		t.Fatalf("Run() reported lines %v, want %v", lines, want)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("Run() reported lines %v, want %v", lines, want)
+		}
+	}
+}
+
+func TestRunSelected(t *testing.T) {
+	src := "kampung main\n\naction main() {\n\tgot a nombor\n\tbalek\n\tgong(1)\n}\n"
+	only, err := Select([]string{"unreachable"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := run(t, src, only)
+	if len(diags) != 1 || diags[0].Code != lexer.CodeVetUnreachable || diags[0].Severity != lexer.SeverityWarning {
+		t.Errorf("Run(unreachable) = %+v, want one unreachable warning", diags)
+	}
+	if diags := run(t, src, nil); len(diags) != 0 {
+		t.Errorf("Run(no analyzers) = %+v, want nothing", diags)
+	}
+}
+
+func TestRunPackage(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	sources := map[string]string{
+		"main.singlish":  "kampung main\n\naction main() {\n\tk := Kedai{}\n\tk.Tutup()\n}\n",
+		"kedai.singlish": "kampung main\n\npattern Kedai barang {\n\tgot nama tar\n}\n\naction (k Kedai) Tutup() salah {\n\tbalek kosong\n}\n",
+	}
+	var files []typecheck.File
+	for _, path := range []string{"main.singlish", "kedai.singlish"} {
+		program, err := transpiler.ParseFile(sources[path], dict)
+		if err != nil {
+			t.Fatalf("ParseFile(%s) failed: %v", path, err)
+		}
+		files = append(files, typecheck.File{Path: path, Source: sources[path], Program: program})
+	}
+	diags, err := RunPackage(files, dict, Analyzers)
+	if err != nil {
+		t.Fatalf("RunPackage failed: %v", err)
+	}
+	if got := diags["main.singlish"]; len(got) != 1 || got[0].Code != lexer.CodeVetIgnoredError || got[0].Line != 5 {
+		t.Errorf("RunPackage() main.singlish = %+v, want the ignored error of Tutup on line 5", got)
+	}
+	if got := diags["kedai.singlish"]; len(got) != 0 {
+		t.Errorf("RunPackage() kedai.singlish = %+v, want nothing", got)
+	}
+}
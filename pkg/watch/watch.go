@@ -0,0 +1,114 @@
+// Package watch notices when files change by polling their contents.
+//
+// Polling keeps the package free of platform-specific file notification
+// APIs, and comparing contents rather than modification times means that
+// rewriting a file with identical bytes (as singlish fmt does) is not
+// reported as a change.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"sort"
+	"time"
+)
+
+// Default timings used when a Watcher leaves them zero.
+const (
+	DefaultInterval = 250 * time.Millisecond
+	DefaultDebounce = 200 * time.Millisecond
+)
+
+// Watcher reports changes to a set of files.
+type Watcher struct {
+	// Paths returns the files to watch. It is called on every poll so that
+	// files appearing later (a new _test.singlish, say) are picked up.
+	Paths func() []string
+
+	Interval time.Duration // how often to poll
+	Debounce time.Duration // how long files must stay unchanged before a change is reported
+}
+
+// snapshot maps each watched path to a hash of its contents; missing or
+// unreadable files hash to the zero value.
+type snapshot map[string][sha256.Size]byte
+
+func (w *Watcher) snapshot() snapshot {
+	snap := make(snapshot)
+	for _, path := range w.Paths() {
+		var sum [sha256.Size]byte
+		if data, err := os.ReadFile(path); err == nil {
+			sum = sha256.Sum256(data)
+		}
+		snap[path] = sum
+	}
+	return snap
+}
+
+// changed lists the paths that differ between two snapshots.
+func changed(old, cur snapshot) []string {
+	var paths []string
+	for path, sum := range cur {
+		if prev, ok := old[path]; !ok || prev != sum {
+			paths = append(paths, path)
+		}
+	}
+	for path := range old {
+		if _, ok := cur[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Run polls until ctx is done. Once the files have changed and then stayed
+// unchanged for the debounce period, it calls onChange with the sorted
+// paths that changed. Rapid successive saves therefore trigger one call.
+// onChange runs on the polling goroutine, so long-running work should be
+// started in the background.
+func (w *Watcher) Run(ctx context.Context, onChange func(changed []string)) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	last := w.snapshot()
+	pending := make(map[string]bool)
+	var lastChange time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := w.snapshot()
+		if diff := changed(last, cur); len(diff) > 0 {
+			for _, path := range diff {
+				pending[path] = true
+			}
+			lastChange = time.Now()
+			last = cur
+			continue
+		}
+		if len(pending) == 0 || time.Since(lastChange) < debounce {
+			continue
+		}
+
+		paths := make([]string, 0, len(pending))
+		for path := range pending {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		pending = make(map[string]bool)
+		onChange(paths)
+	}
+}
@@ -0,0 +1,59 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunDebouncesChanges(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.singlish")
+	b := filepath.Join(dir, "b.singlish")
+	if err := os.WriteFile(a, []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Watcher{
+		Paths:    func() []string { return []string{a, b} },
+		Interval: 10 * time.Millisecond,
+		Debounce: 50 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := make(chan []string, 10)
+	go w.Run(ctx, func(changed []string) { calls <- changed })
+
+	// Let the watcher take its first snapshot, then save several times in quick succession.
+	time.Sleep(30 * time.Millisecond)
+	for _, content := range []string{"two", "three", "four"} {
+		if err := os.WriteFile(a, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(15 * time.Millisecond)
+	}
+	if err := os.WriteFile(b, []byte("new file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-calls:
+		if len(got) != 2 || got[0] != a || got[1] != b {
+			t.Errorf("changed = %v, want [%s %s]", got, a, b)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change reported")
+	}
+
+	// Rewriting identical contents is not a change.
+	if err := os.WriteFile(a, []byte("four"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-calls:
+		t.Errorf("unexpected change report %v", got)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
@@ -0,0 +1,60 @@
+package workspace
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/lexer"
+)
+
+// IsTestFile reports whether path names a Singlish test file (*_test.singlish).
+func IsTestFile(path string) bool {
+	return strings.HasSuffix(path, "_test.singlish")
+}
+
+// TestFunctions returns the names of the tests declared in program, i.e.
+// top-level actions named TestXxx. A test must look like
+// action TestXxx(t ki testing.T); any other signature is reported as a
+// diagnostic at the offending action.
+func TestFunctions(program *ast.Program, dict *dictionaries.Dictionary) ([]string, []lexer.Diagnostic) {
+	var names []string
+	var diags []lexer.Diagnostic
+	for _, s := range program.Statements {
+		fn, ok := s.(*ast.FunctionStatement)
+		if !ok || fn.Receiver != nil || fn.Name == nil {
+			continue
+		}
+		name := fn.Name.Value
+		if translated, found := dict.Lookup(name); found {
+			name = translated
+		}
+		if !isTestName(name) {
+			continue
+		}
+		if len(fn.Parameters) != 1 || fn.Parameters[0].Type == nil ||
+			fn.Parameters[0].Type.String() != "*testing.T" || fn.ReturnType != nil {
+			diags = append(diags, lexer.Diagnostic{
+				Message: fmt.Sprintf("wrong signature for %s, must be: action %s(t ki testing.T)", name, name),
+				Line:    fn.Name.Token.Line,
+				Col:     fn.Name.Token.Col,
+				Length:  utf8.RuneCountInString(fn.Name.Value),
+				Code:    lexer.CodeTestSignature,
+			})
+			continue
+		}
+		names = append(names, name)
+	}
+	return names, diags
+}
+
+// isTestName mirrors go test: "Test" followed by nothing or a non-lowercase letter.
+func isTestName(name string) bool {
+	if !strings.HasPrefix(name, "Test") {
+		return false
+	}
+	rest := name[len("Test"):]
+	return rest == "" || !(rest[0] >= 'a' && rest[0] <= 'z')
+}
@@ -0,0 +1,226 @@
+// Package workspace builds a throwaway Go module out of Singlish source files
+// so that the go tool can compile and test them, and maps the tool's output
+// back to Singlish file names and line numbers.
+package workspace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/rickchow/singlish/pkg/ast"
+	"github.com/rickchow/singlish/pkg/codegen"
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/suggest"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+// ModulePath is the module path written to the workspace go.mod.
+const ModulePath = "singlishpkg"
+
+// Workspace is a temporary Go module holding transpiled Singlish files.
+type Workspace struct {
+	Dir   string // directory of the generated module
+	Label string // shown in place of ModulePath in mapped output
+
+	dict  *dictionaries.Dictionary
+	files map[string]*sourceFile // generated Go base name -> Singlish origin
+}
+
+type sourceFile struct {
+	path      string
+	program   *ast.Program
+	code      string // the Go last written for it
+	sourceMap *codegen.SourceMap
+}
+
+// New creates an empty workspace in a fresh temporary directory.
+func New(dict *dictionaries.Dictionary) (*Workspace, error) {
+	dir, err := os.MkdirTemp("", "singlish_ws_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	goMod := fmt.Sprintf("module %s\n\ngo %s\n", ModulePath, goVersion())
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0644); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to write go.mod: %w", err)
+	}
+	return &Workspace{
+		Dir:   dir,
+		Label: ModulePath,
+		dict:  dict,
+		files: make(map[string]*sourceFile),
+	}, nil
+}
+
+// goVersion returns the language version of the running toolchain, e.g. "1.22".
+func goVersion() string {
+	v := strings.TrimPrefix(runtime.Version(), "go")
+	parts := strings.SplitN(v, ".", 3)
+	if len(parts) < 2 {
+		return "1.21"
+	}
+	if _, err := strconv.Atoi(parts[1]); err != nil {
+		return "1.21"
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// Add transpiles the Singlish file at path into the workspace. foo.singlish
+// becomes foo.go and foo_test.singlish becomes foo_test.go. Adding the same
+// file again replaces its earlier version. Files already added are written
+// again if their Go changes, as when the new file declares a builtin that
+// they then refer to by its Go name. It returns the parsed program so
+// callers can inspect it further.
+func (w *Workspace) Add(path string) (*ast.Program, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %w", err)
+	}
+
+	program, err := transpiler.ParseFile(string(content), w.dict)
+	if err != nil {
+		return nil, err
+	}
+
+	name := goName(path)
+	prev, ok := w.files[name]
+	if ok && prev.path != path {
+		return nil, fmt.Errorf("%s and %s both transpile to %s", prev.path, path, name)
+	}
+	w.files[name] = &sourceFile{path: path, program: program}
+	if err := w.generate(); err != nil {
+		if ok {
+			w.files[name] = prev
+		} else {
+			delete(w.files, name)
+		}
+		return nil, err
+	}
+	return program, nil
+}
+
+// Remove takes the transpiled form of the Singlish file at path out of the
+// workspace.
+func (w *Workspace) Remove(path string) error {
+	name := goName(path)
+	if src, ok := w.files[name]; !ok || src.path != path {
+		return nil
+	}
+	delete(w.files, name)
+	if err := os.Remove(filepath.Join(w.Dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return w.generate()
+}
+
+// generate writes the Go for each file whose Go changed, transpiling the
+// files together as one package.
+func (w *Workspace) generate() error {
+	pkg := make([]*ast.Program, 0, len(w.files))
+	for _, src := range w.files {
+		pkg = append(pkg, src.program)
+	}
+	for name, src := range w.files {
+		code, sourceMap, err := codegen.GenerateInPackage(src.program, pkg, w.dict)
+		if err != nil {
+			return fmt.Errorf("codegen error: %w", err)
+		}
+		if code == src.code {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(w.Dir, name), []byte(code), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		src.code, src.sourceMap = code, sourceMap
+	}
+	return nil
+}
+
+// goName is the name of the Go file generated for the Singlish file at path.
+func goName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ".go"
+}
+
+// Close removes the workspace directory.
+func (w *Workspace) Close() error {
+	return os.RemoveAll(w.Dir)
+}
+
+var (
+	goPosition = regexp.MustCompile(`[^\s:()"]*?([\w.-]+\.go):(\d+)(:\d+)?`)
+	modulePath = regexp.MustCompile(`(^|[\s\[])` + regexp.QuoteMeta(ModulePath) + `(\.test\]|\s|$)`)
+)
+
+// MapLine rewrites references to generated Go files in one line of go tool
+// output, such as "foo_test.go:12:3", into "foo_test.singlish:7".
+// References to files outside the workspace are left alone. If the line
+// mentions a name that was renamed in Go, a note giving its Singlish name
+// follows it, and an undefined name close to a keyword gets a "did you
+// mean" hint.
+func (w *Workspace) MapLine(line string) string {
+	var mapped []*sourceFile
+	line = goPosition.ReplaceAllStringFunc(line, func(match string) string {
+		m := goPosition.FindStringSubmatch(match)
+		src, ok := w.files[m[1]]
+		if !ok {
+			return match
+		}
+		mapped = append(mapped, src)
+		goLine, _ := strconv.Atoi(m[2])
+		if srcLine, ok := src.sourceMap.Lookup(goLine); ok {
+			return fmt.Sprintf("%s:%d", src.path, srcLine)
+		}
+		return src.path
+	})
+	if w.Label != ModulePath {
+		line = modulePath.ReplaceAllString(line, "${1}"+w.Label+"${2}")
+	}
+	for _, src := range mapped {
+		line = src.sourceMap.Explain(line)
+	}
+	if len(mapped) > 0 {
+		line = strings.TrimSuffix(suggest.AnnotateCompilerOutput(line, w.dict), "\n")
+	}
+	return line
+}
+
+// Writer returns a writer that maps every complete line written to it with
+// MapLine before passing it on to dst. Close flushes any trailing partial
+// line and must be called once writing is done.
+func (w *Workspace) Writer(dst io.Writer) io.WriteCloser {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			fmt.Fprintln(dst, w.MapLine(scanner.Text()))
+		}
+		// Keep draining so the writer side never blocks on an overlong line.
+		io.Copy(io.Discard, pr)
+	}()
+	return &mappedWriter{pw: pw, done: done}
+}
+
+type mappedWriter struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+}
+
+func (m *mappedWriter) Write(p []byte) (int, error) {
+	return m.pw.Write(p)
+}
+
+func (m *mappedWriter) Close() error {
+	err := m.pw.Close()
+	<-m.done
+	return err
+}
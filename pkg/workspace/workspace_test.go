@@ -0,0 +1,149 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rickchow/singlish/pkg/dictionaries"
+	"github.com/rickchow/singlish/pkg/transpiler"
+)
+
+func TestAddAndMapLine(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := filepath.Join(t.TempDir(), "math_test.singlish")
+	source := "kampung main\n\ndapao \"testing\"\n\naction TestAdd(t ki testing.T) {\n\tt.Errorf(\"sian\")\n}\n"
+	if err := os.WriteFile(src, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := New(dict)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer ws.Close()
+
+	if _, err := ws.Add(src); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	code, err := os.ReadFile(filepath.Join(ws.Dir, "math_test.go"))
+	if err != nil {
+		t.Fatalf("generated file missing: %v", err)
+	}
+	goLine := 0
+	for i, l := range strings.Split(string(code), "\n") {
+		if strings.Contains(l, "t.Errorf") {
+			goLine = i + 1
+		}
+	}
+
+	ws.Label = "./mathlah"
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"    math_test.go:" + strconv.Itoa(goLine) + ": sian", "    " + src + ":6: sian"},
+		{"./math_test.go:" + strconv.Itoa(goLine) + ":3: undefined: nope", src + ":6: undefined: nope"},
+		{"./math_test.go:" + strconv.Itoa(goLine) + ":9: undefined: nombar", src + ":6: undefined: nombar\n\tdid you mean `nombor`?"},
+		{"\t" + ws.Dir + "/math_test.go:" + strconv.Itoa(goLine) + " +0x9", "\t" + src + ":6 +0x9"},
+		{"/usr/local/go/src/testing/testing.go:2123 +0x232", "/usr/local/go/src/testing/testing.go:2123 +0x232"},
+		{"FAIL\tsinglishpkg\t0.002s", "FAIL\t./mathlah\t0.002s"},
+	}
+	for _, tt := range tests {
+		if got := ws.MapLine(tt.in); got != tt.want {
+			t.Errorf("MapLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMapLineRenames(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	src := filepath.Join(t.TempDir(), "kopi.singlish")
+	source := "kampung main\n\naction brew() {\n\tgot type = 1\n}\n"
+	if err := os.WriteFile(src, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := New(dict)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer ws.Close()
+	if _, err := ws.Add(src); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	want := src + ":4: declared and not used: type_\n\tnote: `type_` is `type` in the Singlish source, renamed because `type` is a Go keyword"
+	if got := ws.MapLine("./kopi.go:4:6: declared and not used: type_"); got != want {
+		t.Errorf("MapLine() = %q, want %q", got, want)
+	}
+}
+
+func TestAddRenamesAcrossFiles(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	dir := t.TempDir()
+	sources := map[string]string{
+		"size.singlish": "kampung main\n\naction size() nombor {\n\tbalek len + count(\"ab\")\n}\n",
+		"len.singlish":  "kampung main\n\ngot len = 3\n",
+	}
+	for name, source := range sources {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ws, err := New(dict)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer ws.Close()
+
+	read := func() string {
+		code, err := os.ReadFile(filepath.Join(ws.Dir, "size.go"))
+		if err != nil {
+			t.Fatalf("generated file missing: %v", err)
+		}
+		return string(code)
+	}
+	if _, err := ws.Add(filepath.Join(dir, "size.singlish")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if code := read(); !strings.Contains(code, "return (len + len(\"ab\"))") {
+		t.Errorf("size.go on its own:\n%s", code)
+	}
+	// Declaring len in another file renames it in size.go too.
+	if _, err := ws.Add(filepath.Join(dir, "len.singlish")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if code := read(); !strings.Contains(code, "return (len_ + len(\"ab\"))") {
+		t.Errorf("size.go after len.singlish was added:\n%s", code)
+	}
+	if err := ws.Remove(filepath.Join(dir, "len.singlish")); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if code := read(); !strings.Contains(code, "return (len + len(\"ab\"))") {
+		t.Errorf("size.go after len.singlish was removed:\n%s", code)
+	}
+}
+
+func TestTestFunctions(t *testing.T) {
+	dict := dictionaries.NewDefaultDictionary()
+	source := `kampung main
+dapao "testing"
+action TestGood(t ki testing.T) {}
+action TestBad(n nombor) {}
+action Testing() {}
+action helper(t ki testing.T) {}
+`
+	program, err := transpiler.Parse(source, dict)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	names, diags := TestFunctions(program, dict)
+	if len(names) != 1 || names[0] != "TestGood" {
+		t.Errorf("names = %v, want [TestGood]", names)
+	}
+	if len(diags) != 1 || diags[0].Line != 4 || !strings.Contains(diags[0].Message, "TestBad") {
+		t.Errorf("unexpected diagnostics: %+v", diags)
+	}
+}
@@ -2,13 +2,15 @@ package integration
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"testing"
+
+	"github.com/rickchow/singlish/pkg/examples"
 )
 
 var singlishBinary string
@@ -59,18 +61,6 @@ func TestExamples(t *testing.T) {
 		t.Fatalf("Failed to resolve examples directory: %v", err)
 	}
 
-	// files to skip
-	skipFiles := map[string]bool{
-		"debug_catch.singlish": true, // Intentionally blocks/deadlocks
-	}
-
-	// specific output expectations (partial match)
-	expectedOutputs := map[string]string{
-		"01_hello_world.singlish": "Hello Singapore! Limpeh is coding now.",
-		"02_fizzbuzz.singlish":    "FizzBuzz",
-		"11_pointers.singlish":    "60",
-	}
-
 	files, err := filepath.Glob(filepath.Join(examplesDir, "*.singlish"))
 	if err != nil {
 		t.Fatalf("Failed to list example files: %v", err)
@@ -82,12 +72,17 @@ func TestExamples(t *testing.T) {
 
 	for _, path := range files {
 		filename := filepath.Base(path)
-		if skipFiles[filename] {
-			t.Logf("Skipping %s", filename)
-			continue
-		}
 
 		t.Run(filename, func(t *testing.T) {
+			// Expectations live next to the example: // expect:, // skip: or a .golden file
+			spec, err := examples.Load(path)
+			if err != nil {
+				t.Fatalf("Failed to load expectations: %v", err)
+			}
+			if spec.Skip != "" {
+				t.Skip(spec.Skip)
+			}
+
 			// Cmd to run
 			cmd := exec.Command(singlishBinary, "run", path)
 			cmd.Dir = filepath.Dir(singlishBinary) // Run in clean dir
@@ -97,18 +92,19 @@ func TestExamples(t *testing.T) {
 			cmd.Stdout = &out
 			cmd.Stderr = &stderr
 
-			err := cmd.Run()
-			if err != nil {
-				t.Fatalf("Command failed: %v\nStderr: %s\nStdout: %s", err, stderr.String(), out.String())
+			outcome := examples.Outcome{}
+			if err := cmd.Run(); err != nil {
+				var exitErr *exec.ExitError
+				if !errors.As(err, &exitErr) {
+					t.Fatalf("Command failed: %v", err)
+				}
+				outcome.ExitCode = exitErr.ExitCode()
 			}
+			outcome.Stdout = out.String()
+			outcome.Stderr = stderr.String()
 
-			output := out.String()
-
-			// Check against expected output if defined
-			if expected, ok := expectedOutputs[filename]; ok {
-				if !strings.Contains(output, expected) {
-					t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
-				}
+			if result := examples.Check(spec, outcome); result.Status != examples.Pass {
+				t.Errorf("%s\n%sStderr: %s", result.Reason, result.Diff, outcome.Stderr)
 			}
 		})
 	}